@@ -8,4 +8,6 @@ require (
 	golang.org/x/crypto v0.32.0
 )
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
+
+require github.com/lib/pq v1.12.3
@@ -0,0 +1,40 @@
+// Package audit names the privileged actions kept in the audit log and
+// turns their targets into snapshots
+package audit
+
+import "encoding/json"
+
+// Actions recorded in the audit log
+const (
+	ActionHideContent       = "content.hide"
+	ActionResolveReport     = "report.resolve"
+	ActionSanctionUser      = "user.sanction"
+	ActionLiftSanction      = "user.lift_sanction"
+	ActionChangeRole        = "user.change_role"
+	ActionCreateCategory    = "category.create"
+	ActionUpdateCategory    = "category.update"
+	ActionReorderCategories = "category.reorder"
+	ActionArchiveCategory   = "category.archive"
+	ActionRestoreCategory   = "category.restore"
+)
+
+// Kinds of target an action applies to. Hidden content uses the item types
+// of the moderation package.
+const (
+	TargetUser     = "user"
+	TargetReport   = "report"
+	TargetCategory = "category"
+)
+
+// Snapshot encodes the state of a target as JSON. A nil value, for a target
+// that does not exist, gives an empty snapshot.
+func Snapshot(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
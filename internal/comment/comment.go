@@ -1,48 +1,59 @@
 package comment
 
 import (
-	"forum/internal/database"
 	"forum/internal/model"
-	"forum/internal/reaction"
 )
 
-func FetchCommentsForPost(postID int) ([]model.Comment, error) {
-	commentRows, err := database.Db.Query(`
-    SELECT c.id, c.user_id, c.content, u.username
-    FROM comments c
-    JOIN users u ON u.id = c.user_id
-    WHERE c.post_id = ?`, postID)
-	if err != nil {
-		return nil, err
+// DefaultMaxDepth is how many levels of replies are returned when the
+// client does not ask for a specific depth
+const DefaultMaxDepth = 5
+
+// BuildTree nests the comments of a post, given in posting order, by reply,
+// at most maxDepth levels deep. Deeper replies are collapsed into their
+// ancestor's ReplyCount and deleted comments without replies are dropped.
+func BuildTree(comments []model.Comment, maxDepth int) []model.Comment {
+	// Group comments under their parent, treating unknown parents as top level
+	known := make(map[int]bool)
+	for _, c := range comments {
+		known[c.ID] = true
+	}
+	children := make(map[int][]model.Comment)
+	for _, c := range comments {
+		parentID := c.ParentID
+		if !known[parentID] {
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], c)
 	}
-	defer commentRows.Close()
 
-	var comments []model.Comment
+	tree, _ := buildTree(children, 0, 1, maxDepth)
+	return tree
+}
 
-	for commentRows.Next() {
-		var comment model.Comment
-		err := commentRows.Scan(&comment.ID, &comment.UserID, &comment.Content, &comment.Username)
-		if err != nil {
-			return nil, err
-		}
+// buildTree assembles the replies of parentID at the given depth and returns
+// them with the number of visible comments they contain
+func buildTree(children map[int][]model.Comment, parentID, depth, maxDepth int) ([]model.Comment, int) {
+	nodes := []model.Comment{}
+	total := 0
+
+	for _, c := range children[parentID] {
+		replies, replyCount := buildTree(children, c.ID, depth+1, maxDepth)
 
-		comment.Likes, comment.Dislikes, err = reaction.FetchReactionsNumber(comment.ID, true)
-		if err != nil {
-			return nil, err
+		// A deleted comment only matters if someone replied to it
+		if c.Deleted && replyCount == 0 {
+			continue
 		}
 
-		comments = append(comments, comment)
-	}
+		c.ReplyCount = replyCount
+		if depth >= maxDepth {
+			c.Collapsed = replyCount > 0
+		} else {
+			c.Replies = replies
+		}
 
-	if err := commentRows.Err(); err != nil {
-		return nil, err
+		nodes = append(nodes, c)
+		total += 1 + replyCount
 	}
 
-	return comments, nil
-}
-
-func AddComment(userID int, postID string, content string) error {
-	query := "INSERT INTO comments (user_id, post_id, content) VALUES (?, ?, ?)"
-	_, err := database.Db.Exec(query, userID, postID, content)
-	return err
+	return nodes, total
 }
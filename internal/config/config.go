@@ -0,0 +1,485 @@
+// Package config loads the server settings. Every setting has a default and
+// can be overridden, in increasing order of precedence, by a JSON config
+// file, a FORUM_* environment variable and a command-line flag.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of the server
+type Config struct {
+	// HTTP server
+	Addr         string
+	TLSCert      string
+	TLSKey       string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout bounds how long a stopping server waits for requests
+	// to finish, and then as long again for websocket connections
+	ShutdownTimeout time.Duration
+
+	// Storage: DB selects the backend that keeps all of the forum's data.
+	// Only the DSN of that backend is used.
+	DB          string
+	SQLiteDSN   string
+	PostgresDSN string
+
+	// Sessions
+	SessionTTL             time.Duration
+	SessionCleanupInterval time.Duration
+
+	// WebSocket connections
+	WSMaxMessageSize int64
+	WSPongWait       time.Duration
+	WSWriteWait      time.Duration
+	WSSendBuffer     int
+
+	// How long after sending a private message its sender may edit or
+	// delete it
+	WSMessageEditWindow time.Duration
+
+	// Incoming websocket messages allowed per connection and per user. Chat
+	// covers sending, editing and deleting messages, typing the typing
+	// notifications and requests everything else. A connection is closed
+	// once it exceeds WSMaxViolations of them.
+	WSRateChat        Rate
+	WSRateChatUser    Rate
+	WSRateTyping      Rate
+	WSRateTypingUser  Rate
+	WSRateRequest     Rate
+	WSRateRequestUser Rate
+	WSMaxViolations   Rate
+
+	// Requests allowed per client IP address on the busiest routes
+	RateLimitLogin    Rate
+	RateLimitRegister Rate
+	RateLimitPost     Rate
+	RateLimitComment  Rate
+	RateLimitLike     Rate
+
+	// An account or IP address is locked out of logging in after this many
+	// failed attempts, for LoginLockout at first and twice as long after
+	// each further failure, up to LoginMaxLockout
+	LoginMaxFailures   int
+	LoginMaxIPFailures int
+	LoginLockout       time.Duration
+	LoginMaxLockout    time.Duration
+
+	StaticDir string
+	LogLevel  slog.Level
+
+	// BootstrapAdmin names a user to make admin at startup while the forum
+	// has no admin
+	BootstrapAdmin string
+
+	// sources records where each setting came from, for Print
+	sources map[string]string
+}
+
+// Rate is a number of requests allowed per period. A zero Count means no
+// limit.
+type Rate struct {
+	Count  int
+	Period time.Duration
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Addr:         ":8080",
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+
+		ShutdownTimeout: 15 * time.Second,
+
+		DB:        "sqlite",
+		SQLiteDSN: "data/forum.db?_journal=WAL&_busy_timeout=5000",
+
+		SessionTTL:             24 * time.Hour,
+		SessionCleanupInterval: 30 * time.Minute,
+
+		WSMaxMessageSize: 4096,
+		WSPongWait:       60 * time.Second,
+		WSWriteWait:      10 * time.Second,
+		WSSendBuffer:     256,
+
+		WSMessageEditWindow: 15 * time.Minute,
+
+		WSRateChat:        Rate{10, 10 * time.Second},
+		WSRateChatUser:    Rate{20, 10 * time.Second},
+		WSRateTyping:      Rate{20, 10 * time.Second},
+		WSRateTypingUser:  Rate{40, 10 * time.Second},
+		WSRateRequest:     Rate{30, 10 * time.Second},
+		WSRateRequestUser: Rate{60, 10 * time.Second},
+		WSMaxViolations:   Rate{10, time.Minute},
+
+		RateLimitLogin:    Rate{10, time.Minute},
+		RateLimitRegister: Rate{5, time.Hour},
+		RateLimitPost:     Rate{5, time.Minute},
+		RateLimitComment:  Rate{20, time.Minute},
+		RateLimitLike:     Rate{60, time.Minute},
+
+		LoginMaxFailures:   5,
+		LoginMaxIPFailures: 20,
+		LoginLockout:       time.Minute,
+		LoginMaxLockout:    time.Hour,
+
+		StaticDir: "./web/static",
+		LogLevel:  slog.LevelInfo,
+	}
+}
+
+// option describes one setting. The name is used as the flag and as the
+// config file key; the first environment variable is the documented one.
+type option struct {
+	name   string
+	env    []string
+	usage  string
+	secret bool
+	field  func(c *Config) interface{}
+}
+
+var options = []option{
+	{"addr", []string{"FORUM_ADDR"}, "address the HTTP server listens on", false,
+		func(c *Config) interface{} { return &c.Addr }},
+	{"tls-cert", []string{"FORUM_TLS_CERT"}, "TLS certificate file; serves HTTPS together with -tls-key", false,
+		func(c *Config) interface{} { return &c.TLSCert }},
+	{"tls-key", []string{"FORUM_TLS_KEY"}, "TLS private key file", false,
+		func(c *Config) interface{} { return &c.TLSKey }},
+	{"read-timeout", []string{"FORUM_READ_TIMEOUT"}, "maximum time to read a request, 0 for none", false,
+		func(c *Config) interface{} { return &c.ReadTimeout }},
+	{"write-timeout", []string{"FORUM_WRITE_TIMEOUT"}, "maximum time to write a response, 0 for none", false,
+		func(c *Config) interface{} { return &c.WriteTimeout }},
+	{"idle-timeout", []string{"FORUM_IDLE_TIMEOUT"}, "how long idle keep-alive connections stay open, 0 for none", false,
+		func(c *Config) interface{} { return &c.IdleTimeout }},
+	{"shutdown-timeout", []string{"FORUM_SHUTDOWN_TIMEOUT"}, "how long to wait for open requests, then for websocket connections, when stopping", false,
+		func(c *Config) interface{} { return &c.ShutdownTimeout }},
+	{"db", []string{"FORUM_DB"}, "where the forum's data is kept: sqlite or postgres", false,
+		func(c *Config) interface{} { return &c.DB }},
+	{"sqlite-dsn", []string{"FORUM_SQLITE_DSN"}, "SQLite database file and connection options", false,
+		func(c *Config) interface{} { return &c.SQLiteDSN }},
+	{"postgres-dsn", []string{"FORUM_POSTGRES_DSN", "DATABASE_URL"}, "PostgreSQL connection string for -db=postgres", true,
+		func(c *Config) interface{} { return &c.PostgresDSN }},
+	{"session-ttl", []string{"FORUM_SESSION_TTL"}, "how long a session stays valid without activity", false,
+		func(c *Config) interface{} { return &c.SessionTTL }},
+	{"session-cleanup-interval", []string{"FORUM_SESSION_CLEANUP_INTERVAL"}, "how often expired sessions are removed", false,
+		func(c *Config) interface{} { return &c.SessionCleanupInterval }},
+	{"ws-max-message-size", []string{"FORUM_WS_MAX_MESSAGE_SIZE"}, "largest websocket message a client may send, in bytes", false,
+		func(c *Config) interface{} { return &c.WSMaxMessageSize }},
+	{"ws-pong-wait", []string{"FORUM_WS_PONG_WAIT"}, "how long a silent websocket connection is kept; pings go out at 9/10 of it", false,
+		func(c *Config) interface{} { return &c.WSPongWait }},
+	{"ws-write-wait", []string{"FORUM_WS_WRITE_WAIT"}, "maximum time to write one websocket message", false,
+		func(c *Config) interface{} { return &c.WSWriteWait }},
+	{"ws-send-buffer", []string{"FORUM_WS_SEND_BUFFER"}, "outgoing messages queued per websocket connection", false,
+		func(c *Config) interface{} { return &c.WSSendBuffer }},
+	{"ws-message-edit-window", []string{"FORUM_WS_MESSAGE_EDIT_WINDOW"}, "how long after sending a private message its sender may edit or delete it", false,
+		func(c *Config) interface{} { return &c.WSMessageEditWindow }},
+	{"ws-rate-chat", []string{"FORUM_WS_RATE_CHAT"}, "chat messages, edits and deletions allowed per websocket connection, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateChat }},
+	{"ws-rate-chat-user", []string{"FORUM_WS_RATE_CHAT_USER"}, "chat messages, edits and deletions allowed per user across connections, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateChatUser }},
+	{"ws-rate-typing", []string{"FORUM_WS_RATE_TYPING"}, "typing notifications allowed per websocket connection, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateTyping }},
+	{"ws-rate-typing-user", []string{"FORUM_WS_RATE_TYPING_USER"}, "typing notifications allowed per user across connections, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateTypingUser }},
+	{"ws-rate-request", []string{"FORUM_WS_RATE_REQUEST"}, "other websocket requests, such as loading history, allowed per connection, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateRequest }},
+	{"ws-rate-request-user", []string{"FORUM_WS_RATE_REQUEST_USER"}, "other websocket requests allowed per user across connections, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.WSRateRequestUser }},
+	{"ws-max-violations", []string{"FORUM_WS_MAX_VIOLATIONS"}, "throttled websocket messages after which a connection is closed, as count/period or 0 to never close", false,
+		func(c *Config) interface{} { return &c.WSMaxViolations }},
+	{"rate-limit-login", []string{"FORUM_RATE_LIMIT_LOGIN"}, "login attempts allowed per IP address, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.RateLimitLogin }},
+	{"rate-limit-register", []string{"FORUM_RATE_LIMIT_REGISTER"}, "registrations allowed per IP address, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.RateLimitRegister }},
+	{"rate-limit-post", []string{"FORUM_RATE_LIMIT_POST"}, "new posts allowed per IP address, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.RateLimitPost }},
+	{"rate-limit-comment", []string{"FORUM_RATE_LIMIT_COMMENT"}, "new comments allowed per IP address, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.RateLimitComment }},
+	{"rate-limit-like", []string{"FORUM_RATE_LIMIT_LIKE"}, "likes and dislikes allowed per IP address, as count/period or 0 for no limit", false,
+		func(c *Config) interface{} { return &c.RateLimitLike }},
+	{"login-max-failures", []string{"FORUM_LOGIN_MAX_FAILURES"}, "failed logins after which an account is locked out", false,
+		func(c *Config) interface{} { return &c.LoginMaxFailures }},
+	{"login-max-ip-failures", []string{"FORUM_LOGIN_MAX_IP_FAILURES"}, "failed logins after which an IP address is locked out", false,
+		func(c *Config) interface{} { return &c.LoginMaxIPFailures }},
+	{"login-lockout", []string{"FORUM_LOGIN_LOCKOUT"}, "first lockout after too many failed logins; doubles with each further failure", false,
+		func(c *Config) interface{} { return &c.LoginLockout }},
+	{"login-max-lockout", []string{"FORUM_LOGIN_MAX_LOCKOUT"}, "longest lockout after failed logins; failures are forgotten after this long", false,
+		func(c *Config) interface{} { return &c.LoginMaxLockout }},
+	{"static-dir", []string{"FORUM_STATIC_DIR"}, "directory served under /static/", false,
+		func(c *Config) interface{} { return &c.StaticDir }},
+	{"log-level", []string{"FORUM_LOG_LEVEL"}, "minimum level of leveled log messages: debug, info, warn or error", false,
+		func(c *Config) interface{} { return &c.LogLevel }},
+	{"bootstrap-admin", []string{"FORUM_BOOTSTRAP_ADMIN"}, "username to make admin at startup when the forum has no admin yet", false,
+		func(c *Config) interface{} { return &c.BootstrapAdmin }},
+}
+
+// Load registers the settings as flags on fs, parses args with it and
+// returns the resulting configuration. The config file is named by -config
+// or FORUM_CONFIG. Callers may add their own flags to fs beforehand.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	defaults := Default()
+	configFile := fs.String("config", os.Getenv("FORUM_CONFIG"), "JSON file with settings keyed by flag name (env FORUM_CONFIG)")
+
+	// Flags are only recorded while parsing; they are applied last so that
+	// they win over the file and the environment
+	flagValues := make(map[string]string)
+	for _, o := range options {
+		o := o
+		fs.Func(o.name, o.help(&defaults), func(value string) error {
+			var scratch Config
+			if err := set(o.field(&scratch), value); err != nil {
+				return err
+			}
+			flagValues[o.name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := defaults
+	cfg.sources = make(map[string]string)
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, o := range options {
+		for _, env := range o.env {
+			value, ok := os.LookupEnv(env)
+			if !ok {
+				continue
+			}
+			if err := set(o.field(&cfg), value); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", env, err)
+			}
+			cfg.sources[o.name] = "env " + env
+			break
+		}
+		if value, ok := flagValues[o.name]; ok {
+			set(o.field(&cfg), value)
+			cfg.sources[o.name] = "flag"
+		}
+	}
+
+	return cfg, cfg.validate()
+}
+
+// loadFile applies the settings of a JSON config file
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	for key, value := range values {
+		o, ok := lookup(key)
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if err := set(o.field(c), fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		c.sources[key] = "file"
+	}
+	return nil
+}
+
+// validate reports every invalid setting at once
+func (c *Config) validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	_, port, err := net.SplitHostPort(c.Addr)
+	if err == nil {
+		_, err = net.LookupPort("tcp", port)
+	}
+	check(err == nil, "addr %q must be a host and a valid port, e.g. :8080", c.Addr)
+	check((c.TLSCert == "") == (c.TLSKey == ""), "tls-cert and tls-key must be set together")
+	check(c.ReadTimeout >= 0 && c.WriteTimeout >= 0 && c.IdleTimeout >= 0, "timeouts must not be negative")
+	check(c.ShutdownTimeout > 0, "shutdown-timeout must be positive")
+
+	switch c.DB {
+	case "sqlite":
+		check(c.SQLiteDSN != "", "sqlite-dsn must not be empty")
+	case "postgres":
+		check(c.PostgresDSN != "", "db=postgres needs postgres-dsn")
+	default:
+		check(false, "db must be sqlite or postgres, not %q", c.DB)
+	}
+
+	check(c.SessionTTL > 0, "session-ttl must be positive")
+	check(c.SessionCleanupInterval > 0, "session-cleanup-interval must be positive")
+
+	check(c.WSMaxMessageSize > 0, "ws-max-message-size must be positive")
+	check(c.WSPongWait >= time.Second, "ws-pong-wait must be at least 1s")
+	check(c.WSWriteWait > 0, "ws-write-wait must be positive")
+	check(c.WSSendBuffer > 0, "ws-send-buffer must be positive")
+	check(c.WSMessageEditWindow > 0, "ws-message-edit-window must be positive")
+
+	check(c.LoginMaxFailures > 0 && c.LoginMaxIPFailures > 0, "login-max-failures and login-max-ip-failures must be positive")
+	check(c.LoginLockout > 0, "login-lockout must be positive")
+	check(c.LoginMaxLockout >= c.LoginLockout, "login-max-lockout must not be shorter than login-lockout")
+
+	info, err := os.Stat(c.StaticDir)
+	check(err == nil && info.IsDir(), "static-dir %q is not a directory", c.StaticDir)
+
+	return errors.Join(problems...)
+}
+
+// Print writes every setting with where it came from. Secrets such as the
+// password of the PostgreSQL connection string are redacted.
+func (c Config) Print(w io.Writer) {
+	fmt.Fprintln(w, "Configuration:")
+	for _, o := range options {
+		value := format(o.field(&c))
+		if o.secret {
+			value = redactDSN(value)
+		}
+		source := c.sources[o.name]
+		if source == "" {
+			source = "default"
+		}
+		fmt.Fprintf(w, "  %-26s %-40s (%s)\n", o.name, value, source)
+	}
+}
+
+// help returns the flag usage, mentioning the environment variable and the
+// default value
+func (o option) help(defaults *Config) string {
+	usage := fmt.Sprintf("%s (env %s)", o.usage, o.env[0])
+	if value := format(o.field(defaults)); value != "" && value != "0" {
+		usage += fmt.Sprintf(" (default %s)", value)
+	}
+	return usage
+}
+
+// lookup finds an option by name
+func lookup(name string) (option, bool) {
+	for _, o := range options {
+		if o.name == name {
+			return o, true
+		}
+	}
+	return option{}, false
+}
+
+// set parses value into the field pointer
+func set(field interface{}, value string) error {
+	value = strings.TrimSpace(value)
+	switch p := field.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = n
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use e.g. 30s, 15m or 24h", value)
+		}
+		*p = d
+	case *slog.Level:
+		if err := p.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid log level %q", value)
+		}
+	case *Rate:
+		if value == "0" {
+			*p = Rate{}
+			return nil
+		}
+		count, period, ok := strings.Cut(value, "/")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 0 {
+			return fmt.Errorf("invalid rate %q, use e.g. 10/1m or 0 for no limit", value)
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid rate %q, use e.g. 10/1m or 0 for no limit", value)
+		}
+		*p = Rate{n, d}
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
+
+// format renders the field pointer the way set accepts it
+func format(field interface{}) string {
+	switch p := field.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *int64:
+		return strconv.FormatInt(*p, 10)
+	case *time.Duration:
+		return p.String()
+	case *slog.Level:
+		return strings.ToLower(p.String())
+	case *Rate:
+		if p.Count == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%d/%s", p.Count, p.Period)
+	}
+	return fmt.Sprint(field)
+}
+
+// keywordPassword matches the password of a key=value connection string
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDSN hides the password of a connection string in URL or key=value form
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.String()
+	}
+	return keywordPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load runs Load on a fresh flag set with a valid static-dir unless args
+// name one
+func load(t *testing.T, args ...string) (Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("forum", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, append([]string{"-static-dir", t.TempDir()}, args...))
+}
+
+// writeFile writes a config file into a temporary directory
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "forum.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing the config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `{
+		"addr": ":1001",
+		"session-ttl": "1h",
+		"ws-rate-chat": "5/1m",
+		"login-max-failures": 7
+	}`)
+	t.Setenv("FORUM_CONFIG", path)
+	t.Setenv("FORUM_ADDR", ":1002")
+	t.Setenv("FORUM_SESSION_TTL", "2h")
+
+	cfg, err := load(t, "-addr", ":1003")
+	if err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		got    interface{}
+		want   interface{}
+		source string
+	}{
+		{"addr", cfg.Addr, ":1003", "flag"},
+		{"session-ttl", cfg.SessionTTL, 2 * time.Hour, "env FORUM_SESSION_TTL"},
+		{"ws-rate-chat", cfg.WSRateChat, Rate{5, time.Minute}, "file"},
+		{"login-max-failures", cfg.LoginMaxFailures, 7, "file"},
+		{"idle-timeout", cfg.IdleTimeout, Default().IdleTimeout, ""},
+	} {
+		if test.got != test.want || cfg.sources[test.name] != test.source {
+			t.Errorf("%s is %v from %q, want %v from %q", test.name, test.got, cfg.sources[test.name], test.want, test.source)
+		}
+	}
+}
+
+func TestLoadEnvironmentAliases(t *testing.T) {
+	t.Setenv("FORUM_DB", "postgres")
+	t.Setenv("DATABASE_URL", "postgres://db/fallback")
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+	if cfg.PostgresDSN != "postgres://db/fallback" {
+		t.Errorf("postgres-dsn is %q, want DATABASE_URL", cfg.PostgresDSN)
+	}
+
+	// The documented variable wins over its alias
+	t.Setenv("FORUM_POSTGRES_DSN", "postgres://db/forum")
+	if cfg, err = load(t); err != nil || cfg.PostgresDSN != "postgres://db/forum" {
+		t.Errorf("postgres-dsn is %q (%v), want FORUM_POSTGRES_DSN", cfg.PostgresDSN, err)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		env  map[string]string
+		file string
+		args []string
+		want string
+	}{
+		{name: "duration flag", args: []string{"-read-timeout", "soon"}, want: "invalid duration"},
+		{name: "duration env", env: map[string]string{"FORUM_SESSION_TTL": "forever"}, want: "FORUM_SESSION_TTL: invalid duration"},
+		{name: "duration file", file: `{"idle-timeout": "1 minute"}`, want: "idle-timeout: invalid duration"},
+		{name: "negative duration", args: []string{"-session-ttl", "-1h"}, want: "session-ttl must be positive"},
+		{name: "port out of range", args: []string{"-addr", ":99999"}, want: `addr ":99999"`},
+		{name: "port missing", args: []string{"-addr", "localhost"}, want: `addr "localhost"`},
+		{name: "unknown driver", args: []string{"-db", "mysql"}, want: `db must be sqlite or postgres, not "mysql"`},
+		{name: "postgres without a DSN", args: []string{"-db", "postgres"}, want: "db=postgres needs postgres-dsn"},
+		{name: "number", env: map[string]string{"FORUM_WS_SEND_BUFFER": "lots"}, want: "invalid number"},
+		{name: "rate", args: []string{"-ws-rate-chat", "10"}, want: "invalid rate"},
+		{name: "log level", args: []string{"-log-level", "loud"}, want: "invalid log level"},
+		{name: "half a TLS setup", args: []string{"-tls-cert", "cert.pem"}, want: "tls-cert and tls-key must be set together"},
+		{name: "unknown file setting", file: `{"colour": "blue"}`, want: `unknown setting "colour"`},
+		{name: "static dir", args: []string{"-static-dir", "/does/not/exist"}, want: "is not a directory"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			args := test.args
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, test.file))
+			}
+
+			_, err := load(t, args...)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	_, err := load(t, "-db", "mysql", "-session-ttl", "0s", "-ws-send-buffer", "0")
+	if err == nil {
+		t.Fatal("an invalid configuration was accepted")
+	}
+	for _, want := range []string{"db must be", "session-ttl", "ws-send-buffer"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestPrintRedactsDSN(t *testing.T) {
+	for _, dsn := range []string{
+		"postgres://forum:s3cret@db:5432/forum?sslmode=disable",
+		"postgres://forum@db/forum?password=s3cret",
+		"host=db user=forum password=s3cret dbname=forum",
+		"host=db password='s3cret phrase' dbname=forum",
+	} {
+		cfg, err := load(t, "-db", "postgres", "-postgres-dsn", dsn)
+		if err != nil {
+			t.Fatalf("loading %q: %v", dsn, err)
+		}
+
+		var out strings.Builder
+		cfg.Print(&out)
+		if strings.Contains(out.String(), "s3cret") {
+			t.Errorf("the printed configuration reveals the password of %q:\n%s", dsn, out.String())
+		}
+		if !strings.Contains(out.String(), "xxxxx") || !strings.Contains(out.String(), "db") {
+			t.Errorf("the printed configuration lost the rest of %q:\n%s", dsn, out.String())
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration is one numbered step of the schema. Up moves the schema from
+// Version-1 to Version and Down reverses it; a nil Down means the step cannot
+// be rolled back.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// AppliedMigration is a row of the schema_version table
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// createSchemaVersionTable records which migrations have been applied
+const createSchemaVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME NOT NULL
+);`
+
+// SchemaVersion returns the version of the newest applied migration, or 0
+// for a database that has never been migrated. It never writes to the
+// database, so that dry runs leave it untouched.
+func SchemaVersion() (int, error) {
+	exists, err := TableExists(Db, "schema_version")
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = Db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// AppliedMigrations lists the applied migrations, oldest first
+func AppliedMigrations() ([]AppliedMigration, error) {
+	exists, err := TableExists(Db, "schema_version")
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := Db.Query("SELECT version, name, applied_at FROM schema_version ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var migration AppliedMigration
+		if err := rows.Scan(&migration.Version, &migration.Name, &migration.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations lists the migrations newer than the current schema version
+func PendingMigrations() ([]Migration, error) {
+	version, err := SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// LatestVersion returns the version of the newest known migration
+func LatestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate applies every pending migration in order, each in its own
+// transaction. With dryRun set it only logs what would be applied.
+func Migrate(dryRun bool) error {
+	pending, err := PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		log.Printf("Database schema is up to date (version %d)", LatestVersion())
+		return nil
+	}
+
+	if !dryRun {
+		if _, err := Db.Exec(createSchemaVersionTable); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range pending {
+		if dryRun {
+			log.Printf("Would apply migration %d: %s", migration.Version, migration.Name)
+			continue
+		}
+
+		err := inTransaction(func(tx *sql.Tx) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(
+				"INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now(),
+			)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Applied migration %d: %s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// Rollback reverts applied migrations, newest first, until the schema is at
+// the target version. A target below a migration that cannot be rolled back
+// is rejected before anything is reverted. With dryRun set it only logs what
+// would be reverted.
+func Rollback(target int, dryRun bool) error {
+	version, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > version {
+		return fmt.Errorf("cannot roll back from version %d to %d", version, target)
+	}
+
+	var reverting []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("cannot roll back to version %d: migration %d (%s) cannot be rolled back",
+				target, migration.Version, migration.Name)
+		}
+		reverting = append(reverting, migration)
+	}
+
+	for _, migration := range reverting {
+		if dryRun {
+			log.Printf("Would roll back migration %d: %s", migration.Version, migration.Name)
+			continue
+		}
+
+		err := inTransaction(func(tx *sql.Tx) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_version WHERE version = ?", migration.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Rolled back migration %d: %s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// inTransaction runs fn in a transaction and commits it when fn succeeds
+func inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execAll returns a migration step that runs the statements in order
+func execAll(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumn adds a column unless the table already has it, which is the
+// case for databases created before migrations were versioned
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
+
+// dropColumn removes a column if the table has it
+func dropColumn(tx *sql.Tx, table, column string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.Exec("ALTER TABLE " + table + " DROP COLUMN " + column)
+	return err
+}
+
+// columnExists reports whether a table already has the given column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// QueryRower is a database or a transaction
+type QueryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// TableExists reports whether the database has a table, virtual or not,
+// with the given name
+func TableExists(q QueryRower, name string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+// FTS5Available reports whether the SQLite driver was built with FTS5,
+// which the search index needs (build with -tags sqlite_fts5)
+func FTS5Available(q QueryRower) (bool, error) {
+	var available bool
+	err := q.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&available)
+	return available, err
+}
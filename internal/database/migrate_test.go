@@ -0,0 +1,349 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB points Db at a new database file in a temporary directory
+func openTestDB(t *testing.T) {
+	t.Helper()
+	Connect(filepath.Join(t.TempDir(), "forum.db") + "?_journal=WAL&_busy_timeout=5000")
+	t.Cleanup(func() { Db.Close() })
+}
+
+// useMigrations replaces the migrations for the duration of a test
+func useMigrations(t *testing.T, replacement []Migration) {
+	t.Helper()
+	saved := migrations
+	migrations = replacement
+	t.Cleanup(func() { migrations = saved })
+}
+
+// tableMigration creates one table, and drops it again unless irreversible
+func tableMigration(version int, table string, irreversible bool) Migration {
+	migration := Migration{
+		Version: version,
+		Name:    "create " + table,
+		Up:      execAll("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY);"),
+	}
+	if !irreversible {
+		migration.Down = execAll("DROP TABLE " + table + ";")
+	}
+	return migration
+}
+
+// requireFTS5 skips tests that apply the search index migration on builds
+// without FTS5
+func requireFTS5(t *testing.T) {
+	t.Helper()
+	available, err := FTS5Available(Db)
+	if err != nil {
+		t.Fatalf("checking for FTS5: %v", err)
+	}
+	if !available {
+		t.Skip("build with -tags sqlite_fts5 to apply every migration")
+	}
+}
+
+// expectVersion fails the test unless the schema is at the wanted version
+func expectVersion(t *testing.T, want int) {
+	t.Helper()
+	version, err := SchemaVersion()
+	if err != nil {
+		t.Fatalf("reading the schema version: %v", err)
+	}
+	if version != want {
+		t.Fatalf("schema version is %d, want %d", version, want)
+	}
+}
+
+// expectTables fails the test unless each table exists or not as wanted
+func expectTables(t *testing.T, want map[string]bool) {
+	t.Helper()
+	for table, wanted := range want {
+		exists, err := TableExists(Db, table)
+		if err != nil {
+			t.Fatalf("looking up %s: %v", table, err)
+		}
+		if exists != wanted {
+			t.Errorf("table %s exists: %v, want %v", table, exists, wanted)
+		}
+	}
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	openTestDB(t)
+	useMigrations(t, []Migration{
+		tableMigration(1, "one", false),
+		tableMigration(2, "two", false),
+		tableMigration(3, "three", false),
+	})
+
+	if err := Migrate(true); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	expectVersion(t, 0)
+	expectTables(t, map[string]bool{"schema_version": false, "one": false})
+
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	expectVersion(t, 3)
+	expectTables(t, map[string]bool{"one": true, "two": true, "three": true})
+
+	if err := Rollback(1, false); err != nil {
+		t.Fatalf("rolling back two steps: %v", err)
+	}
+	expectVersion(t, 1)
+	expectTables(t, map[string]bool{"one": true, "two": false, "three": false})
+
+	pending, err := PendingMigrations()
+	if err != nil || len(pending) != 2 || pending[0].Version != 2 {
+		t.Errorf("pending migrations are %+v (%v), want 2 and 3", pending, err)
+	}
+	applied, err := AppliedMigrations()
+	if err != nil || len(applied) != 1 || applied[0].Name != "create one" {
+		t.Errorf("applied migrations are %+v (%v), want 1", applied, err)
+	}
+
+	if err := Rollback(0, true); err != nil {
+		t.Fatalf("dry run of the rollback: %v", err)
+	}
+	expectVersion(t, 1)
+
+	if err := Rollback(0, false); err != nil {
+		t.Fatalf("rolling back to 0: %v", err)
+	}
+	expectVersion(t, 0)
+	expectTables(t, map[string]bool{"one": false})
+
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating again: %v", err)
+	}
+	expectVersion(t, 3)
+	expectTables(t, map[string]bool{"one": true, "two": true, "three": true})
+}
+
+func TestRollbackBounds(t *testing.T) {
+	openTestDB(t)
+	useMigrations(t, []Migration{
+		tableMigration(1, "one", false),
+		tableMigration(2, "two", false),
+	})
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	// -to above the current version, or -steps 3 from version 2
+	for _, target := range []int{3, -1} {
+		if err := Rollback(target, false); err == nil {
+			t.Errorf("rolling back to %d was accepted", target)
+		}
+		expectVersion(t, 2)
+	}
+
+	// Rolling back to the current version changes nothing
+	if err := Rollback(2, false); err != nil {
+		t.Errorf("rolling back to the current version: %v", err)
+	}
+	expectVersion(t, 2)
+}
+
+func TestRollbackIrreversible(t *testing.T) {
+	openTestDB(t)
+	useMigrations(t, []Migration{
+		tableMigration(1, "one", true),
+		tableMigration(2, "two", false),
+		tableMigration(3, "three", false),
+	})
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		err := Rollback(0, dryRun)
+		if err == nil || !strings.Contains(err.Error(), "migration 1") {
+			t.Errorf("rolling back past an irreversible migration (dry run %v): got %v", dryRun, err)
+		}
+		// Nothing is reverted when the target is out of reach
+		expectVersion(t, 3)
+		expectTables(t, map[string]bool{"two": true, "three": true})
+	}
+
+	if err := Rollback(1, false); err != nil {
+		t.Fatalf("rolling back to the irreversible migration: %v", err)
+	}
+	expectVersion(t, 1)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	openTestDB(t)
+	broken := tableMigration(2, "two", false)
+	broken.Up = execAll("CREATE TABLE two (id INTEGER PRIMARY KEY);", "NOT SQL;")
+	useMigrations(t, []Migration{tableMigration(1, "one", false), broken})
+
+	if err := Migrate(false); err == nil {
+		t.Fatal("a failing migration was applied")
+	}
+	// The failed migration is neither recorded nor half applied
+	expectVersion(t, 1)
+	expectTables(t, map[string]bool{"one": true, "two": false})
+}
+
+func TestMigrationsUpDownUp(t *testing.T) {
+	openTestDB(t)
+	requireFTS5(t)
+
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	expectVersion(t, LatestVersion())
+
+	if err := Rollback(1, false); err != nil {
+		t.Fatalf("rolling back to the initial schema: %v", err)
+	}
+	expectVersion(t, 1)
+	expectTables(t, map[string]bool{"users": true, "categories": false, "posts_fts": false, "audit_log": false})
+
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating again: %v", err)
+	}
+	expectVersion(t, LatestVersion())
+	expectTables(t, map[string]bool{"categories": true, "posts_fts": true, "audit_log": true})
+
+	// The initial schema cannot be rolled back
+	if err := Rollback(0, false); err == nil {
+		t.Error("rolling back the initial schema was accepted")
+	}
+	expectVersion(t, LatestVersion())
+}
+
+func TestSearchIndexNeedsFTS5(t *testing.T) {
+	openTestDB(t)
+	available, err := FTS5Available(Db)
+	if err != nil {
+		t.Fatalf("checking for FTS5: %v", err)
+	}
+	if available {
+		t.Skip("this build has FTS5")
+	}
+
+	err = Migrate(false)
+	if err == nil || !strings.Contains(err.Error(), "sqlite_fts5") {
+		t.Fatalf("migrating without FTS5: got %v, want an error naming the build tag", err)
+	}
+	// Migrations stop before the search index, which is not recorded
+	expectVersion(t, 12)
+}
+
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	openTestDB(t)
+	requireFTS5(t)
+
+	// The schema the forum created before migrations were versioned
+	err := inTransaction(execAll(
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT UNIQUE, email TEXT UNIQUE,
+			password TEXT, first_name TEXT, last_name TEXT, age INTEGER, gender TEXT);`,
+		`CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, title TEXT, content TEXT,
+			category TEXT, date DATETIME DEFAULT CURRENT_TIMESTAMP);`,
+		`CREATE TABLE comments (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id INTEGER, user_id INTEGER, content TEXT);`,
+		`CREATE TABLE sessions (session_id TEXT PRIMARY KEY, id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, expires_at DATETIME, user_agent TEXT DEFAULT '');`,
+		`INSERT INTO users (username, email, password) VALUES ('alice', 'alice@example.com', 'hash');`,
+		`INSERT INTO posts (user_id, title, content, category) VALUES (1, 'Hello', 'First post', 'Travel, Knitting');`,
+		`INSERT INTO posts (user_id, title, content, category) VALUES (1, 'Untitled', 'No category', '');`,
+	))
+	if err != nil {
+		t.Fatalf("creating the old schema: %v", err)
+	}
+
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	expectVersion(t, LatestVersion())
+
+	var username, role string
+	if err := Db.QueryRow("SELECT username, role FROM users WHERE id = 1").Scan(&username, &role); err != nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if username != "alice" || role != "user" {
+		t.Errorf("user is %s with role %s, want alice with role user", username, role)
+	}
+
+	categories := make(map[int][]string)
+	rows, err := Db.Query(`
+		SELECT pc.post_id, c.name FROM post_categories pc
+		JOIN categories c ON c.id = pc.category_id
+		ORDER BY pc.post_id, c.position`)
+	if err != nil {
+		t.Fatalf("loading the post categories: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			t.Fatalf("scanning a post category: %v", err)
+		}
+		categories[postID] = append(categories[postID], name)
+	}
+	if got := strings.Join(categories[1], ", "); got != "Travel, Knitting" {
+		t.Errorf("categories of the first post are %q, want the old ones", got)
+	}
+	if got := strings.Join(categories[2], ", "); got != DefaultCategories[0] {
+		t.Errorf("categories of the second post are %q, want %q", got, DefaultCategories[0])
+	}
+
+	var indexed int
+	if err := Db.QueryRow("SELECT COUNT(*) FROM posts_fts WHERE posts_fts MATCH 'hello'").Scan(&indexed); err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	if indexed != 1 {
+		t.Errorf("%d old posts are in the search index, want 1", indexed)
+	}
+}
+
+func TestMigrateRoomCategories(t *testing.T) {
+	openTestDB(t)
+	requireFTS5(t)
+
+	// Rooms created before they referred to categories by ID
+	all := migrations
+	useMigrations(t, all[:len(all)-1])
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	err := inTransaction(execAll(
+		`INSERT INTO rooms (name, category) VALUES ('Boats', 'sailing');`,
+		`INSERT INTO rooms (name, category) VALUES ('Gone', 'Knitting');`,
+		`INSERT INTO rooms (name, category) VALUES ('Plain', '');`,
+	))
+	if err != nil {
+		t.Fatalf("creating rooms: %v", err)
+	}
+
+	migrations = all
+	if err := Migrate(false); err != nil {
+		t.Fatalf("migrating the rooms: %v", err)
+	}
+	for name, want := range map[string]string{"Boats": "Sailing", "Gone": "", "Plain": ""} {
+		var category string
+		err := Db.QueryRow(`
+			SELECT COALESCE(c.name, '') FROM rooms r
+			LEFT JOIN categories c ON c.id = r.category_id
+			WHERE r.name = ?`, name).Scan(&category)
+		if err != nil || category != want {
+			t.Errorf("room %s is in category %q (%v), want %q", name, category, err, want)
+		}
+	}
+
+	if err := Rollback(LatestVersion()-1, false); err != nil {
+		t.Fatalf("rolling back: %v", err)
+	}
+	var category string
+	if err := Db.QueryRow("SELECT category FROM rooms WHERE name = 'Boats'").Scan(&category); err != nil || category != "Sailing" {
+		t.Errorf("rolled back room is in category %q (%v), want Sailing", category, err)
+	}
+}
@@ -0,0 +1,693 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// DefaultCategories seeds the categories of a new database, in display order
+var DefaultCategories = []string{
+	"General",
+	"Local News & Events",
+	"Viking line",
+	"Travel",
+	"Sailing",
+	"Cuisine & food",
+	"Politics",
+}
+
+// migrations lists every schema change in order. Versions must keep
+// increasing and an applied migration must never be edited; change the
+// schema by appending a new one. Steps that add columns or tables skip
+// anything already present, because databases created before migrations
+// were versioned may have part of the schema without a schema_version row.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT UNIQUE,
+				email TEXT UNIQUE,
+				password TEXT,
+				first_name TEXT,
+				last_name TEXT,
+				age INTEGER,
+				gender TEXT
+			);`,
+			`CREATE TABLE IF NOT EXISTS posts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER,
+				title TEXT,
+				content TEXT,
+				category TEXT,
+				date DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS comments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				post_id INTEGER,
+				user_id INTEGER,
+				content TEXT,
+				FOREIGN KEY(post_id) REFERENCES posts(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS reactions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				post_id INTEGER,
+				user_id INTEGER,
+				comment_id INTEGER,
+				type TEXT CHECK(type IN ('like', 'dislike')),
+				FOREIGN KEY(post_id) REFERENCES posts(id),
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				UNIQUE (user_id, post_id, comment_id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				session_id TEXT PRIMARY KEY,
+				id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				expires_at DATETIME,
+				FOREIGN KEY(id) REFERENCES users(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS private_messages (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				sender_id INTEGER,
+				receiver_id INTEGER,
+				content TEXT,
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(sender_id) REFERENCES users(id),
+				FOREIGN KEY(receiver_id) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_id ON sessions(id);`,
+		),
+		// Rolling back the initial schema would delete every table
+		Down: nil,
+	},
+	{
+		Version: 2,
+		Name:    "session devices",
+		Up: func(tx *sql.Tx) error {
+			// SQLite cannot add a column with a CURRENT_TIMESTAMP default
+			if err := addColumn(tx, "sessions", "user_agent", "TEXT DEFAULT ''"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "sessions", "ip_address", "TEXT DEFAULT ''"); err != nil {
+				return err
+			}
+			return addColumn(tx, "sessions", "last_seen", "DATETIME")
+		},
+		Down: func(tx *sql.Tx) error {
+			for _, column := range []string{"user_agent", "ip_address", "last_seen"} {
+				if err := dropColumn(tx, "sessions", column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "conversation read state",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS conversation_reads (
+				user_id INTEGER NOT NULL,
+				partner_id INTEGER NOT NULL,
+				last_read_id INTEGER NOT NULL DEFAULT 0,
+				read_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, partner_id),
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(partner_id) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_private_messages_pair ON private_messages(sender_id, receiver_id);`,
+		),
+		Down: execAll(
+			`DROP INDEX IF EXISTS idx_private_messages_pair;`,
+			`DROP TABLE IF EXISTS conversation_reads;`,
+		),
+	},
+	{
+		Version: 4,
+		Name:    "pending notifications",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS pending_notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				sender_id INTEGER NOT NULL,
+				message_id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(sender_id) REFERENCES users(id),
+				FOREIGN KEY(message_id) REFERENCES private_messages(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_pending_notifications_user_id ON pending_notifications(user_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS pending_notifications;`,
+		),
+	},
+	{
+		Version: 5,
+		Name:    "chat rooms",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS rooms (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				category TEXT DEFAULT '',
+				created_by INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(created_by) REFERENCES users(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS room_members (
+				room_id INTEGER NOT NULL,
+				user_id INTEGER NOT NULL,
+				role TEXT NOT NULL DEFAULT 'member' CHECK(role IN ('owner', 'member')),
+				joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (room_id, user_id),
+				FOREIGN KEY(room_id) REFERENCES rooms(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS room_messages (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				room_id INTEGER NOT NULL,
+				sender_id INTEGER NOT NULL,
+				content TEXT,
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(room_id) REFERENCES rooms(id),
+				FOREIGN KEY(sender_id) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_room_members_user_id ON room_members(user_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_room_messages_room_id ON room_messages(room_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS room_messages;`,
+			`DROP TABLE IF EXISTS room_members;`,
+			`DROP TABLE IF EXISTS rooms;`,
+		),
+	},
+	{
+		Version: 6,
+		Name:    "private message edits",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "private_messages", "edited_at", "DATETIME"); err != nil {
+				return err
+			}
+			return addColumn(tx, "private_messages", "deleted_at", "DATETIME")
+		},
+		Down: func(tx *sql.Tx) error {
+			// The old schema has no tombstones, so deleted messages go for good
+			_, err := tx.Exec("DELETE FROM private_messages WHERE deleted_at IS NOT NULL")
+			if err != nil {
+				return err
+			}
+			if err := dropColumn(tx, "private_messages", "edited_at"); err != nil {
+				return err
+			}
+			return dropColumn(tx, "private_messages", "deleted_at")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "post and comment revisions",
+		Up: func(tx *sql.Tx) error {
+			for _, column := range []struct{ table, name string }{
+				{"posts", "edited_at"},
+				{"posts", "deleted_at"},
+				{"comments", "edited_at"},
+				{"comments", "deleted_at"},
+			} {
+				if err := addColumn(tx, column.table, column.name, "DATETIME"); err != nil {
+					return err
+				}
+			}
+			return execAll(
+				`CREATE TABLE IF NOT EXISTS post_revisions (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					post_id INTEGER NOT NULL,
+					title TEXT,
+					content TEXT,
+					category TEXT,
+					edited_by INTEGER,
+					edited_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(post_id) REFERENCES posts(id),
+					FOREIGN KEY(edited_by) REFERENCES users(id)
+				);`,
+				`CREATE TABLE IF NOT EXISTS comment_revisions (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					comment_id INTEGER NOT NULL,
+					content TEXT,
+					edited_by INTEGER,
+					edited_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(comment_id) REFERENCES comments(id),
+					FOREIGN KEY(edited_by) REFERENCES users(id)
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON post_revisions(post_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_id ON comment_revisions(comment_id);`,
+			)(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			// The old schema has no tombstones, so deleted content goes for good
+			err := execAll(
+				`DELETE FROM comments WHERE deleted_at IS NOT NULL;`,
+				`DELETE FROM posts WHERE deleted_at IS NOT NULL;`,
+				`DROP TABLE IF EXISTS comment_revisions;`,
+				`DROP TABLE IF EXISTS post_revisions;`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			for _, column := range []struct{ table, name string }{
+				{"posts", "edited_at"},
+				{"posts", "deleted_at"},
+				{"comments", "edited_at"},
+				{"comments", "deleted_at"},
+			} {
+				if err := dropColumn(tx, column.table, column.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "threaded comments",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "comments", "parent_comment_id", "INTEGER REFERENCES comments(id)")
+		},
+		Down: func(tx *sql.Tx) error {
+			// SQLite cannot drop a column that is a foreign key, so replies
+			// become top-level comments and the column stays unused
+			_, err := tx.Exec("UPDATE comments SET parent_comment_id = NULL")
+			return err
+		},
+	},
+	{
+		Version: 9,
+		Name:    "categories",
+		Up:      migrateCategoriesUp,
+		Down:    migrateCategoriesDown,
+	},
+	{
+		Version: 10,
+		Name:    "reports and sanctions",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS reports (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				reporter_id INTEGER NOT NULL,
+				item_type TEXT NOT NULL,
+				item_id INTEGER NOT NULL,
+				author_id INTEGER NOT NULL,
+				content TEXT NOT NULL DEFAULT '',
+				reason TEXT NOT NULL,
+				details TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				action TEXT,
+				note TEXT,
+				resolved_by INTEGER,
+				resolved_at DATETIME,
+				FOREIGN KEY(reporter_id) REFERENCES users(id),
+				FOREIGN KEY(author_id) REFERENCES users(id),
+				FOREIGN KEY(resolved_by) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_reports_item ON reports(item_type, item_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_reports_reporter_id ON reports(reporter_id);`,
+			`CREATE TABLE IF NOT EXISTS sanctions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				kind TEXT NOT NULL,
+				reason TEXT NOT NULL DEFAULT '',
+				moderator_id INTEGER NOT NULL,
+				report_id INTEGER,
+				created_at DATETIME NOT NULL,
+				expires_at DATETIME,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(moderator_id) REFERENCES users(id),
+				FOREIGN KEY(report_id) REFERENCES reports(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_sanctions_user_id ON sanctions(user_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS sanctions;`,
+			`DROP TABLE IF EXISTS reports;`,
+		),
+	},
+	{
+		Version: 11,
+		Name:    "lifted sanctions and hidden messages",
+		Up: func(tx *sql.Tx) error {
+			for _, column := range []struct{ table, name, definition string }{
+				{"sanctions", "lifted_by", "INTEGER"},
+				{"sanctions", "lifted_at", "DATETIME"},
+				{"private_messages", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+				{"room_messages", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+			} {
+				if err := addColumn(tx, column.table, column.name, column.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			// Hidden messages were never seen by anyone but their sender
+			err := execAll(
+				`DELETE FROM private_messages WHERE hidden = 1;`,
+				`DELETE FROM room_messages WHERE hidden = 1;`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			for _, column := range []struct{ table, name string }{
+				{"sanctions", "lifted_by"},
+				{"sanctions", "lifted_at"},
+				{"private_messages", "hidden"},
+				{"room_messages", "hidden"},
+			} {
+				if err := dropColumn(tx, column.table, column.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "audit log",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				actor_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id INTEGER NOT NULL DEFAULT 0,
+				before_state TEXT,
+				after_state TEXT,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(actor_id) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);`,
+			// The log is append-only, even for code that bypasses the store
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+				SELECT RAISE(ABORT, 'audit log is append-only');
+			END;`,
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+				SELECT RAISE(ABORT, 'audit log is append-only');
+			END;`,
+		),
+		Down: execAll(
+			`DROP TRIGGER IF EXISTS audit_log_no_delete;`,
+			`DROP TRIGGER IF EXISTS audit_log_no_update;`,
+			`DROP TABLE IF EXISTS audit_log;`,
+		),
+	},
+	{
+		Version: 13,
+		Name:    "search index",
+		Up:      migrateSearchIndexUp,
+		Down:    migrateSearchIndexDown,
+	},
+	{
+		Version: 14,
+		Name:    "user roles",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "role", "TEXT NOT NULL DEFAULT 'user'")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "role")
+		},
+	},
+	{
+		Version: 15,
+		Name:    "stored notifications",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				frame TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS notifications;`,
+		),
+	},
+	{
+		Version: 16,
+		Name:    "room categories",
+		Up:      migrateRoomCategoriesUp,
+		Down:    migrateRoomCategoriesDown,
+	},
+}
+
+// migrateCategoriesUp creates the categories tables, seeds the default
+// categories and converts the old comma-joined posts.category column into
+// post_categories rows before dropping it
+func migrateCategoriesUp(tx *sql.Tx) error {
+	err := execAll(
+		`CREATE TABLE IF NOT EXISTS categories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			description TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL DEFAULT 0,
+			archived_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS post_categories (
+			post_id INTEGER NOT NULL,
+			category_id INTEGER NOT NULL,
+			PRIMARY KEY (post_id, category_id),
+			FOREIGN KEY(post_id) REFERENCES posts(id),
+			FOREIGN KEY(category_id) REFERENCES categories(id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_post_categories_category_id ON post_categories(category_id);`,
+	)(tx)
+	if err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM categories").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		for position, name := range DefaultCategories {
+			_, err := tx.Exec("INSERT INTO categories (name, position) VALUES (?, ?)", name, position)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	legacy, err := columnExists(tx, "posts", "category")
+	if err != nil || !legacy {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, COALESCE(category, '') FROM posts")
+	if err != nil {
+		return err
+	}
+	postCategories := make(map[int][]string)
+	for rows.Next() {
+		var postID int
+		var joined string
+		if err := rows.Scan(&postID, &joined); err != nil {
+			rows.Close()
+			return err
+		}
+		for _, name := range strings.Split(joined, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				postCategories[postID] = append(postCategories[postID], name)
+			}
+		}
+		if len(postCategories[postID]) == 0 {
+			postCategories[postID] = []string{DefaultCategories[0]}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for postID, names := range postCategories {
+		for _, name := range names {
+			// Unknown names become new categories at the end of the list
+			_, err := tx.Exec(`
+				INSERT INTO categories (name, position)
+				SELECT ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM categories)
+				WHERE NOT EXISTS (SELECT 1 FROM categories WHERE name = ?)
+			`, name, name)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`
+				INSERT OR IGNORE INTO post_categories (post_id, category_id)
+				SELECT ?, id FROM categories WHERE name = ?
+			`, postID, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Migrated categories of %d posts", len(postCategories))
+
+	return dropColumn(tx, "posts", "category")
+}
+
+// migrateCategoriesDown joins the category names back into posts.category and
+// drops the categories tables
+func migrateCategoriesDown(tx *sql.Tx) error {
+	if err := addColumn(tx, "posts", "category", "TEXT"); err != nil {
+		return err
+	}
+	return execAll(
+		`UPDATE posts SET category = (
+			SELECT group_concat(name, ', ') FROM (
+				SELECT c.name FROM post_categories pc
+				JOIN categories c ON c.id = pc.category_id
+				WHERE pc.post_id = posts.id
+				ORDER BY c.position, c.id
+			)
+		);`,
+		`DROP TABLE IF EXISTS post_categories;`,
+		`DROP TABLE IF EXISTS categories;`,
+	)(tx)
+}
+
+// searchIndexes lists each full-text table of migration 13 with the content
+// table it mirrors
+var searchIndexes = []struct {
+	name    string
+	source  string
+	columns []string
+}{
+	{"posts_fts", "posts", []string{"title", "content"}},
+	{"comments_fts", "comments", []string{"content"}},
+	{"messages_fts", "private_messages", []string{"content"}},
+}
+
+// migrateSearchIndexUp creates the external-content FTS5 tables over posts,
+// comments and private messages, the triggers that keep them in sync, and
+// fills new ones from their source tables. It fails when SQLite was built
+// without FTS5, so that the migration is never recorded without its index.
+func migrateSearchIndexUp(tx *sql.Tx) error {
+	available, err := FTS5Available(tx)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return errors.New("SQLite was built without FTS5, which the search index needs; build with -tags sqlite_fts5")
+	}
+
+	for _, index := range searchIndexes {
+		exists, err := TableExists(tx, index.name)
+		if err != nil {
+			return err
+		}
+
+		cols := strings.Join(index.columns, ", ")
+		newCols := "new." + strings.Join(index.columns, ", new.")
+		oldCols := "old." + strings.Join(index.columns, ", old.")
+
+		err = execAll(
+			fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s', content_rowid='id', tokenize='unicode61 remove_diacritics 2');`,
+				index.name, cols, index.source),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN
+				INSERT INTO %s(rowid, %s) VALUES (new.id, %s);
+			END;`, index.name, index.source, index.name, cols, newCols),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s_ad AFTER DELETE ON %s BEGIN
+				INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.id, %s);
+			END;`, index.name, index.source, index.name, index.name, cols, oldCols),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE ON %s BEGIN
+				INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.id, %s);
+				INSERT INTO %s(rowid, %s) VALUES (new.id, %s);
+			END;`, index.name, index.source, index.name, index.name, cols, oldCols, index.name, cols, newCols),
+		)(tx)
+		if err != nil {
+			return err
+		}
+
+		// Index rows that existed before the index was created
+		if !exists {
+			_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", index.name, index.name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateSearchIndexDown drops the search index and its triggers
+func migrateSearchIndexDown(tx *sql.Tx) error {
+	for _, index := range searchIndexes {
+		err := execAll(
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_au;`, index.name),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ad;`, index.name),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ai;`, index.name),
+			fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, index.name),
+		)(tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRoomCategoriesUp replaces the category names of rooms with a
+// reference to the categories table. Rooms whose category no longer exists
+// are left without one.
+func migrateRoomCategoriesUp(tx *sql.Tx) error {
+	if err := addColumn(tx, "rooms", "category_id", "INTEGER REFERENCES categories(id)"); err != nil {
+		return err
+	}
+
+	legacy, err := columnExists(tx, "rooms", "category")
+	if err != nil || !legacy {
+		return err
+	}
+	err = execAll(
+		`UPDATE rooms SET category_id = (
+			SELECT id FROM categories WHERE name = TRIM(rooms.category)
+		) WHERE TRIM(COALESCE(category, '')) != '';`,
+	)(tx)
+	if err != nil {
+		return err
+	}
+
+	var orphaned int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM rooms WHERE category_id IS NULL AND TRIM(COALESCE(category, '')) != ''",
+	).Scan(&orphaned)
+	if err != nil {
+		return err
+	}
+	if orphaned > 0 {
+		log.Printf("Left %d rooms without a category because theirs no longer exists", orphaned)
+	}
+
+	return dropColumn(tx, "rooms", "category")
+}
+
+// migrateRoomCategoriesDown turns the category references of rooms back into
+// names
+func migrateRoomCategoriesDown(tx *sql.Tx) error {
+	if err := addColumn(tx, "rooms", "category", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	err := execAll(
+		`UPDATE rooms SET category = COALESCE(
+			(SELECT name FROM categories WHERE id = rooms.category_id), ''
+		);`,
+	)(tx)
+	if err != nil {
+		return err
+	}
+	return dropColumn(tx, "rooms", "category_id")
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding/json"
+	"forum/internal/audit"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Page sizes of the audit log
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// AuditLogHandler lists audit entries, newest first. It filters on the
+// optional "actor_id", "action", "target_type", "target_id", "since" and
+// "until" values and pages with "limit" and "offset".
+func AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, defaultAuditPageSize, maxAuditPageSize)
+	if !ok {
+		return
+	}
+
+	stored, total, err := Stores.Audit.List(filter, limit, offset)
+	if err != nil {
+		log.Println("Failed to load audit log:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load audit log"}, http.StatusInternalServerError)
+		return
+	}
+
+	usernames := make(map[int]string)
+	entries := []model.AuditEntry{}
+	for _, entry := range stored {
+		entries = append(entries, auditView(entry, usernames))
+	}
+
+	util.ExecuteJSON(w, struct {
+		Entries []model.AuditEntry `json:"entries"`
+		Total   int                `json:"total"`
+	}{
+		Entries: entries,
+		Total:   total,
+	}, http.StatusOK)
+}
+
+// ExportAuditLogHandler downloads every audit entry matching the same
+// filters as AuditLogHandler as JSON Lines, oldest first
+func ExportAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+
+	// The status is sent with the first line, so a failure halfway can
+	// only cut the download short
+	usernames := make(map[int]string)
+	encoder := json.NewEncoder(w)
+	err := Stores.Audit.Each(filter, func(entry store.AuditEntry) error {
+		return encoder.Encode(auditView(entry, usernames))
+	})
+	if err != nil {
+		log.Println("Failed to export audit log:", err)
+	}
+}
+
+// auditFilter reads the audit log filters of a request, writing a 400
+// response and returning false when one is invalid. Times are RFC 3339 or
+// plain dates; until=2024-05-01 includes all of May 1st.
+func auditFilter(w http.ResponseWriter, r *http.Request) (store.AuditFilter, bool) {
+	query := r.URL.Query()
+	filter := store.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+
+	for name, field := range map[string]*int{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id <= 0 {
+				util.ExecuteJSON(w, model.MsgData{"Invalid " + name}, http.StatusBadRequest)
+				return filter, false
+			}
+			*field = id
+		}
+	}
+
+	for name, field := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				t, err = time.Parse("2006-01-02", value)
+				// Until is exclusive, a plain date includes the whole day
+				if err == nil && name == "until" {
+					t = t.AddDate(0, 0, 1)
+				}
+			}
+			if err != nil {
+				util.ExecuteJSON(w, model.MsgData{"Invalid " + name + " time"}, http.StatusBadRequest)
+				return filter, false
+			}
+			*field = t
+		}
+	}
+
+	return filter, true
+}
+
+// recordAudit appends a privileged action to the audit log with snapshots of
+// its target before and after, nil for a target that did not exist. The
+// action has already happened, so failing to record it is only logged.
+func recordAudit(actorID int, action, targetType string, targetID int, before, after interface{}) {
+	entry := store.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	var err error
+	if entry.Before, err = audit.Snapshot(before); err == nil {
+		entry.After, err = audit.Snapshot(after)
+	}
+	if err == nil {
+		_, err = Stores.Audit.Append(entry)
+	}
+	if err != nil {
+		log.Printf("Failed to record %s by user %d in the audit log: %v", action, actorID, err)
+	}
+}
+
+// auditView converts a stored audit entry to what admins are shown,
+// caching actor names in usernames
+func auditView(entry store.AuditEntry, usernames map[int]string) model.AuditEntry {
+	actor, ok := usernames[entry.ActorID]
+	if !ok {
+		actor, _ = Stores.Users.Username(entry.ActorID)
+		usernames[entry.ActorID] = actor
+	}
+
+	view := model.AuditEntry{
+		ID:         entry.ID,
+		ActorID:    entry.ActorID,
+		Actor:      actor,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		CreatedAt:  entry.CreatedAt.Format(time.RFC3339),
+	}
+	if entry.Before != "" {
+		view.Before = json.RawMessage(entry.Before)
+	}
+	if entry.After != "" {
+		view.After = json.RawMessage(entry.After)
+	}
+	return view
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuditFilterDates(t *testing.T) {
+	for query, want := range map[string]time.Time{
+		"until=2024-05-01":                  time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		"until=2024-05-01T12:00:00Z":        time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		"since=2024-05-01&until=2024-05-31": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		w := httptest.NewRecorder()
+		filter, ok := auditFilter(w, httptest.NewRequest("GET", "/admin/audit?"+query, nil))
+		if !ok {
+			t.Fatalf("%s: rejected with status %d", query, w.Code)
+		}
+		if !filter.Until.Equal(want) {
+			t.Errorf("%s: until is %v, want %v", query, filter.Until, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	filter, ok := auditFilter(w, httptest.NewRequest("GET", "/admin/audit?since=2024-05-01", nil))
+	if !ok || !filter.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("since of a plain date is %v, want the start of the day", filter.Since)
+	}
+
+	w = httptest.NewRecorder()
+	if _, ok := auditFilter(w, httptest.NewRequest("GET", "/admin/audit?until=May", nil)); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("an invalid until was accepted")
+	}
+}
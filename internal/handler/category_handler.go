@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/audit"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/user"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits for category fields
+const (
+	maxCategoryNameLength        = 50
+	maxCategoryDescriptionLength = 300
+)
+
+// CategoriesHandler lists the categories in display order. Admins can add
+// archived=true to include archived categories.
+func CategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	includeArchived := false
+	if r.URL.Query().Get("archived") == "true" {
+		if _, ok := requirePermission(w, r, user.ManageCategories); !ok {
+			return
+		}
+		includeArchived = true
+	}
+
+	categories, err := Stores.Categories.List(includeArchived)
+	if err != nil {
+		log.Println("Failed to load categories:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load categories"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Categories []model.Category `json:"categories"`
+	}{
+		Categories: categories,
+	}, http.StatusOK)
+}
+
+// CreateCategoryHandler adds a category with a description
+func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, description, ok := categoryFields(w, r)
+	if !ok {
+		return
+	}
+
+	categoryID, err := Stores.Categories.Create(name, description)
+	if err != nil {
+		categoryError(w, err, "Category creation failed")
+		return
+	}
+	if created, err := Stores.Categories.Get(categoryID); err == nil {
+		recordAudit(permittedUserID(r), audit.ActionCreateCategory, audit.TargetCategory, categoryID, nil, created)
+	}
+
+	util.ExecuteJSON(w, struct {
+		Message string `json:"message"`
+		ID      int    `json:"id"`
+	}{
+		Message: "Category created successfully",
+		ID:      categoryID,
+	}, http.StatusOK)
+}
+
+// UpdateCategoryHandler renames a category or changes its description
+func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	categoryID, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	name, description, ok := categoryFields(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := Stores.Categories.Get(categoryID)
+	if err != nil {
+		categoryError(w, err, "Category update failed")
+		return
+	}
+
+	if err := Stores.Categories.Update(categoryID, name, description); err != nil {
+		categoryError(w, err, "Category update failed")
+		return
+	}
+
+	after := before
+	after.Name, after.Description = name, description
+	recordAudit(permittedUserID(r), audit.ActionUpdateCategory, audit.TargetCategory, categoryID, before, after)
+
+	util.ExecuteJSON(w, model.MsgData{"Category updated successfully"}, http.StatusOK)
+}
+
+// ReorderCategoriesHandler changes the display order. The "ids"
+// field lists category IDs, comma-separated, in their new order.
+func ReorderCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var categoryIDs []int
+	for _, value := range strings.Split(r.FormValue("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || id <= 0 {
+			util.ExecuteJSON(w, model.MsgData{"Invalid category ID list"}, http.StatusBadRequest)
+			return
+		}
+		categoryIDs = append(categoryIDs, id)
+	}
+
+	before, err := categoryOrder()
+	if err != nil {
+		categoryError(w, err, "Category reorder failed")
+		return
+	}
+
+	if err := Stores.Categories.Reorder(categoryIDs); err != nil {
+		categoryError(w, err, "Category reorder failed")
+		return
+	}
+
+	if after, err := categoryOrder(); err == nil {
+		recordAudit(permittedUserID(r), audit.ActionReorderCategories, audit.TargetCategory, 0, before, after)
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Categories reordered successfully"}, http.StatusOK)
+}
+
+// ArchiveCategoryHandler archives a category, or restores it
+// when "archived" is false
+func ArchiveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	categoryID, ok := categoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := Stores.Categories.Get(categoryID)
+	if err != nil {
+		categoryError(w, err, "Category update failed")
+		return
+	}
+
+	archived := r.FormValue("archived") != "false"
+	if err := Stores.Categories.SetArchived(categoryID, archived); err != nil {
+		categoryError(w, err, "Category update failed")
+		return
+	}
+
+	after := before
+	after.Archived = archived
+	action := audit.ActionArchiveCategory
+	if !archived {
+		action = audit.ActionRestoreCategory
+	}
+	recordAudit(permittedUserID(r), action, audit.TargetCategory, categoryID, before, after)
+
+	if archived {
+		util.ExecuteJSON(w, model.MsgData{"Category archived"}, http.StatusOK)
+	} else {
+		util.ExecuteJSON(w, model.MsgData{"Category restored"}, http.StatusOK)
+	}
+}
+
+// categoryOrder returns the IDs of every category, archived ones included,
+// in display order
+func categoryOrder() ([]int, error) {
+	categories, err := Stores.Categories.List(true)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(categories))
+	for _, c := range categories {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// categoryIDParam reads the "category_id" form value
+func categoryIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil || categoryID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid category ID"}, http.StatusBadRequest)
+		return 0, false
+	}
+	return categoryID, true
+}
+
+// categoryFields reads and validates the name and description form values
+func categoryFields(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	description := strings.TrimSpace(r.FormValue("description"))
+
+	if name == "" || utf8.RuneCountInString(name) > maxCategoryNameLength {
+		util.ExecuteJSON(w, model.MsgData{"Category name must be between 1 and 50 characters"}, http.StatusBadRequest)
+		return "", "", false
+	}
+	if utf8.RuneCountInString(description) > maxCategoryDescriptionLength {
+		util.ExecuteJSON(w, model.MsgData{"Category description is too long"}, http.StatusBadRequest)
+		return "", "", false
+	}
+	return name, description, true
+}
+
+// categoryError maps category store errors to JSON responses
+func categoryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrDuplicateCategory):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusConflict)
+	case errors.Is(err, store.ErrCategoryNotFound):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+	default:
+		log.Println(fallback+":", err)
+		util.ExecuteJSON(w, model.MsgData{fallback}, http.StatusInternalServerError)
+	}
+}
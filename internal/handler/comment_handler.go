@@ -1,12 +1,13 @@
 package handler
 
 import (
-	"forum/internal/comment"
 	"forum/internal/model"
-	"forum/internal/session"
+	"forum/internal/store"
 	"forum/internal/util"
+	"forum/internal/websocket"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -17,15 +18,15 @@ func CommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID, err := session.GetUserIDFromSession(r)
+	sessionID, err := Sessions.GetUserIDFromSession(r)
     if err != nil || sessionID == 0 {
         log.Println("Unauthorized access attempt to view post")
         util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
         return
     }
 
-	postID := r.FormValue("post_id")
-	if postID == "" {
+	postParam := r.FormValue("post_id")
+	if postParam == "" {
 		util.ExecuteJSON(w, model.MsgData{"Post ID is missing"}, http.StatusBadRequest)
 		return
 	}
@@ -36,12 +37,54 @@ func CommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := comment.AddComment(sessionID, postID, content); err != nil {
+	// Optional comment being replied to
+	parentID := 0
+	if parentParam := r.FormValue("parent_comment_id"); parentParam != "" {
+		parentID, err = strconv.Atoi(parentParam)
+		if err != nil || parentID <= 0 {
+			util.ExecuteJSON(w, model.MsgData{"Invalid parent comment ID"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// A post ID that is not a number cannot match any post
+	postID, err := strconv.Atoi(postParam)
+	var commentID int
+	if err != nil {
+		err = store.ErrPostNotFound
+	} else {
+		commentID, err = Stores.Comments.Create(sessionID, postID, parentID, content)
+	}
+	if err == store.ErrPostNotFound {
+		util.ExecuteJSON(w, model.MsgData{"Post not found"}, http.StatusNotFound)
+		return
+	} else if err == store.ErrCommentNotFound {
+		util.ExecuteJSON(w, model.MsgData{"Parent comment not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
 		log.Println("Failed to add comment:", err)
 		util.ExecuteJSON(w, model.MsgData{"Failed to add the comment"}, http.StatusInternalServerError)
 		return
 	}
 
+	// Let the parent comment's author know about the reply, unless nobody
+	// else can see it
+	if parentID > 0 && !shadowbanned(sessionID) {
+		notifyCommentReply(sessionID, postID, parentID, commentID)
+	}
+
 	// Send success response
 	util.ExecuteJSON(w, model.MsgData{"Comment added successfully"}, http.StatusOK)
-}
\ No newline at end of file
+}
+
+// notifyCommentReply notifies the parent comment's author of a reply unless
+// they replied to themselves
+func notifyCommentReply(replierID, postID, parentID, replyID int) {
+	authorID, err := Stores.Comments.AuthorID(parentID)
+	if err != nil || authorID == replierID {
+		return
+	}
+
+	replierName, _ := Stores.Users.Username(replierID)
+	websocket.PushCommentReply(WebSocketHub, authorID, postID, parentID, replyID, replierName)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestComment(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	bobID, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+
+	comment := func(sessionID string, form url.Values) (int, response) {
+		return submit(t, CommentHandler, sessionID, form)
+	}
+
+	code, body := comment("", url.Values{"post_id": {strconv.Itoa(postID)}, "content": {"Hi"}})
+	expect(t, "commenting logged out", code, body, http.StatusUnauthorized)
+
+	code, body = comment(bob, url.Values{"post_id": {strconv.Itoa(postID)}, "content": {" "}})
+	expect(t, "posting an empty comment", code, body, http.StatusBadRequest)
+
+	code, body = comment(bob, url.Values{"post_id": {strconv.Itoa(postID + 1)}, "content": {"Hi"}})
+	expect(t, "commenting on a missing post", code, body, http.StatusNotFound)
+
+	code, body = comment(bob, url.Values{"post_id": {"abc"}, "content": {"Hi"}})
+	expect(t, "commenting on an invalid post ID", code, body, http.StatusNotFound)
+
+	code, body = comment(bob, url.Values{"post_id": {strconv.Itoa(postID)}, "content": {"Hi"}, "parent_comment_id": {"42"}})
+	expect(t, "replying to a missing comment", code, body, http.StatusNotFound)
+
+	code, body = comment(bob, url.Values{"post_id": {strconv.Itoa(postID)}, "content": {"Nice post"}})
+	expect(t, "commenting", code, body, http.StatusOK)
+
+	comments, err := Stores.Comments.ListForPost(postID)
+	if err != nil {
+		t.Fatalf("loading the comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Content != "Nice post" {
+		t.Fatalf("post comments are %+v, want the new comment", comments)
+	}
+
+	parentID := comments[0].ID
+	code, body = comment(alice, url.Values{
+		"post_id":           {strconv.Itoa(postID)},
+		"content":           {"Thanks"},
+		"parent_comment_id": {strconv.Itoa(parentID)},
+	})
+	expect(t, "replying", code, body, http.StatusOK)
+
+	reply, err := Stores.Comments.Get(parentID + 1)
+	if err != nil {
+		t.Fatalf("loading the reply: %v", err)
+	}
+	if reply.ParentID != parentID || reply.UserID != aliceID {
+		t.Errorf("stored reply is %+v", reply)
+	}
+
+	// Bob is offline, so the reply waits for his next connection
+	frames, err := Stores.Notifications.Take(bobID)
+	if err != nil {
+		t.Fatalf("loading the notifications of bob: %v", err)
+	}
+	var notification struct {
+		Type    string `json:"type"`
+		ReplyID int    `json:"reply_id"`
+	}
+	if len(frames) != 1 {
+		t.Fatalf("bob has %d notifications, want 1", len(frames))
+	}
+	if err := json.Unmarshal(frames[0], &notification); err != nil || notification.Type != "comment_reply" || notification.ReplyID != reply.ID {
+		t.Errorf("notification of bob is %s (%v), want the reply", frames[0], err)
+	}
+}
+
+func TestEditComment(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+
+	commentID, err := Stores.Comments.Create(aliceID, postID, 0, "Typo")
+	if err != nil {
+		t.Fatalf("creating a comment: %v", err)
+	}
+	id := strconv.Itoa(commentID)
+
+	code, body := submit(t, EditCommentHandler, bob, url.Values{"comment_id": {id}, "content": {"Mine now"}})
+	expect(t, "editing someone else's comment", code, body, http.StatusForbidden)
+
+	code, body = submit(t, EditCommentHandler, alice, url.Values{"comment_id": {id}, "content": {""}})
+	expect(t, "blanking a comment", code, body, http.StatusBadRequest)
+
+	code, body = submit(t, EditCommentHandler, alice, url.Values{"comment_id": {strconv.Itoa(commentID + 1)}, "content": {"Fixed"}})
+	expect(t, "editing a missing comment", code, body, http.StatusNotFound)
+
+	code, body = submit(t, EditCommentHandler, alice, url.Values{"comment_id": {id}, "content": {"Fixed"}})
+	expect(t, "editing the comment", code, body, http.StatusOK)
+
+	c, err := Stores.Comments.Get(commentID)
+	if err != nil {
+		t.Fatalf("loading the comment: %v", err)
+	}
+	if c.Content != "Fixed" || c.EditedAt == "" {
+		t.Errorf("edited comment is %+v", c)
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+
+	commentID, err := Stores.Comments.Create(aliceID, postID, 0, "Regrettable")
+	if err != nil {
+		t.Fatalf("creating a comment: %v", err)
+	}
+	id := strconv.Itoa(commentID)
+
+	code, body := submit(t, DeleteCommentHandler, bob, url.Values{"comment_id": {id}})
+	expect(t, "deleting someone else's comment", code, body, http.StatusForbidden)
+
+	code, body = submit(t, DeleteCommentHandler, alice, url.Values{"comment_id": {id}})
+	expect(t, "deleting the comment", code, body, http.StatusOK)
+
+	c, err := Stores.Comments.Get(commentID)
+	if err == nil && !c.Deleted {
+		t.Errorf("deleted comment is still there: %+v", c)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"forum/internal/model"
+	"forum/internal/util"
+	"forum/internal/websocket"
+	"log"
+	"net/http"
+)
+
+// ConversationsHandler returns the chat partners of the logged-in user,
+// ordered by most recent message
+func ConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	conversations, err := websocket.GetConversations(WebSocketHub, userID)
+	if err != nil {
+		log.Println("Failed to load conversations:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load conversations"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Conversations []model.Conversation `json:"conversations"`
+	}{
+		Conversations: conversations,
+	}, http.StatusOK)
+}
@@ -1,20 +1,57 @@
 package handler
 
 import (
-	"forum/internal/database"
+	"errors"
 	"forum/internal/model"
-	"forum/internal/post"
-	"forum/internal/session"
+	"forum/internal/store"
 	"forum/internal/util"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+// postCategories reads the submitted category IDs, defaulting to the first
+// active category. It writes the error response and returns false when a
+// category is unknown or archived.
+func postCategories(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	var categoryIDs []int
+	for _, value := range r.Form["categories"] {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+			return nil, false
+		}
+		categoryIDs = append(categoryIDs, id)
+	}
+
+	if len(categoryIDs) == 0 {
+		defaultID, err := Stores.Categories.DefaultID()
+		if err != nil {
+			log.Println("Failed to load default category:", err)
+			util.ExecuteJSON(w, model.MsgData{"No category available"}, http.StatusInternalServerError)
+			return nil, false
+		}
+		return []int{defaultID}, true
+	}
+
+	err := Stores.Categories.ValidateIDs(categoryIDs)
+	switch {
+	case errors.Is(err, store.ErrCategoryNotFound), errors.Is(err, store.ErrCategoryArchived):
+		util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+		return nil, false
+	case err != nil:
+		log.Println("Failed to validate categories:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to validate categories"}, http.StatusInternalServerError)
+		return nil, false
+	}
+	return categoryIDs, true
+}
+
 // CreatePostHandler handles creating a new post
 func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		userID, err := session.GetUserIDFromSession(r)
+		userID, err := Sessions.GetUserIDFromSession(r)
 		if err != nil {
 			util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
 			return
@@ -22,54 +59,25 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 
 		title := strings.TrimSpace(r.FormValue("title")) 
         content := strings.TrimSpace(r.FormValue("content")) 
-        categories := strings.Join(r.Form["categories"], ", ")
 
 		if title == "" || content == "" {
 			util.ExecuteJSON(w, model.MsgData{"Post cannot be empty"}, http.StatusBadRequest)
 			return
 		}
 
-		categoriesList := []string{
-			"General",
-			"Local News & Events",
-			"Viking line",
-			"Travel",
-			"Sailing",
-			"Cuisine & food",
-			"Politics"}
-
-		for _, userCategory := range r.Form["categories"] {
-			catValid := false
-			for _, categoryItem := range categoriesList {
-				if categoryItem == userCategory {
-					catValid = true
-					break
-				}
-			}
-			if !catValid {
-				util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
-				return
-			}
-		}
-
-		if len(categories) == 0 {
-			categories = "General"
+		categories, ok := postCategories(w, r)
+		if !ok {
+			return
 		}
 
 		// Insert the post into the database
-		if err := post.CreatePost(userID, title, content, categories); err != nil {
+		id, err := Stores.Posts.Create(userID, title, content, categories)
+		if err != nil {
 			log.Println("Post creation failed:", err)
 			util.ExecuteJSON(w, model.MsgData{"Post creation failed"}, http.StatusInternalServerError)
 			return
 		}
 
-		id, err := post.GetPostId()
-		if err != nil {
-			log.Println("Database issue:", err)
-			util.ExecuteJSON(w, model.MsgData{"Database issue"}, http.StatusInternalServerError)
-			return
-		}
-
 		// Return JSON response with the new post ID
 		util.ExecuteJSON(w, struct {
 			Message string `json:"message"`
@@ -79,14 +87,14 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 			ID:      id,
 		}, http.StatusOK)
 	} else if r.Method == "GET" {
-		sessionID, err := session.GetUserIDFromSession(r)
+		sessionID, err := Sessions.GetUserIDFromSession(r)
 		if err != nil {
 			sessionID = 0 // If there's an error, set sessionID to 0
 		}
 
 		var username string
 		if sessionID > 0 {
-			_ = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", sessionID).Scan(&username)
+			username, _ = Stores.Users.Username(sessionID)
 		}
 
 		data := struct {
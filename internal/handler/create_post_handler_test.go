@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCreatePost(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+
+	code, body := submit(t, CreatePostHandler, "", url.Values{"title": {"Hello"}, "content": {"Hi"}})
+	expect(t, "posting logged out", code, body, http.StatusUnauthorized)
+
+	code, body = submit(t, CreatePostHandler, alice, url.Values{"title": {"  "}, "content": {"Hi"}})
+	expect(t, "posting without a title", code, body, http.StatusBadRequest)
+
+	code, body = submit(t, CreatePostHandler, alice, url.Values{"title": {"Hello"}, "content": {"Hi"}, "categories": {"99"}})
+	expect(t, "posting to a missing category", code, body, http.StatusBadRequest)
+
+	postID := newPost(t, alice)
+	p, err := Stores.Posts.Get(postID)
+	if err != nil {
+		t.Fatalf("loading the post: %v", err)
+	}
+	if p.UserID != aliceID || p.Title != "Hello" || p.Content != "First post" {
+		t.Errorf("stored post is %+v", p)
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"forum/internal/model"
+	"forum/internal/session"
+	"forum/internal/util"
+	"net/http"
+)
+
+// RequireCSRFToken guards every request that can change state (anything but
+// GET, HEAD, OPTIONS and TRACE) so that only pages of the forum itself can
+// make it. They get the token from /user/status and send it back in the
+// X-CSRF-Token header or the csrf_token form field.
+func RequireCSRFToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD", "OPTIONS", "TRACE":
+		default:
+			if !session.ValidCSRFToken(r) {
+				util.ExecuteJSON(w, model.MsgData{"Invalid or missing CSRF token, please reload the page"}, http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// EditCommentHandler lets the author change the content of a comment
+func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("comment_id"))
+	if err != nil || commentID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Comment ID is missing"}, http.StatusBadRequest)
+		return
+	}
+
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		util.ExecuteJSON(w, model.MsgData{"Content is missing"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := Stores.Comments.Update(commentID, userID, content); err != nil {
+		commentError(w, err, "Failed to update the comment")
+		return
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Comment updated successfully"}, http.StatusOK)
+}
+
+// DeleteCommentHandler lets the author remove a comment
+func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("comment_id"))
+	if err != nil || commentID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Comment ID is missing"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := Stores.Comments.Delete(commentID, userID); err != nil {
+		commentError(w, err, "Failed to delete the comment")
+		return
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Comment deleted successfully"}, http.StatusOK)
+}
+
+// commentError maps comment store errors to JSON responses
+func commentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrNotCommentOwner):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusForbidden)
+	case errors.Is(err, store.ErrCommentNotFound), errors.Is(err, store.ErrPostNotFound):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+	default:
+		log.Println(fallback+":", err)
+		util.ExecuteJSON(w, model.MsgData{fallback}, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// EditPostHandler lets the author change the title, content and categories of a post
+func EditPostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid PostID"}, http.StatusBadRequest)
+		return
+	}
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	if title == "" || content == "" {
+		util.ExecuteJSON(w, model.MsgData{"Post cannot be empty"}, http.StatusBadRequest)
+		return
+	}
+
+	categories, ok := postCategories(w, r)
+	if !ok {
+		return
+	}
+
+	if err := Stores.Posts.Update(postID, userID, title, content, categories); err != nil {
+		postError(w, err, "Post update failed")
+		return
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Post updated successfully"}, http.StatusOK)
+}
+
+// DeletePostHandler lets the author remove a post along with its comments and reactions
+func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid PostID"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := Stores.Posts.Delete(postID, userID); err != nil {
+		postError(w, err, "Post deletion failed")
+		return
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Post deleted successfully"}, http.StatusOK)
+}
+
+// PostRevisionsHandler returns the edit history of a post
+func PostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || sessionID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || postID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid PostID"}, http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := Stores.Posts.Revisions(postID)
+	if err != nil {
+		postError(w, err, "Failed to load revisions")
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		PostID    int              `json:"postID"`
+		Revisions []model.Revision `json:"revisions"`
+	}{
+		PostID:    postID,
+		Revisions: revisions,
+	}, http.StatusOK)
+}
+
+// postError maps post store errors to JSON responses
+func postError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrNotPostOwner):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusForbidden)
+	case errors.Is(err, store.ErrPostNotFound):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+	default:
+		log.Println(fallback+":", err)
+		util.ExecuteJSON(w, model.MsgData{fallback}, http.StatusInternalServerError)
+	}
+}
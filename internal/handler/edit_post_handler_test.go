@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/store"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestEditPost(t *testing.T) {
+	setup(t)
+	_, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+
+	edit := func(sessionID string, postID int) (int, response) {
+		return submit(t, EditPostHandler, sessionID, url.Values{
+			"post_id": {strconv.Itoa(postID)},
+			"title":   {"Hello again"},
+			"content": {"Edited"},
+		})
+	}
+
+	code, body := edit(bob, postID)
+	expect(t, "editing someone else's post", code, body, http.StatusForbidden)
+
+	code, body = edit(alice, postID+1)
+	expect(t, "editing a missing post", code, body, http.StatusNotFound)
+
+	code, body = submit(t, EditPostHandler, alice, url.Values{"post_id": {strconv.Itoa(postID)}, "title": {"Hello"}, "content": {""}})
+	expect(t, "blanking a post", code, body, http.StatusBadRequest)
+
+	code, body = edit(alice, postID)
+	expect(t, "editing the post", code, body, http.StatusOK)
+
+	p, err := Stores.Posts.Get(postID)
+	if err != nil {
+		t.Fatalf("loading the post: %v", err)
+	}
+	if p.Title != "Hello again" || p.Content != "Edited" {
+		t.Errorf("edited post is %+v", p)
+	}
+	revisions, err := Stores.Posts.Revisions(postID)
+	if err != nil || len(revisions) != 1 {
+		t.Errorf("got revisions %+v (%v), want the original version", revisions, err)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	setup(t)
+	_, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+
+	code, body := submit(t, DeletePostHandler, bob, url.Values{"post_id": {strconv.Itoa(postID)}})
+	expect(t, "deleting someone else's post", code, body, http.StatusForbidden)
+
+	code, body = submit(t, DeletePostHandler, alice, url.Values{"post_id": {strconv.Itoa(postID)}})
+	expect(t, "deleting the post", code, body, http.StatusOK)
+
+	if _, err := Stores.Posts.Get(postID); !errors.Is(err, store.ErrPostNotFound) {
+		t.Errorf("loading the deleted post: got %v, want %v", err, store.ErrPostNotFound)
+	}
+
+	code, body = submit(t, DeletePostHandler, alice, url.Values{"post_id": {strconv.Itoa(postID)}})
+	expect(t, "deleting the post again", code, body, http.StatusNotFound)
+}
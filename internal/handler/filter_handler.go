@@ -1,12 +1,11 @@
 package handler
 
 import (
-	"database/sql"
-	"forum/internal/database"
 	"forum/internal/model"
-	"forum/internal/session"
 	"forum/internal/util"
+	"log"
 	"net/http"
+	"strconv"
 )
 
 // FilterHandler handles filtering posts by category
@@ -16,60 +15,65 @@ func FilterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID, err := session.GetUserIDFromSession(r)
+	sessionID, err := Sessions.GetUserIDFromSession(r)
 	if err != nil || sessionID == 0 {
 		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
 		return
 	}
 
 	// Get logged-in user details
-	var username string
-	if sessionID > 0 {
-		_ = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", sessionID).Scan(&username)
-	}
+	username, _ := Stores.Users.Username(sessionID)
 
 	// Get filter parameters
-	category := r.URL.Query().Get("category")
+	categoryParam := r.URL.Query().Get("category_id")
 	userCreated := r.URL.Query().Get("user_created") == "true"
 	liked := r.URL.Query().Get("liked") == "true"
 
-	var rows *sql.Rows
-	var queryErr error
+	var posts []model.PostData
+	var categoryName string
 
-	// Determine which query to run based on filter
+	// Determine which posts to load based on filter
 	switch {
-	case userCreated && sessionID > 0:
-		rows, queryErr = database.Db.Query("SELECT id, title, category FROM posts WHERE user_id = ?", sessionID)
-	case liked && sessionID > 0:
-		rows, queryErr = database.Db.Query(`
-			SELECT p.id, p.title, p.category 
-			FROM posts p
-			JOIN reactions r ON p.id = r.post_id 
-			WHERE r.user_id = ? AND r.type = 'like'
-		`, sessionID)
-	case category != "":
-		rows, queryErr = database.Db.Query("SELECT id, title, category FROM posts WHERE category LIKE ?", "%"+category+"%")
+	case userCreated:
+		posts, err = Stores.Posts.ListByAuthor(sessionID)
+	case liked:
+		posts, err = Stores.Posts.ListLikedBy(sessionID)
+	case categoryParam != "":
+		categoryID, convErr := strconv.Atoi(categoryParam)
+		if convErr != nil {
+			util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+			return
+		}
+		selected, getErr := Stores.Categories.Get(categoryID)
+		if getErr != nil {
+			categoryError(w, getErr, "Failed to fetch posts")
+			return
+		}
+		categoryName = selected.Name
+		posts, err = Stores.Posts.ListByCategory(categoryID)
 	default:
 		util.ExecuteJSON(w, model.MsgData{"Invalid filter request"}, http.StatusBadRequest)
 		return
 	}
 
-	if queryErr != nil {
+	if err != nil {
+		log.Println("Failed to fetch posts:", err)
 		util.ExecuteJSON(w, model.MsgData{"Failed to fetch posts"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	// Collect filtered posts
-	var posts []model.PostData
-	for rows.Next() {
-		var post model.PostData
-		if err := rows.Scan(&post.ID, &post.Title, &post.Category); err != nil {
-			util.ExecuteJSON(w, model.MsgData{"Failed to scan post"}, http.StatusInternalServerError)
-			return
+	// Leave out the posts of shadowbanned users
+	hidden, ok := hiddenAuthors(w, sessionID)
+	if !ok {
+		return
+	}
+	visible := posts[:0]
+	for _, p := range posts {
+		if !isHidden(hidden, p.UserID) {
+			visible = append(visible, p)
 		}
-		posts = append(posts, post)
 	}
+	posts = visible
 
 	// Send JSON response
 	util.ExecuteJSON(w, struct {
@@ -78,7 +82,7 @@ func FilterHandler(w http.ResponseWriter, r *http.Request) {
 		SessionID int             `json:"sessionID"`
 		Username  string          `json:"username"`
 	}{
-		Category:  category,
+		Category:  categoryName,
 		Posts:     posts,
 		SessionID: sessionID,
 		Username:  username,
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"forum/internal/session"
+	"forum/internal/store"
+	"time"
+)
+
+// Stores is the data layer the handlers read from and write to
+var Stores store.Stores
+
+// Sessions resolves the logged-in user of a request
+var Sessions *session.Manager
+
+// Init sets the data layer used by the handlers and how long login sessions
+// last. It must be called before InitWebSocketHub and before serving requests.
+func Init(stores store.Stores, sessionLifetime time.Duration) {
+	Stores = stores
+	Sessions = session.NewManager(stores.Sessions, stores.Sanctions, sessionLifetime)
+}
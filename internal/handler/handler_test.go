@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/model"
+	"forum/internal/store/memory"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setup points the handlers at fresh in-memory stores, holding one
+// category, and a running websocket hub
+func setup(t *testing.T) {
+	t.Helper()
+	Init(memory.NewStores(), time.Hour)
+	if _, err := Stores.Categories.Create("General", "Anything goes"); err != nil {
+		t.Fatalf("creating a category: %v", err)
+	}
+	InitWebSocketHub()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		WebSocketHub.Shutdown(ctx, "test finished")
+	})
+}
+
+// newUser registers a user with the given role and returns their ID and a
+// logged-in session ID
+func newUser(t *testing.T, username, role string) (int, string) {
+	t.Helper()
+	userID, err := Stores.Users.Create(model.User{
+		Username: username,
+		Email:    username + "@example.com",
+	}, "hash")
+	if err != nil {
+		t.Fatalf("creating %s: %v", username, err)
+	}
+	if err := Stores.Users.SetRole(userID, role); err != nil {
+		t.Fatalf("setting the role of %s: %v", username, err)
+	}
+
+	return userID, login(t, userID)
+}
+
+// login starts a session for a user and returns its ID
+func login(t *testing.T, userID int) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/login", nil)
+	if err := Sessions.CreateSession(w, r, userID); err != nil {
+		t.Fatalf("logging in user %d: %v", userID, err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			return cookie.Value
+		}
+	}
+	t.Fatalf("no session cookie for user %d", userID)
+	return ""
+}
+
+// response is the decoded JSON body of a handler response
+type response struct {
+	Message  string `json:"message"`
+	ID       int    `json:"id"`
+	Resolved int    `json:"resolved"`
+}
+
+// submit sends a form to a handler as the user of the session, which may be
+// empty, and returns the status code and decoded body
+func submit(t *testing.T, h http.HandlerFunc, sessionID string, form url.Values) (int, response) {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if sessionID != "" {
+		r.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	}
+
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	var body response
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding the response: %v", err)
+	}
+	return w.Code, body
+}
+
+// expect fails the test unless a response has the wanted status code
+func expect(t *testing.T, what string, code int, body response, want int) {
+	t.Helper()
+	if code != want {
+		t.Fatalf("%s: got status %d (%q), want %d", what, code, body.Message, want)
+	}
+}
+
+// newPost creates a post by the user of the session and returns its ID
+func newPost(t *testing.T, sessionID string) int {
+	t.Helper()
+	code, body := submit(t, CreatePostHandler, sessionID, url.Values{
+		"title":   {"Hello"},
+		"content": {"First post"},
+	})
+	expect(t, "creating a post", code, body, http.StatusOK)
+	return body.ID
+}
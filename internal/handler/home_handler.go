@@ -1,10 +1,8 @@
 package handler
 
 import (
-	"forum/internal/database"
 	"forum/internal/model"
 	"forum/internal/post"
-	"forum/internal/session"
 	"forum/internal/util"
 	"net/http"
 )
@@ -16,7 +14,7 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get session and validate
-	sessionID, err := session.GetUserIDFromSession(r)
+	sessionID, err := Sessions.GetUserIDFromSession(r)
 	if err != nil || sessionID == 0 {
 		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
 		return
@@ -25,29 +23,31 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	// Get username for the logged-in user
 	var username string
 	if sessionID > 0 {
-		err = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", sessionID).Scan(&username)
+		username, err = Stores.Users.Username(sessionID)
 		if err != nil {
 			username = "" // Continue even if username fetch fails
 		}
 	}
 
-	// Fetch posts
-	allPosts, err := post.FetchPosts()
-	if err != nil {
-		util.ExecuteJSON(w, model.MsgData{"Failed to load posts"}, http.StatusInternalServerError)
+	// Fetch the first page of posts; the client loads further pages from /posts
+	opts, ok := listOptions(w, r, post.SortTop)
+	if !ok {
 		return
 	}
-	
-	// Limit to 5 most recent/popular posts
-	if len(allPosts) > 5 {
-		allPosts = allPosts[:5]
+	if opts.HiddenAuthors, ok = hiddenAuthors(w, sessionID); !ok {
+		return
+	}
+	allPosts, nextCursor, ok := listPosts(w, opts)
+	if !ok {
+		return
 	}
 
 	// Prepare response data
 	data := model.Data{
-		Posts:     allPosts,
-		SessionID: sessionID,
-		Username:  username,
+		Posts:      allPosts,
+		NextCursor: nextCursor,
+		SessionID:  sessionID,
+		Username:   username,
 	}
 
 	// Set proper content type and return JSON response
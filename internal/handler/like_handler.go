@@ -2,10 +2,10 @@ package handler
 
 import (
 	"forum/internal/model"
-	"forum/internal/reaction"
-	"forum/internal/session"
+	"forum/internal/store"
 	"forum/internal/util"
 	"net/http"
+	"strconv"
 )
 
 // LikeHandler handles liking or disliking a post or comment
@@ -16,25 +16,34 @@ func LikeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate session
-	sessionID, err := session.GetUserIDFromSession(r)
+	sessionID, err := Sessions.GetUserIDFromSession(r)
 	if err != nil {
 		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
 		return
 	}
 
 	// Get request parameters
-	itemID := r.FormValue("item_id")
+	itemParam := r.FormValue("item_id")
 	isComment := r.FormValue("is_comment") == "true"
 	reactionType := r.FormValue("type") // "like" or "dislike"
 
 	// Validate inputs
-	if itemID == "" {
+	if itemParam == "" {
 		util.ExecuteJSON(w, model.MsgData{"Item ID is missing"}, http.StatusBadRequest)
 		return
 	}
 
 	// Process reaction
-	if err := reaction.LikeItem(sessionID, itemID, isComment, reactionType); err != nil {
+	itemID, err := strconv.Atoi(itemParam)
+	if err != nil {
+		err = store.ErrItemNotFound
+	} else {
+		err = Stores.Reactions.Toggle(sessionID, itemID, isComment, reactionType)
+	}
+	if err == store.ErrItemNotFound {
+		util.ExecuteJSON(w, model.MsgData{"Item not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
 		util.ExecuteJSON(w, model.MsgData{"Failed to process reaction"}, http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/post"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListPostsHandler returns one page of posts. Supported query parameters are
+// sort (newest, top, comments, hot), window for the top sort (day, week,
+// month, year, all), category_id, author, limit and the cursor of the next page.
+func ListPostsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || sessionID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
+		return
+	}
+
+	opts, ok := listOptions(w, r, post.SortNewest)
+	if !ok {
+		return
+	}
+	if opts.HiddenAuthors, ok = hiddenAuthors(w, sessionID); !ok {
+		return
+	}
+
+	posts, nextCursor, ok := listPosts(w, opts)
+	if !ok {
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Posts      []model.HomePageData `json:"posts"`
+		NextCursor string               `json:"next_cursor"`
+		Sort       string               `json:"sort"`
+	}{
+		Posts:      posts,
+		NextCursor: nextCursor,
+		Sort:       opts.Sort,
+	}, http.StatusOK)
+}
+
+// listOptions reads the listing parameters of a request, writing a 400
+// response and returning false when one is invalid
+func listOptions(w http.ResponseWriter, r *http.Request, defaultSort string) (post.ListOptions, bool) {
+	query := r.URL.Query()
+	opts := post.ListOptions{
+		Sort:   query.Get("sort"),
+		Window: query.Get("window"),
+		Author: strings.TrimSpace(query.Get("author")),
+		Cursor: query.Get("cursor"),
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = defaultSort
+	}
+	if !post.IsValidSort(opts.Sort) {
+		util.ExecuteJSON(w, model.MsgData{"Invalid sort mode"}, http.StatusBadRequest)
+		return opts, false
+	}
+
+	if opts.Window == "" {
+		opts.Window = "all"
+	}
+	if _, ok := post.Windows[opts.Window]; !ok {
+		util.ExecuteJSON(w, model.MsgData{"Invalid time window"}, http.StatusBadRequest)
+		return opts, false
+	}
+
+	if categoryParam := query.Get("category_id"); categoryParam != "" {
+		categoryID, err := strconv.Atoi(categoryParam)
+		if err != nil || categoryID <= 0 {
+			util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+			return opts, false
+		}
+		opts.CategoryID = categoryID
+	}
+
+	if limitParam := query.Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > post.MaxPageSize {
+			util.ExecuteJSON(w, model.MsgData{"Invalid limit"}, http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Limit = limit
+	}
+
+	return opts, true
+}
+
+// listPosts loads a page of posts, writing the error response and returning
+// false when that fails
+func listPosts(w http.ResponseWriter, opts post.ListOptions) ([]model.HomePageData, string, bool) {
+	posts, nextCursor, err := Stores.Posts.List(opts)
+	if errors.Is(err, post.ErrInvalidCursor) {
+		util.ExecuteJSON(w, model.MsgData{"Invalid cursor"}, http.StatusBadRequest)
+		return nil, "", false
+	} else if err != nil {
+		log.Println("Failed to list posts:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load posts"}, http.StatusInternalServerError)
+		return nil, "", false
+	}
+	return posts, nextCursor, true
+}
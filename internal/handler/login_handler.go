@@ -1,12 +1,18 @@
 package handler
 
 import (
-	"forum/internal/database"
 	"forum/internal/model"
+	"errors"
+	"forum/internal/moderation"
 	"forum/internal/session"
+	"forum/internal/store"
 	"forum/internal/user"
 	"forum/internal/util"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -25,31 +31,84 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Accounts and addresses with too many failed attempts have to wait
+	account, ip := lockoutKey(identifier), session.ClientIP(r)
+	if wait := max(AccountLockout.Locked(account), IPLockout.Locked(ip)); wait > 0 {
+		tooManyRequests(w, wait, lockedOutMessage(wait))
+		return
+	}
+
 	// Authenticate user
-	userID, err := user.AuthenticateUser(identifier, password)
-	if err != nil {
+	userID, err := user.AuthenticateUser(Stores.Users, identifier, password)
+	if errors.Is(err, user.ErrInvalidCredentials) {
+		if wait := max(AccountLockout.Fail(account), IPLockout.Fail(ip)); wait > 0 {
+			tooManyRequests(w, wait, lockedOutMessage(wait))
+			return
+		}
 		util.ExecuteJSON(w, model.MsgData{"Invalid identifier or password"}, http.StatusUnauthorized)
 		return
+	} else if err != nil {
+		log.Println("Failed to authenticate user:", err)
+		util.ExecuteJSON(w, model.MsgData{"Login failed"}, http.StatusInternalServerError)
+		return
+	}
+	// The address keeps its failures so that logging into an account of
+	// one's own does not allow guessing on
+	AccountLockout.Reset(account)
+
+	// Suspended and banned users learn why they cannot log in
+	sanctions, err := Stores.Sanctions.Active(userID, time.Now())
+	if err != nil {
+		log.Println("Failed to check sanctions:", err)
+		util.ExecuteJSON(w, model.MsgData{"Login failed"}, http.StatusInternalServerError)
+		return
+	}
+	if blocking, blocked := moderation.Blocking(sanctions); blocked {
+		util.ExecuteJSON(w, model.MsgData{moderation.BlockedMessage(blocking)}, http.StatusForbidden)
+		return
 	}
 
 	// Create session
-	if err := session.CreateSession(w, userID); err != nil {
+	if err := Sessions.CreateSession(w, r, userID); err != nil {
+		util.ExecuteJSON(w, model.MsgData{"Session creation failed"}, http.StatusInternalServerError)
+		return
+	}
+
+	// A token learned before logging in must not work with the new session
+	csrfToken, err := session.RotateCSRFToken(w)
+	if err != nil {
+		log.Println("Failed to rotate CSRF token:", err)
 		util.ExecuteJSON(w, model.MsgData{"Session creation failed"}, http.StatusInternalServerError)
 		return
 	}
 
 	// Get username for response
-	var username string
-	_ = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+	username, _ := Stores.Users.Username(userID)
 
 	// Send successful login response
 	util.ExecuteJSON(w, struct {
 		Message   string `json:"message"`
 		SessionID int    `json:"sessionID"`
 		Username  string `json:"username"`
+		CSRFToken string `json:"csrfToken"`
 	}{
 		Message:   "Login successful",
 		SessionID: userID,
 		Username:  username,
+		CSRFToken: csrfToken,
 	}, http.StatusOK)
-}
\ No newline at end of file
+}
+
+// lockoutKey returns what failed logins with an identifier count against:
+// the account it belongs to, so that its email and username share one
+// count, or else the identifier ignoring case and surrounding spaces
+func lockoutKey(identifier string) string {
+	userID, _, err := Stores.Users.Credentials(identifier)
+	if err == nil {
+		return "user:" + strconv.Itoa(userID)
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		log.Println("Failed to look up login:", err)
+	}
+	return "login:" + strings.ToLower(strings.TrimSpace(identifier))
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"forum/internal/model"
+	"forum/internal/ratelimit"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLoginLockoutFollowsTheAccount(t *testing.T) {
+	setup(t)
+	AccountLockout = ratelimit.NewLockout(3, time.Minute, time.Hour)
+	t.Cleanup(func() { AccountLockout = nil })
+
+	hash, err := user.HashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashing: %v", err)
+	}
+	if _, err := Stores.Users.Create(model.User{Username: "alice", Email: "alice@example.com"}, hash); err != nil {
+		t.Fatalf("creating alice: %v", err)
+	}
+
+	attempt := func(identifier, password string) (int, response) {
+		return submit(t, LoginHandler, "", url.Values{"identifier": {identifier}, "password": {password}})
+	}
+
+	// Failures by email and by username add up
+	code, body := attempt("alice@example.com", "guess")
+	expect(t, "first wrong password", code, body, http.StatusUnauthorized)
+	code, body = attempt("alice", "guess")
+	expect(t, "second wrong password", code, body, http.StatusUnauthorized)
+	code, body = attempt("alice@example.com", "guess")
+	expect(t, "third wrong password", code, body, http.StatusTooManyRequests)
+
+	code, body = attempt("alice", "secret")
+	expect(t, "logging in by username while locked out", code, body, http.StatusTooManyRequests)
+
+	// Unknown identifiers are counted ignoring case and spaces
+	attempt("Nobody", "guess")
+	attempt(" nobody", "guess")
+	code, body = attempt("NOBODY ", "guess")
+	expect(t, "guessing at an unknown account", code, body, http.StatusTooManyRequests)
+}
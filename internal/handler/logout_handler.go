@@ -2,7 +2,6 @@ package handler
 
 import (
 	"forum/internal/model"
-	"forum/internal/session"
 	"forum/internal/util"
 	"net/http"
 )
@@ -22,11 +21,14 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete session
-	if err := session.DeleteSession(cookie.Value); err != nil {
+	if err := Sessions.DeleteSession(cookie.Value); err != nil {
 		util.ExecuteJSON(w, model.MsgData{"Logout failed"}, http.StatusInternalServerError)
 		return
 	}
 
+	// Close any chat connections opened with this session
+	WebSocketHub.DisconnectSession(cookie.Value)
+
 	// Clear session cookie
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_id", 
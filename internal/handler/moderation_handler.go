@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/audit"
+	"forum/internal/model"
+	"forum/internal/moderation"
+	"forum/internal/store"
+	"forum/internal/util"
+	"forum/internal/websocket"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Page sizes of the moderation queue
+const (
+	defaultQueuePageSize = 20
+	maxQueuePageSize     = 100
+)
+
+// conversationContext is how many messages up to a reported one the
+// moderation queue shows
+const conversationContext = 5
+
+// ModerationQueueHandler lists open reports, oldest first, with the reported
+// content and its context. It takes optional "limit" and "offset" values.
+func ModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := pageParams(w, r, defaultQueuePageSize, maxQueuePageSize)
+	if !ok {
+		return
+	}
+
+	stored, total, err := Stores.Reports.ListOpen(limit, offset)
+	if err != nil {
+		log.Println("Failed to load reports:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load reports"}, http.StatusInternalServerError)
+		return
+	}
+
+	reports := []model.QueuedReport{}
+	for _, report := range stored {
+		queued, err := queuedReport(report)
+		if err != nil {
+			log.Printf("Failed to load context of report %d: %v", report.ID, err)
+			util.ExecuteJSON(w, model.MsgData{"Failed to load reports"}, http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, queued)
+	}
+
+	util.ExecuteJSON(w, struct {
+		Reports []model.QueuedReport `json:"reports"`
+		Total   int                  `json:"total"`
+	}{
+		Reports: reports,
+		Total:   total,
+	}, http.StatusOK)
+}
+
+// pageParams reads the "limit" and "offset" values of a paginated listing,
+// writing a 400 response and returning false when one is invalid
+func pageParams(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (int, int, bool) {
+	limit, offset := defaultLimit, 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxLimit {
+			util.ExecuteJSON(w, model.MsgData{"Invalid limit"}, http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			util.ExecuteJSON(w, model.MsgData{"Invalid offset"}, http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// ResolveReportHandler closes the report given by "report_id", together with
+// every other open report on the same item, with an "action": dismiss, hide,
+// warn or suspend. An optional "note" explains the decision and is shown to
+// a warned user; suspensions last "days" days. Reporters are notified.
+func ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+	moderatorID := permittedUserID(r)
+
+	reportID, err := strconv.Atoi(r.FormValue("report_id"))
+	if err != nil || reportID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid report ID"}, http.StatusBadRequest)
+		return
+	}
+
+	action := r.FormValue("action")
+	if !moderation.ValidAction(action) {
+		util.ExecuteJSON(w, model.MsgData{"Action must be dismiss, hide, warn or suspend"}, http.StatusBadRequest)
+		return
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if utf8.RuneCountInString(note) > moderation.MaxNoteLength {
+		util.ExecuteJSON(w, model.MsgData{"Note is too long"}, http.StatusBadRequest)
+		return
+	}
+
+	days := moderation.DefaultSuspensionDays
+	if value := r.FormValue("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 || days > moderation.MaxSuspensionDays {
+			util.ExecuteJSON(w, model.MsgData{"Suspension must last between 1 and 365 days"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := Stores.Reports.Get(reportID)
+	if errors.Is(err, store.ErrReportNotFound) {
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("Failed to load report:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to resolve report"}, http.StatusInternalServerError)
+		return
+	}
+	if !report.Open() {
+		util.ExecuteJSON(w, model.MsgData{store.ErrReportResolved.Error()}, http.StatusConflict)
+		return
+	}
+
+	if !applyModerationAction(w, report, moderatorID, action, note, days) {
+		return
+	}
+
+	resolved, err := Stores.Reports.Resolve(reportID, moderatorID, action, note)
+	if errors.Is(err, store.ErrReportResolved) {
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println("Failed to resolve report:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to resolve report"}, http.StatusInternalServerError)
+		return
+	}
+
+	for _, closed := range resolved {
+		open := closed
+		open.Action, open.Note, open.ResolvedBy, open.ResolvedAt = "", "", 0, time.Time{}
+		recordAudit(moderatorID, audit.ActionResolveReport, audit.TargetReport, closed.ID,
+			reportSnapshot(open), reportSnapshot(closed))
+
+		websocket.PushReportResolved(WebSocketHub, closed.ReporterID, closed.ID, action)
+	}
+
+	util.ExecuteJSON(w, struct {
+		Message  string `json:"message"`
+		Resolved int    `json:"resolved"`
+	}{
+		Message:  "Report resolved",
+		Resolved: len(resolved),
+	}, http.StatusOK)
+}
+
+// applyModerationAction carries out the action chosen for a report, writing
+// the error response and returning false when it fails
+func applyModerationAction(w http.ResponseWriter, report store.Report, moderatorID int, action, note string, days int) bool {
+	switch action {
+	case moderation.ActionHide:
+		hidden, err := hideReportedItem(report)
+		if err != nil {
+			log.Println("Failed to hide reported content:", err)
+			util.ExecuteJSON(w, model.MsgData{"Failed to hide content"}, http.StatusInternalServerError)
+			return false
+		}
+		if hidden != nil {
+			recordAudit(moderatorID, audit.ActionHideContent, report.ItemType, report.ItemID, hidden, nil)
+		}
+
+	case moderation.ActionWarn, moderation.ActionSuspend:
+		reason := note
+		if reason == "" {
+			reason = report.Reason
+		}
+		sanction := store.Sanction{
+			UserID:      report.AuthorID,
+			Kind:        moderation.SanctionWarning,
+			Reason:      reason,
+			ModeratorID: moderatorID,
+			ReportID:    report.ID,
+			CreatedAt:   time.Now(),
+		}
+		if action == moderation.ActionSuspend {
+			sanction.Kind = moderation.SanctionSuspension
+			sanction.ExpiresAt = sanction.CreatedAt.AddDate(0, 0, days)
+		}
+		if _, ok := imposeSanction(w, sanction); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hideReportedItem removes the reported content and returns it as it was.
+// Content its author already deleted counts as hidden and is returned as nil.
+func hideReportedItem(report store.Report) (interface{}, error) {
+	switch report.ItemType {
+	case moderation.ItemPost:
+		p, err := Stores.Posts.Get(report.ItemID)
+		if errors.Is(err, store.ErrPostNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return p, ignoreMissing(Stores.Posts.Remove(report.ItemID))
+
+	case moderation.ItemComment:
+		c, err := Stores.Comments.Get(report.ItemID)
+		if errors.Is(err, store.ErrCommentNotFound) || c.Deleted {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return c, ignoreMissing(Stores.Comments.Remove(report.ItemID))
+
+	case moderation.ItemMessage:
+		m, err := Stores.Messages.Get(report.ItemID)
+		if errors.Is(err, store.ErrNotFound) || m.Deleted {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return m, ignoreMissing(WebSocketHub.RemoveMessage(report.ItemID))
+	}
+	return nil, nil
+}
+
+// reportSnapshot is what the audit log keeps of a report: what its reporter
+// sees plus the moderator's note
+func reportSnapshot(report store.Report) interface{} {
+	return struct {
+		model.Report
+		Note string `json:"note,omitempty"`
+	}{
+		Report: reportView(report),
+		Note:   report.Note,
+	}
+}
+
+// ignoreMissing treats content that disappeared while it was being hidden
+// as hidden
+func ignoreMissing(err error) error {
+	if errors.Is(err, store.ErrPostNotFound) || errors.Is(err, store.ErrCommentNotFound) ||
+		errors.Is(err, websocket.ErrMessageNotFound) {
+		return nil
+	}
+	return err
+}
+
+// queuedReport adds what a moderator needs to judge a report: who is
+// involved, whether the content is still there, where it was posted and how
+// often its author was sanctioned before
+func queuedReport(report store.Report) (model.QueuedReport, error) {
+	queued := model.QueuedReport{
+		Report:     reportView(report),
+		ReporterID: report.ReporterID,
+		AuthorID:   report.AuthorID,
+		Content:    report.Content,
+	}
+	queued.Reporter, _ = Stores.Users.Username(report.ReporterID)
+	queued.Author, _ = Stores.Users.Username(report.AuthorID)
+
+	sanctions, err := Stores.Sanctions.ListForUser(report.AuthorID)
+	if err != nil {
+		return queued, err
+	}
+	queued.AuthorSanctions = len(sanctions)
+
+	switch report.ItemType {
+	case moderation.ItemPost:
+		p, err := Stores.Posts.Get(report.ItemID)
+		if errors.Is(err, store.ErrPostNotFound) {
+			queued.Removed = true
+		} else if err != nil {
+			return queued, err
+		} else {
+			queued.PostID, queued.PostTitle = p.ID, p.Title
+		}
+
+	case moderation.ItemComment:
+		c, err := Stores.Comments.Get(report.ItemID)
+		if err != nil {
+			return queued, err
+		}
+		queued.Removed = c.Deleted
+		queued.PostID = c.PostID
+		if p, err := Stores.Posts.Get(c.PostID); err == nil {
+			queued.PostTitle = p.Title
+		}
+
+	case moderation.ItemMessage:
+		m, err := Stores.Messages.Get(report.ItemID)
+		if err != nil {
+			return queued, err
+		}
+		queued.Removed = m.Deleted
+
+		// The messages leading up to the reported one, which ends the list
+		history, _, err := Stores.Messages.History(m.SenderID, m.ReceiverID, m.ID+1, conversationContext)
+		if err != nil {
+			return queued, err
+		}
+		for _, msg := range history {
+			sender, _ := Stores.Users.Username(msg.SenderID)
+			queued.Conversation = append(queued.Conversation, model.ContextMessage{
+				ID:        msg.ID,
+				Sender:    sender,
+				Content:   msg.Content,
+				Timestamp: msg.Timestamp,
+				Deleted:   msg.Deleted,
+			})
+		}
+	}
+	return queued, nil
+}
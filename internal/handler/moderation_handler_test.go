@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/moderation"
+	"forum/internal/store"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestResolveReport(t *testing.T) {
+	setup(t)
+	_, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	_, carol := newUser(t, "carol", user.RoleUser)
+	_, mod := newUser(t, "mod", user.RoleModerator)
+	postID := newPost(t, alice)
+
+	_, first := report(t, bob, moderation.ItemPost, postID, moderation.Reasons[0])
+	_, second := report(t, carol, moderation.ItemPost, postID, moderation.Reasons[0])
+
+	resolve := RequirePermission(user.ModerateContent, ResolveReportHandler)
+	form := url.Values{"report_id": {strconv.Itoa(first.ID)}, "action": {moderation.ActionHide}}
+
+	code, body := submit(t, resolve, bob, form)
+	expect(t, "resolving as a regular user", code, body, http.StatusForbidden)
+
+	code, body = submit(t, resolve, mod, url.Values{"report_id": {strconv.Itoa(first.ID)}, "action": {"delete"}})
+	expect(t, "resolving with an unknown action", code, body, http.StatusBadRequest)
+
+	code, body = submit(t, resolve, mod, url.Values{"report_id": {"99"}, "action": {moderation.ActionDismiss}})
+	expect(t, "resolving a missing report", code, body, http.StatusNotFound)
+
+	code, body = submit(t, resolve, mod, form)
+	expect(t, "hiding the reported post", code, body, http.StatusOK)
+	if body.Resolved != 2 {
+		t.Errorf("resolved %d reports, want both reports on the post", body.Resolved)
+	}
+
+	if _, err := Stores.Posts.Get(postID); !errors.Is(err, store.ErrPostNotFound) {
+		t.Errorf("loading the hidden post: got %v, want %v", err, store.ErrPostNotFound)
+	}
+	for _, id := range []int{first.ID, second.ID} {
+		resolved, err := Stores.Reports.Get(id)
+		if err != nil || resolved.Open() || resolved.Action != moderation.ActionHide {
+			t.Errorf("report %d is %+v (%v), want it resolved by hiding", id, resolved, err)
+		}
+	}
+
+	code, body = submit(t, resolve, mod, form)
+	expect(t, "resolving the report again", code, body, http.StatusConflict)
+}
+
+func TestResolveReportWithSuspension(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	_, bob := newUser(t, "bob", user.RoleUser)
+	_, mod := newUser(t, "mod", user.RoleModerator)
+	postID := newPost(t, alice)
+	_, filed := report(t, bob, moderation.ItemPost, postID, moderation.Reasons[0])
+
+	resolve := RequirePermission(user.ModerateContent, ResolveReportHandler)
+	code, body := submit(t, resolve, mod, url.Values{
+		"report_id": {strconv.Itoa(filed.ID)},
+		"action":    {moderation.ActionSuspend},
+		"days":      {"3"},
+	})
+	expect(t, "suspending the author", code, body, http.StatusOK)
+
+	sanctions, err := Stores.Sanctions.ListForUser(aliceID)
+	if err != nil || len(sanctions) != 1 || sanctions[0].Kind != moderation.SanctionSuspension || sanctions[0].ReportID != filed.ID {
+		t.Fatalf("sanctions of the author are %+v (%v), want the suspension", sanctions, err)
+	}
+
+	code, body = submit(t, CreatePostHandler, alice, url.Values{"title": {"Still here"}, "content": {"?"}})
+	expect(t, "posting while suspended", code, body, http.StatusUnauthorized)
+}
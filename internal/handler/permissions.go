@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"forum/internal/model"
+	"forum/internal/user"
+	"forum/internal/util"
+	"log"
+	"net/http"
+)
+
+// userIDKey is the request context key under which RequirePermission
+// stores the ID of the logged-in user
+type userIDKey struct{}
+
+// RequirePermission guards a handler so that only logged-in users whose role
+// grants the permission reach it. The handler can read the user's ID with
+// permittedUserID.
+func RequirePermission(permission user.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requirePermission(w, r, permission)
+		if !ok {
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userIDKey{}, userID)))
+	}
+}
+
+// permittedUserID returns the ID of the user let through by RequirePermission
+func permittedUserID(r *http.Request) int {
+	userID, _ := r.Context().Value(userIDKey{}).(int)
+	return userID
+}
+
+// requirePermission writes the error response and returns false unless the
+// request comes from a logged-in user whose role grants the permission
+func requirePermission(w http.ResponseWriter, r *http.Request, permission user.Permission) (int, bool) {
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return 0, false
+	}
+
+	allowed, err := user.HasPermission(Stores.Users, userID, permission)
+	if err != nil {
+		log.Println("Failed to check permissions:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to check permissions"}, http.StatusInternalServerError)
+		return 0, false
+	}
+	if !allowed {
+		util.ExecuteJSON(w, model.MsgData{"You do not have permission to do this"}, http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
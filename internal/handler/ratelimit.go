@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"forum/internal/model"
+	"forum/internal/ratelimit"
+	"forum/internal/session"
+	"forum/internal/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Lock accounts and IP addresses out of logging in after too many failed
+// attempts. Left nil, nothing is locked out.
+var (
+	AccountLockout *ratelimit.Lockout
+	IPLockout      *ratelimit.Lockout
+)
+
+// RateLimit guards a handler so that each client IP address can only make
+// as many requests as limiter allows. Reads (GET and HEAD) are not counted.
+// A nil limiter returns the handler as is.
+func RateLimit(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			if ok, wait := limiter.Allow(session.ClientIP(r)); !ok {
+				tooManyRequests(w, wait, "Too many requests, please slow down")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// tooManyRequests answers 429 with a Retry-After header, rounding the wait
+// up to whole seconds
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	util.ExecuteJSON(w, model.MsgData{message}, http.StatusTooManyRequests)
+}
+
+// lockedOutMessage tells a client how long it has to wait before trying to
+// log in again
+func lockedOutMessage(wait time.Duration) string {
+	if wait <= time.Minute {
+		return "Too many failed login attempts, try again in a minute"
+	}
+	minutes := int((wait + time.Minute - 1) / time.Minute)
+	return fmt.Sprintf("Too many failed login attempts, try again in %d minutes", minutes)
+}
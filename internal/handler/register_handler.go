@@ -44,14 +44,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if username exists
-	exists, err := user.UsernameExists(username)
+	exists, err := Stores.Users.UsernameExists(username)
 	if err != nil || exists {
 		util.ExecuteJSON(w, model.MsgData{"Username already taken"}, http.StatusConflict)
 		return
 	}
 
 	// Check if email exists
-	exists, err = user.EmailExists(email)
+	exists, err = Stores.Users.EmailExists(email)
 	if err != nil || exists {
 		util.ExecuteJSON(w, model.MsgData{"Email already taken"}, http.StatusConflict)
 		return
@@ -65,7 +65,15 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save user to the database
-	if err := user.SaveUser(username, email, hashedPassword, firstName, lastName, gender, age); err != nil {
+	newUser := model.User{
+		Username:  username,
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+		Age:       age,
+		Gender:    gender,
+	}
+	if _, err := Stores.Users.Create(newUser, hashedPassword); err != nil {
 		util.ExecuteJSON(w, model.MsgData{"User registration failed"}, http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/moderation"
+	"forum/internal/store"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// errNotReportable is returned by reportedItem for content the user cannot
+// report, such as their own
+var errNotReportable = errors.New("you cannot report your own content")
+
+// ReportHandler files a report about a post, comment or private message.
+// It takes "item_type" (post, comment or message), "item_id", a "reason"
+// from moderation.Reasons and optional free-text "details".
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	itemType := r.FormValue("item_type")
+	itemID, err := strconv.Atoi(r.FormValue("item_id"))
+	if !moderation.ValidItemType(itemType) || err != nil || itemID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid item to report"}, http.StatusBadRequest)
+		return
+	}
+
+	reason := r.FormValue("reason")
+	if !moderation.ValidReason(reason) {
+		util.ExecuteJSON(w, model.MsgData{"Reason must be one of " + strings.Join(moderation.Reasons, ", ")}, http.StatusBadRequest)
+		return
+	}
+
+	details := strings.TrimSpace(r.FormValue("details"))
+	if utf8.RuneCountInString(details) > moderation.MaxDetailsLength {
+		util.ExecuteJSON(w, model.MsgData{"Report details are too long"}, http.StatusBadRequest)
+		return
+	}
+
+	authorID, content, err := reportedItem(itemType, itemID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errNotReportable):
+			util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusBadRequest)
+		case errors.Is(err, store.ErrNotFound):
+			util.ExecuteJSON(w, model.MsgData{"The reported " + itemType + " does not exist"}, http.StatusNotFound)
+		default:
+			log.Println("Failed to load reported content:", err)
+			util.ExecuteJSON(w, model.MsgData{"Failed to submit report"}, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	reportID, err := Stores.Reports.Create(store.Report{
+		ReporterID: userID,
+		ItemType:   itemType,
+		ItemID:     itemID,
+		AuthorID:   authorID,
+		Content:    content,
+		Reason:     reason,
+		Details:    details,
+	})
+	if errors.Is(err, store.ErrDuplicateReport) {
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println("Failed to create report:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to submit report"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Message string `json:"message"`
+		ID      int    `json:"id"`
+	}{
+		Message: "Report submitted, a moderator will look at it",
+		ID:      reportID,
+	}, http.StatusOK)
+}
+
+// ReportsHandler lists the reports the logged-in user filed and how they
+// were resolved
+func ReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	stored, err := Stores.Reports.ListByReporter(userID)
+	if err != nil {
+		log.Println("Failed to load reports:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load reports"}, http.StatusInternalServerError)
+		return
+	}
+
+	reports := []model.Report{}
+	for _, report := range stored {
+		reports = append(reports, reportView(report))
+	}
+
+	util.ExecuteJSON(w, struct {
+		Reports []model.Report `json:"reports"`
+		Reasons []string       `json:"reasons"`
+	}{
+		Reports: reports,
+		Reasons: moderation.Reasons,
+	}, http.StatusOK)
+}
+
+// reportedItem returns the author and content of the item a user wants to
+// report. Missing or deleted items, and messages the user did not receive,
+// return store.ErrNotFound.
+func reportedItem(itemType string, itemID, reporterID int) (int, string, error) {
+	var authorID int
+	var content string
+
+	switch itemType {
+	case moderation.ItemPost:
+		p, err := Stores.Posts.Get(itemID)
+		if errors.Is(err, store.ErrPostNotFound) {
+			return 0, "", store.ErrNotFound
+		} else if err != nil {
+			return 0, "", err
+		}
+		authorID, content = p.UserID, p.Title+"\n\n"+p.Content
+
+	case moderation.ItemComment:
+		c, err := Stores.Comments.Get(itemID)
+		if errors.Is(err, store.ErrCommentNotFound) || c.Deleted {
+			return 0, "", store.ErrNotFound
+		} else if err != nil {
+			return 0, "", err
+		}
+		authorID, content = c.UserID, c.Content
+
+	case moderation.ItemMessage:
+		m, err := Stores.Messages.Get(itemID)
+		if errors.Is(err, store.ErrNotFound) || m.Deleted || m.ReceiverID != reporterID {
+			return 0, "", store.ErrNotFound
+		} else if err != nil {
+			return 0, "", err
+		}
+		authorID, content = m.SenderID, m.Content
+	}
+
+	if authorID == reporterID {
+		return 0, "", errNotReportable
+	}
+	return authorID, content, nil
+}
+
+// reportView converts a stored report to what its reporter is shown
+func reportView(report store.Report) model.Report {
+	view := model.Report{
+		ID:        report.ID,
+		ItemType:  report.ItemType,
+		ItemID:    report.ItemID,
+		Reason:    report.Reason,
+		Details:   report.Details,
+		CreatedAt: report.CreatedAt.Format(time.RFC3339),
+		Status:    "open",
+	}
+	if !report.Open() {
+		view.Status = "resolved"
+		view.Action = report.Action
+		view.ResolvedAt = report.ResolvedAt.Format(time.RFC3339)
+	}
+	return view
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"forum/internal/moderation"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// report files a report about an item as the user of the session
+func report(t *testing.T, sessionID, itemType string, itemID int, reason string) (int, response) {
+	t.Helper()
+	return submit(t, ReportHandler, sessionID, url.Values{
+		"item_type": {itemType},
+		"item_id":   {strconv.Itoa(itemID)},
+		"reason":    {reason},
+	})
+}
+
+func TestReport(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	bobID, bob := newUser(t, "bob", user.RoleUser)
+	postID := newPost(t, alice)
+	reason := moderation.Reasons[0]
+
+	code, body := report(t, "", moderation.ItemPost, postID, reason)
+	expect(t, "reporting logged out", code, body, http.StatusUnauthorized)
+
+	code, body = report(t, bob, "profile", postID, reason)
+	expect(t, "reporting an unknown kind of item", code, body, http.StatusBadRequest)
+
+	code, body = report(t, bob, moderation.ItemPost, postID, "boring")
+	expect(t, "reporting for an unknown reason", code, body, http.StatusBadRequest)
+
+	code, body = report(t, alice, moderation.ItemPost, postID, reason)
+	expect(t, "reporting one's own post", code, body, http.StatusBadRequest)
+
+	code, body = report(t, bob, moderation.ItemPost, postID+1, reason)
+	expect(t, "reporting a missing post", code, body, http.StatusNotFound)
+
+	code, body = report(t, bob, moderation.ItemPost, postID, reason)
+	expect(t, "reporting the post", code, body, http.StatusOK)
+
+	stored, err := Stores.Reports.Get(body.ID)
+	if err != nil {
+		t.Fatalf("loading the report: %v", err)
+	}
+	if stored.ReporterID != bobID || stored.AuthorID != aliceID || stored.Content != "Hello\n\nFirst post" || !stored.Open() {
+		t.Errorf("stored report is %+v", stored)
+	}
+
+	code, body = report(t, bob, moderation.ItemPost, postID, reason)
+	expect(t, "reporting the post twice", code, body, http.StatusConflict)
+
+	// Private messages can only be reported by who received them
+	message, err := Stores.Messages.Create(aliceID, bobID, "Hi bob", false)
+	if err != nil {
+		t.Fatalf("creating a message: %v", err)
+	}
+	_, carol := newUser(t, "carol", user.RoleUser)
+	code, body = report(t, carol, moderation.ItemMessage, message.ID, reason)
+	expect(t, "reporting someone else's message", code, body, http.StatusNotFound)
+
+	code, body = report(t, bob, moderation.ItemMessage, message.ID, reason)
+	expect(t, "reporting a received message", code, body, http.StatusOK)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/audit"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/user"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// AdminUsersHandler lists every user with their role
+func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	all, err := Stores.Users.List()
+	if err != nil {
+		log.Println("Failed to load users:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load users"}, http.StatusInternalServerError)
+		return
+	}
+
+	type userRole struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	users := []userRole{}
+	for _, u := range all {
+		users = append(users, userRole{ID: u.ID, Username: u.Username, Role: u.Role})
+	}
+
+	util.ExecuteJSON(w, struct {
+		Users []userRole `json:"users"`
+	}{
+		Users: users,
+	}, http.StatusOK)
+}
+
+// SetRoleHandler promotes or demotes the user given by "user_id" to "role".
+// Admins cannot change their own role, so the forum always keeps at least
+// one admin.
+func SetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	targetID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || targetID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid user ID"}, http.StatusBadRequest)
+		return
+	}
+
+	role := r.FormValue("role")
+	if !user.ValidRole(role) {
+		util.ExecuteJSON(w, model.MsgData{"Role must be user, moderator or admin"}, http.StatusBadRequest)
+		return
+	}
+
+	if targetID == permittedUserID(r) {
+		util.ExecuteJSON(w, model.MsgData{"You cannot change your own role"}, http.StatusForbidden)
+		return
+	}
+
+	previous, err := Stores.Users.Role(targetID)
+	if err != nil {
+		log.Println("Failed to load role:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to change role"}, http.StatusInternalServerError)
+		return
+	}
+
+	if err := Stores.Users.SetRole(targetID, role); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			util.ExecuteJSON(w, model.MsgData{"User not found"}, http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to change role:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to change role"}, http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(permittedUserID(r), audit.ActionChangeRole, audit.TargetUser, targetID,
+		map[string]string{"role": previous}, map[string]string{"role": role})
+
+	util.ExecuteJSON(w, model.MsgData{"Role changed to " + role}, http.StatusOK)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/store"
+	"forum/internal/util"
+	"forum/internal/websocket"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRoomNameLength limits how long a room name may be
+const maxRoomNameLength = 50
+
+// RoomsHandler lists the chat rooms of the logged-in user
+func RoomsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	rooms, err := Stores.Rooms.ListForUser(userID)
+	if err != nil {
+		log.Println("Failed to load rooms:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load rooms"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Rooms []model.Room `json:"rooms"`
+	}{
+		Rooms: rooms,
+	}, http.StatusOK)
+}
+
+// CreateRoomHandler creates a room owned by the logged-in user
+func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	name, ok := roomName(w, r)
+	if !ok {
+		return
+	}
+
+	categoryID, ok := roomCategory(w, r)
+	if !ok {
+		return
+	}
+
+	roomID, err := Stores.Rooms.Create(userID, name, categoryID)
+	if err != nil {
+		log.Println("Room creation failed:", err)
+		util.ExecuteJSON(w, model.MsgData{"Room creation failed"}, http.StatusInternalServerError)
+		return
+	}
+
+	websocket.PushRoomUpdate(WebSocketHub, roomID)
+
+	util.ExecuteJSON(w, struct {
+		Message string `json:"message"`
+		ID      int    `json:"id"`
+	}{
+		Message: "Room created successfully",
+		ID:      roomID,
+	}, http.StatusOK)
+}
+
+// InviteRoomHandler adds a user to a room the logged-in user belongs to
+func InviteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	roomID, ok := roomIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	inviteeID, err := Stores.Users.IDByUsername(strings.TrimSpace(r.FormValue("username")))
+	if err != nil {
+		util.ExecuteJSON(w, model.MsgData{"User not found"}, http.StatusNotFound)
+		return
+	}
+
+	if err := Stores.Rooms.Invite(roomID, userID, inviteeID); err != nil {
+		roomError(w, err, "Failed to invite user")
+		return
+	}
+
+	websocket.PushRoomUpdate(WebSocketHub, roomID)
+
+	util.ExecuteJSON(w, model.MsgData{"User invited successfully"}, http.StatusOK)
+}
+
+// LeaveRoomHandler removes the logged-in user from a room
+func LeaveRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	roomID, ok := roomIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := Stores.Rooms.Leave(roomID, userID); err != nil {
+		roomError(w, err, "Failed to leave room")
+		return
+	}
+
+	websocket.PushRoomLeft(WebSocketHub, roomID, userID)
+	websocket.PushRoomUpdate(WebSocketHub, roomID)
+
+	util.ExecuteJSON(w, model.MsgData{"Left room successfully"}, http.StatusOK)
+}
+
+// RenameRoomHandler renames a room owned by the logged-in user
+func RenameRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	roomID, ok := roomIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	name, ok := roomName(w, r)
+	if !ok {
+		return
+	}
+
+	if err := Stores.Rooms.Rename(roomID, userID, name); err != nil {
+		roomError(w, err, "Failed to rename room")
+		return
+	}
+
+	websocket.PushRoomUpdate(WebSocketHub, roomID)
+
+	util.ExecuteJSON(w, model.MsgData{"Room renamed successfully"}, http.StatusOK)
+}
+
+// roomIDParam parses the room_id form value, writing an error response if invalid
+func roomIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	roomID, err := strconv.Atoi(r.FormValue("room_id"))
+	if err != nil || roomID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid room ID"}, http.StatusBadRequest)
+		return 0, false
+	}
+	return roomID, true
+}
+
+// roomName validates the name form value, writing an error response if invalid
+func roomName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || utf8.RuneCountInString(name) > maxRoomNameLength {
+		util.ExecuteJSON(w, model.MsgData{"Room name must be between 1 and 50 characters"}, http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// roomCategory reads the optional category_id form value, 0 when it is
+// empty, writing an error response when the category is unknown or archived
+func roomCategory(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.FormValue("category_id"))
+	if value == "" {
+		return 0, true
+	}
+	categoryID, err := strconv.Atoi(value)
+	if err != nil || categoryID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+		return 0, false
+	}
+
+	category, err := Stores.Categories.Get(categoryID)
+	switch {
+	case errors.Is(err, store.ErrCategoryNotFound), err == nil && category.Archived:
+		util.ExecuteJSON(w, model.MsgData{"Invalid category"}, http.StatusBadRequest)
+		return 0, false
+	case err != nil:
+		log.Println("Failed to load category:", err)
+		util.ExecuteJSON(w, model.MsgData{"Room creation failed"}, http.StatusInternalServerError)
+		return 0, false
+	}
+	return categoryID, true
+}
+
+// roomError maps room store errors to JSON responses
+func roomError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrNotRoomMember), errors.Is(err, store.ErrNotRoomOwner):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusForbidden)
+	case errors.Is(err, store.ErrAlreadyRoomMember):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusConflict)
+	case errors.Is(err, store.ErrRoomNotFound):
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+	default:
+		log.Println(fallback+":", err)
+		util.ExecuteJSON(w, model.MsgData{fallback}, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestCreateRoom(t *testing.T) {
+	setup(t)
+	_, alice := newUser(t, "alice", user.RoleUser)
+
+	archivedID, err := Stores.Categories.Create("Old", "")
+	if err != nil {
+		t.Fatalf("creating a category: %v", err)
+	}
+	if err := Stores.Categories.SetArchived(archivedID, true); err != nil {
+		t.Fatalf("archiving a category: %v", err)
+	}
+	for _, test := range []struct {
+		what       string
+		categoryID string
+	}{
+		{"a category name", "General"},
+		{"a missing category", "99"},
+		{"an archived category", strconv.Itoa(archivedID)},
+	} {
+		code, body := submit(t, CreateRoomHandler, alice, url.Values{"name": {"Lobby"}, "category_id": {test.categoryID}})
+		expect(t, "creating a room in "+test.what, code, body, http.StatusBadRequest)
+	}
+
+	categories, err := Stores.Categories.List(false)
+	if err != nil || len(categories) != 1 {
+		t.Fatalf("listing categories: %v, %v", categories, err)
+	}
+	general := categories[0]
+
+	code, body := submit(t, CreateRoomHandler, alice, url.Values{"name": {"Lobby"}, "category_id": {strconv.Itoa(general.ID)}})
+	expect(t, "creating a room in a category", code, body, http.StatusOK)
+	room, err := Stores.Rooms.Get(body.ID)
+	if err != nil {
+		t.Fatalf("loading the room: %v", err)
+	}
+	if room.CategoryID != general.ID || room.Category != "General" {
+		t.Errorf("room is in category %d %q, want %d %q", room.CategoryID, room.Category, general.ID, general.Name)
+	}
+
+	// Renaming the category renames it for the room too
+	if err := Stores.Categories.Update(general.ID, "Lounge", ""); err != nil {
+		t.Fatalf("renaming the category: %v", err)
+	}
+	if room, err = Stores.Rooms.Get(body.ID); err != nil || room.Category != "Lounge" {
+		t.Errorf("room is in category %q (%v) after the rename, want Lounge", room.Category, err)
+	}
+
+	code, body = submit(t, CreateRoomHandler, alice, url.Values{"name": {"Lobby"}})
+	expect(t, "creating a room without a category", code, body, http.StatusOK)
+	if room, err = Stores.Rooms.Get(body.ID); err != nil || room.CategoryID != 0 || room.Category != "" {
+		t.Errorf("room without a category is %+v (%v)", room, err)
+	}
+}
@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/audit"
+	"forum/internal/model"
+	"forum/internal/moderation"
+	"forum/internal/store"
+	"forum/internal/user"
+	"forum/internal/util"
+	"forum/internal/websocket"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SanctionsHandler lists every sanction of the user given by "user_id",
+// newest first, including expired and lifted ones
+func SanctionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid user ID"}, http.StatusBadRequest)
+		return
+	}
+
+	stored, err := Stores.Sanctions.ListForUser(userID)
+	if err != nil {
+		log.Println("Failed to load sanctions:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load sanctions"}, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	sanctions := []model.Sanction{}
+	for _, sanction := range stored {
+		sanctions = append(sanctions, sanctionView(sanction, now))
+	}
+
+	util.ExecuteJSON(w, struct {
+		Sanctions []model.Sanction `json:"sanctions"`
+	}{
+		Sanctions: sanctions,
+	}, http.StatusOK)
+}
+
+// SanctionUserHandler sanctions the user given by "user_id" outside of any
+// report. It takes a "kind" (warning, suspension, ban or shadowban) and a
+// "reason". Suspensions last "days" days; shadowbans last "days" days or
+// until lifted when it is left out. Only admins can ban.
+func SanctionUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+	moderatorID := permittedUserID(r)
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || userID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid user ID"}, http.StatusBadRequest)
+		return
+	}
+
+	kind := r.FormValue("kind")
+	if !moderation.ValidSanctionKind(kind) {
+		util.ExecuteJSON(w, model.MsgData{"Kind must be warning, suspension, ban or shadowban"}, http.StatusBadRequest)
+		return
+	}
+	if kind == moderation.SanctionBan {
+		if _, ok := requirePermission(w, r, user.BanUsers); !ok {
+			return
+		}
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if utf8.RuneCountInString(reason) > moderation.MaxNoteLength {
+		util.ExecuteJSON(w, model.MsgData{"Reason is too long"}, http.StatusBadRequest)
+		return
+	}
+
+	days := 0
+	if value := r.FormValue("days"); value != "" {
+		if kind != moderation.SanctionSuspension && kind != moderation.SanctionShadowban {
+			util.ExecuteJSON(w, model.MsgData{"Only suspensions and shadowbans can be limited in time"}, http.StatusBadRequest)
+			return
+		}
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 || days > moderation.MaxSuspensionDays {
+			util.ExecuteJSON(w, model.MsgData{"Sanction must last between 1 and 365 days"}, http.StatusBadRequest)
+			return
+		}
+	} else if kind == moderation.SanctionSuspension {
+		days = moderation.DefaultSuspensionDays
+	}
+
+	sanction := store.Sanction{
+		UserID:      userID,
+		Kind:        kind,
+		Reason:      reason,
+		ModeratorID: moderatorID,
+		CreatedAt:   time.Now(),
+	}
+	if days > 0 {
+		sanction.ExpiresAt = sanction.CreatedAt.AddDate(0, 0, days)
+	}
+
+	sanctionID, ok := imposeSanction(w, sanction)
+	if !ok {
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Message string `json:"message"`
+		ID      int    `json:"id"`
+	}{
+		Message: "Sanction recorded",
+		ID:      sanctionID,
+	}, http.StatusOK)
+}
+
+// LiftSanctionHandler ends the sanction given by "sanction_id" early. Only
+// admins can lift bans.
+func LiftSanctionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+	moderatorID := permittedUserID(r)
+
+	sanctionID, err := strconv.Atoi(r.FormValue("sanction_id"))
+	if err != nil || sanctionID <= 0 {
+		util.ExecuteJSON(w, model.MsgData{"Missing or invalid sanction ID"}, http.StatusBadRequest)
+		return
+	}
+
+	sanction, err := Stores.Sanctions.Get(sanctionID)
+	if errors.Is(err, store.ErrSanctionNotFound) {
+		util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("Failed to load sanction:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to lift sanction"}, http.StatusInternalServerError)
+		return
+	}
+	if sanction.Kind == moderation.SanctionBan {
+		if _, ok := requirePermission(w, r, user.BanUsers); !ok {
+			return
+		}
+	}
+
+	if err := Stores.Sanctions.Lift(sanctionID, moderatorID); err != nil {
+		if errors.Is(err, store.ErrSanctionNotFound) {
+			util.ExecuteJSON(w, model.MsgData{err.Error()}, http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to lift sanction:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to lift sanction"}, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	lifted := sanction
+	lifted.LiftedBy, lifted.LiftedAt = moderatorID, now
+	recordAudit(moderatorID, audit.ActionLiftSanction, audit.TargetUser, sanction.UserID,
+		sanctionView(sanction, now), sanctionView(lifted, now))
+
+	util.ExecuteJSON(w, model.MsgData{"Sanction lifted"}, http.StatusOK)
+}
+
+// imposeSanction records a sanction against a regular user and puts it into
+// effect, writing the error response and returning false when that fails
+func imposeSanction(w http.ResponseWriter, sanction store.Sanction) (int, bool) {
+	// Staff have to be demoted before they can be sanctioned
+	role, err := Stores.Users.Role(sanction.UserID)
+	if err != nil {
+		log.Println("Failed to check role:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to record sanction"}, http.StatusInternalServerError)
+		return 0, false
+	}
+	if role == "" {
+		util.ExecuteJSON(w, model.MsgData{"User not found"}, http.StatusNotFound)
+		return 0, false
+	}
+	if role != user.RoleUser {
+		util.ExecuteJSON(w, model.MsgData{"Moderators and admins cannot be sanctioned"}, http.StatusForbidden)
+		return 0, false
+	}
+
+	sanctionID, err := Stores.Sanctions.Create(sanction)
+	if err != nil {
+		log.Println("Failed to record sanction:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to record sanction"}, http.StatusInternalServerError)
+		return 0, false
+	}
+	sanction.ID = sanctionID
+	recordAudit(sanction.ModeratorID, audit.ActionSanctionUser, audit.TargetUser, sanction.UserID,
+		nil, sanctionView(sanction, sanction.CreatedAt))
+
+	switch sanction.Kind {
+	case moderation.SanctionWarning:
+		websocket.PushWarning(WebSocketHub, sanction.UserID, sanction.Reason)
+	case moderation.SanctionSuspension:
+		logOutEverywhere(sanction.UserID, "account suspended")
+	case moderation.SanctionBan:
+		logOutEverywhere(sanction.UserID, "account banned")
+	}
+	// Shadowbanned users are deliberately not told
+	return sanctionID, true
+}
+
+// logOutEverywhere ends every session of a user and closes their chat
+// connections with the given reason
+func logOutEverywhere(userID int, reason string) {
+	if _, err := Sessions.RevokeOtherSessions(userID, ""); err != nil {
+		log.Printf("Failed to log out user %d: %v", userID, err)
+	}
+	WebSocketHub.DisconnectUser(userID, reason)
+}
+
+// hiddenAuthors returns the shadowbanned users whose content viewerID must
+// not see, writing the error response and returning false when that fails.
+// Shadowbanned users still see their own content.
+func hiddenAuthors(w http.ResponseWriter, viewerID int) ([]int, bool) {
+	userIDs, err := Stores.Sanctions.ActiveUserIDs(moderation.SanctionShadowban, time.Now())
+	if err != nil {
+		log.Println("Failed to load shadowbans:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load content"}, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	hidden := userIDs[:0]
+	for _, userID := range userIDs {
+		if userID != viewerID {
+			hidden = append(hidden, userID)
+		}
+	}
+	return hidden, true
+}
+
+// isHidden reports whether userID is one of the hidden authors
+func isHidden(hidden []int, userID int) bool {
+	for _, id := range hidden {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowbanned reports whether a user is shadowbanned. Failing to check
+// counts as not shadowbanned.
+func shadowbanned(userID int) bool {
+	sanctions, err := Stores.Sanctions.Active(userID, time.Now())
+	if err != nil {
+		log.Printf("Failed to check sanctions of user %d: %v", userID, err)
+		return false
+	}
+	return moderation.Shadowbanned(sanctions)
+}
+
+// sanctionView converts a stored sanction to what moderators are shown
+func sanctionView(sanction store.Sanction, now time.Time) model.Sanction {
+	view := model.Sanction{
+		ID:        sanction.ID,
+		Kind:      sanction.Kind,
+		Reason:    sanction.Reason,
+		ReportID:  sanction.ReportID,
+		CreatedAt: sanction.CreatedAt.Format(time.RFC3339),
+		Active:    sanction.Kind != moderation.SanctionWarning && sanction.ActiveAt(now),
+	}
+	view.Moderator, _ = Stores.Users.Username(sanction.ModeratorID)
+	if !sanction.ExpiresAt.IsZero() {
+		view.ExpiresAt = sanction.ExpiresAt.Format(time.RFC3339)
+	}
+	if !sanction.LiftedAt.IsZero() {
+		view.LiftedBy, _ = Stores.Users.Username(sanction.LiftedBy)
+		view.LiftedAt = sanction.LiftedAt.Format(time.RFC3339)
+	}
+	return view
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"forum/internal/moderation"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanctionUser(t *testing.T) {
+	setup(t)
+	bobID, bob := newUser(t, "bob", user.RoleUser)
+	otherModID, _ := newUser(t, "other", user.RoleModerator)
+	_, mod := newUser(t, "mod", user.RoleModerator)
+	_, admin := newUser(t, "admin", user.RoleAdmin)
+
+	sanction := RequirePermission(user.ModerateContent, SanctionUserHandler)
+	sanctionBob := func(sessionID string, form url.Values) (int, response) {
+		form.Set("user_id", strconv.Itoa(bobID))
+		return submit(t, sanction, sessionID, form)
+	}
+
+	code, body := sanctionBob(bob, url.Values{"kind": {moderation.SanctionWarning}})
+	expect(t, "sanctioning as a regular user", code, body, http.StatusForbidden)
+
+	code, body = sanctionBob(mod, url.Values{"kind": {"exile"}})
+	expect(t, "sanctioning with an unknown kind", code, body, http.StatusBadRequest)
+
+	code, body = sanctionBob(mod, url.Values{"kind": {moderation.SanctionWarning}, "days": {"3"}})
+	expect(t, "limiting a warning in time", code, body, http.StatusBadRequest)
+
+	code, body = sanctionBob(mod, url.Values{"kind": {moderation.SanctionSuspension}, "days": {"400"}})
+	expect(t, "suspending for too long", code, body, http.StatusBadRequest)
+
+	code, body = sanctionBob(mod, url.Values{"kind": {moderation.SanctionBan}})
+	expect(t, "banning as a moderator", code, body, http.StatusForbidden)
+
+	code, body = submit(t, sanction, mod, url.Values{"user_id": {strconv.Itoa(otherModID)}, "kind": {moderation.SanctionWarning}})
+	expect(t, "sanctioning a moderator", code, body, http.StatusForbidden)
+
+	code, body = submit(t, sanction, mod, url.Values{"user_id": {"99"}, "kind": {moderation.SanctionWarning}})
+	expect(t, "sanctioning a missing user", code, body, http.StatusNotFound)
+
+	code, body = sanctionBob(mod, url.Values{"kind": {moderation.SanctionWarning}, "reason": {"Be nice"}})
+	expect(t, "warning", code, body, http.StatusOK)
+
+	// Bob is offline, so the warning waits for his next connection
+	frames, err := Stores.Notifications.Take(bobID)
+	if err != nil || len(frames) != 1 || !strings.Contains(string(frames[0]), `"reason":"Be nice"`) {
+		t.Errorf("notifications of bob are %q (%v), want the warning", frames, err)
+	}
+
+	code, body = submit(t, CreatePostHandler, bob, url.Values{"title": {"Sorry"}, "content": {"I will"}})
+	expect(t, "posting after a warning", code, body, http.StatusOK)
+
+	code, body = sanctionBob(admin, url.Values{"kind": {moderation.SanctionBan}, "reason": {"Spam"}})
+	expect(t, "banning as an admin", code, body, http.StatusOK)
+
+	code, body = submit(t, CreatePostHandler, bob, url.Values{"title": {"Spam"}, "content": {"Spam"}})
+	expect(t, "posting while banned", code, body, http.StatusUnauthorized)
+
+	sanctions, err := Stores.Sanctions.ListForUser(bobID)
+	if err != nil || len(sanctions) != 2 {
+		t.Fatalf("sanctions of bob are %+v (%v), want the warning and the ban", sanctions, err)
+	}
+	if ban := sanctions[0]; ban.Kind != moderation.SanctionBan || ban.Reason != "Spam" || !ban.ExpiresAt.IsZero() {
+		t.Errorf("ban is %+v, want a permanent ban", ban)
+	}
+}
+
+func TestLiftSanction(t *testing.T) {
+	setup(t)
+	bobID, _ := newUser(t, "bob", user.RoleUser)
+	_, mod := newUser(t, "mod", user.RoleModerator)
+	_, admin := newUser(t, "admin", user.RoleAdmin)
+
+	sanction := RequirePermission(user.ModerateContent, SanctionUserHandler)
+	lift := RequirePermission(user.ModerateContent, LiftSanctionHandler)
+
+	_, suspension := submit(t, sanction, mod, url.Values{"user_id": {strconv.Itoa(bobID)}, "kind": {moderation.SanctionSuspension}})
+	_, ban := submit(t, sanction, admin, url.Values{"user_id": {strconv.Itoa(bobID)}, "kind": {moderation.SanctionBan}})
+
+	code, body := submit(t, lift, mod, url.Values{"sanction_id": {"99"}})
+	expect(t, "lifting a missing sanction", code, body, http.StatusNotFound)
+
+	code, body = submit(t, lift, mod, url.Values{"sanction_id": {strconv.Itoa(ban.ID)}})
+	expect(t, "lifting a ban as a moderator", code, body, http.StatusForbidden)
+
+	code, body = submit(t, lift, mod, url.Values{"sanction_id": {strconv.Itoa(suspension.ID)}})
+	expect(t, "lifting the suspension", code, body, http.StatusOK)
+
+	code, body = submit(t, lift, admin, url.Values{"sanction_id": {strconv.Itoa(ban.ID)}})
+	expect(t, "lifting the ban as an admin", code, body, http.StatusOK)
+
+	active, err := Stores.Sanctions.Active(bobID, time.Now())
+	if err != nil || len(active) != 0 {
+		t.Errorf("active sanctions of bob are %+v (%v), want none", active, err)
+	}
+
+	// Bob can log in again
+	code, body = submit(t, CreatePostHandler, login(t, bobID), url.Values{"title": {"Back"}, "content": {"Hello again"}})
+	expect(t, "posting after the sanctions were lifted", code, body, http.StatusOK)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"forum/internal/model"
+	"forum/internal/search"
+	"forum/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SearchHandler runs a full-text search over posts, comments and the user's
+// own private messages
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || sessionID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to search"}, http.StatusUnauthorized)
+		return
+	}
+
+	if !Stores.Search.Available() {
+		util.ExecuteJSON(w, model.MsgData{"Search is unavailable"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	rawQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(search.ParseTerms(rawQuery)) == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Search query is missing"}, http.StatusBadRequest)
+		return
+	}
+
+	// Search every scope unless some are requested
+	scopes := []string{search.ScopePosts, search.ScopeComments, search.ScopeMessages}
+	if scopeParam := r.URL.Query().Get("scope"); scopeParam != "" {
+		scopes = nil
+		for _, scope := range strings.Split(scopeParam, ",") {
+			scope = strings.TrimSpace(scope)
+			if scope != search.ScopePosts && scope != search.ScopeComments && scope != search.ScopeMessages {
+				util.ExecuteJSON(w, model.MsgData{"Invalid search scope"}, http.StatusBadRequest)
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	hidden, ok := hiddenAuthors(w, sessionID)
+	if !ok {
+		return
+	}
+
+	results, err := Stores.Search.Search(sessionID, rawQuery, scopes, limit, hidden)
+	if err != nil {
+		log.Println("Search failed:", err)
+		util.ExecuteJSON(w, model.MsgData{"Search failed"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Query   string                          `json:"query"`
+		Results map[string][]model.SearchResult `json:"results"`
+	}{
+		Query:   rawQuery,
+		Results: results,
+	}, http.StatusOK)
+}
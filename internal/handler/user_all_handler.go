@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"forum/internal/database"
 	"forum/internal/model"
 	"forum/internal/util"
+	"log"
 	"net/http"
 )
 
@@ -14,13 +14,13 @@ func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Query all users
-	rows, err := database.Db.Query("SELECT id, username FROM users")
+	// Load all users
+	all, err := Stores.Users.List()
 	if err != nil {
+		log.Println("Failed to load users:", err)
 		util.ExecuteJSON(w, model.MsgData{"Failed to load users"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
 	// Struct to hold user information
 	type UserInfo struct {
@@ -29,15 +29,8 @@ func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var users []UserInfo
-
-	// Scan and collect user data
-	for rows.Next() {
-		var user UserInfo
-		if err := rows.Scan(&user.ID, &user.Username); err != nil {
-			// Skip individual errors to return as many users as possible
-			continue
-		}
-		users = append(users, user)
+	for _, u := range all {
+		users = append(users, UserInfo{ID: u.ID, Username: u.Username})
 	}
 
 	// Return the user list
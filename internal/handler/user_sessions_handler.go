@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/session"
+	"forum/internal/store"
+	"forum/internal/util"
+	"log"
+	"net/http"
+)
+
+// UserSessionsHandler lists the active sessions of the logged-in user
+func UserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	currentSessionID, _ := session.GetSessionID(r)
+
+	sessions, err := Sessions.GetUserSessions(userID, currentSessionID)
+	if err != nil {
+		log.Println("Failed to load sessions:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load sessions"}, http.StatusInternalServerError)
+		return
+	}
+
+	util.ExecuteJSON(w, struct {
+		Sessions []model.Session `json:"sessions"`
+	}{
+		Sessions: sessions,
+	}, http.StatusOK)
+}
+
+// RevokeSessionHandler revokes one session of the logged-in user, or all of
+// them except the current one when "all_others" is set
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := Sessions.GetUserIDFromSession(r)
+	if err != nil || userID == 0 {
+		util.ExecuteJSON(w, model.MsgData{"Invalid session, please log in"}, http.StatusUnauthorized)
+		return
+	}
+
+	currentSessionID, _ := session.GetSessionID(r)
+
+	// Revoke every other session
+	if r.FormValue("all_others") == "true" {
+		revoked, err := Sessions.RevokeOtherSessions(userID, currentSessionID)
+		for _, sessionID := range revoked {
+			WebSocketHub.DisconnectSession(sessionID)
+		}
+		if err != nil {
+			log.Println("Failed to revoke sessions:", err)
+			util.ExecuteJSON(w, model.MsgData{"Failed to revoke sessions"}, http.StatusInternalServerError)
+			return
+		}
+
+		util.ExecuteJSON(w, model.MsgData{"Other sessions revoked"}, http.StatusOK)
+		return
+	}
+
+	// Revoke a single session
+	publicID := r.FormValue("session_id")
+	if publicID == "" {
+		util.ExecuteJSON(w, model.MsgData{"Session ID is missing"}, http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := Sessions.RevokeSession(userID, publicID)
+	if errors.Is(err, store.ErrNotFound) {
+		util.ExecuteJSON(w, model.MsgData{"Session not found"}, http.StatusNotFound)
+		return
+	}
+	// Close the chat connections of a session that failed to be deleted too,
+	// as it may be partly gone
+	WebSocketHub.DisconnectSession(sessionID)
+	if err != nil {
+		log.Println("Failed to revoke session:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to revoke session"}, http.StatusInternalServerError)
+		return
+	}
+
+	// Clear the cookie when the current session revoked itself
+	if sessionID == currentSessionID {
+		http.SetCookie(w, &http.Cookie{
+			Name:     "session_id",
+			Value:    "",
+			MaxAge:   -1,
+			Path:     "/",
+			HttpOnly: true,
+		})
+	}
+
+	util.ExecuteJSON(w, model.MsgData{"Session revoked"}, http.StatusOK)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"forum/internal/session"
+	"forum/internal/store"
+	"forum/internal/user"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// brokenSessions is a session store whose deletes fail
+type brokenSessions struct {
+	store.SessionStore
+}
+
+func (brokenSessions) Delete(sessionID string) error {
+	return errors.New("disk full")
+}
+
+func TestRevokeSession(t *testing.T) {
+	setup(t)
+	aliceID, alice := newUser(t, "alice", user.RoleUser)
+	phone := login(t, aliceID)
+	_, bob := newUser(t, "bob", user.RoleUser)
+
+	revoke := func(sessionID, publicID string) (int, response) {
+		return submit(t, RevokeSessionHandler, sessionID, url.Values{"session_id": {publicID}})
+	}
+
+	code, body := revoke(alice, "0123456789abcdef")
+	expect(t, "revoking an unknown session", code, body, http.StatusNotFound)
+
+	code, body = revoke(bob, session.PublicID(phone))
+	expect(t, "revoking someone else's session", code, body, http.StatusNotFound)
+
+	Sessions = session.NewManager(brokenSessions{Stores.Sessions}, Stores.Sanctions, time.Hour)
+	code, body = revoke(alice, session.PublicID(phone))
+	expect(t, "revoking while the store fails", code, body, http.StatusInternalServerError)
+
+	Sessions = session.NewManager(Stores.Sessions, Stores.Sanctions, time.Hour)
+	code, body = revoke(alice, session.PublicID(phone))
+	expect(t, "revoking another session", code, body, http.StatusOK)
+
+	if _, err := Stores.Sessions.Get(phone); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("loading the revoked session: got %v, want %v", err, store.ErrNotFound)
+	}
+}
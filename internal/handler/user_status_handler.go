@@ -1,14 +1,16 @@
 package handler
 
 import (
-	"forum/internal/database"
 	"forum/internal/model"
 	"forum/internal/session"
+	"forum/internal/user"
 	"forum/internal/util"
+	"log"
 	"net/http"
 )
 
-// UserStatusHandler returns the current user's session information
+// UserStatusHandler returns the current user's session information and the
+// CSRF token to send with requests that change state
 func UserStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		util.ExecuteJSON(w, model.MsgData{"Invalid request method"}, http.StatusMethodNotAllowed)
@@ -16,24 +18,39 @@ func UserStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user ID from session
-	userID, err := session.GetUserIDFromSession(r)
+	userID, err := Sessions.GetUserIDFromSession(r)
 	
-	// Initialize username
-	var username string
+	// Initialize username and role
+	var username, role string
 	if err == nil && userID > 0 {
-		// Attempt to fetch username
-		_ = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+		// Attempt to fetch username and role
+		username, _ = Stores.Users.Username(userID)
+		role, _ = Stores.Users.Role(userID)
 	}
 	
+	// Anonymous visitors need a token too, to log in and register
+	csrfToken, err := session.CSRFToken(w, r)
+	if err != nil {
+		log.Println("Failed to issue CSRF token:", err)
+		util.ExecuteJSON(w, model.MsgData{"Failed to load user status"}, http.StatusInternalServerError)
+		return
+	}
+
 	// Prepare response data
 	data := struct {
-		SessionID int    `json:"sessionID"`
-		Username  string `json:"username"`
-		LoggedIn  bool   `json:"loggedIn"`
+		SessionID   int               `json:"sessionID"`
+		Username    string            `json:"username"`
+		LoggedIn    bool              `json:"loggedIn"`
+		Role        string            `json:"role"`
+		Permissions []user.Permission `json:"permissions"`
+		CSRFToken   string            `json:"csrfToken"`
 	}{
-		SessionID: userID,
-		Username:  username,
-		LoggedIn:  userID > 0,
+		SessionID:   userID,
+		Username:    username,
+		LoggedIn:    userID > 0,
+		Role:        role,
+		Permissions: user.Permissions(role),
+		CSRFToken:   csrfToken,
 	}
 	
 	// Return user status
@@ -1,14 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"forum/internal/comment"
-	"forum/internal/database"
 	"forum/internal/model"
-	"forum/internal/post"
-	"forum/internal/reaction"
-	"forum/internal/session"
+	"forum/internal/store"
 	"forum/internal/util"
+	"log"
 	"net/http"
+	"strconv"
 )
 
 func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -18,45 +18,74 @@ func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate session
-	sessionID, err := session.GetUserIDFromSession(r)
+	sessionID, err := Sessions.GetUserIDFromSession(r)
 	if err != nil || sessionID == 0 {
 		util.ExecuteJSON(w, model.MsgData{"Unauthorized: Please log in to view posts"}, http.StatusUnauthorized)
 		return
 	}
 
 	// Get the post ID from URL query
-	postID := r.URL.Query().Get("id")
-	if postID == "" || postID == "undefined" || postID == "null" {
+	postID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || postID <= 0 {
 		util.ExecuteJSON(w, model.MsgData{"Missing or invalid PostID"}, http.StatusBadRequest)
 		return
 	}
 
 	// Fetch post details
-	post, err := post.FetchPost(postID)
-	if err != nil {
+	post, err := Stores.Posts.Get(postID)
+	if errors.Is(err, store.ErrPostNotFound) {
+		util.ExecuteJSON(w, model.MsgData{"Post not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("Failed to load post:", err)
 		util.ExecuteJSON(w, model.MsgData{"Failed to load the post"}, http.StatusInternalServerError)
 		return
 	}
 
+	// Content of shadowbanned users is only shown to themselves
+	hidden, ok := hiddenAuthors(w, sessionID)
+	if !ok {
+		return
+	}
+	if isHidden(hidden, post.UserID) {
+		util.ExecuteJSON(w, model.MsgData{"Post not found"}, http.StatusNotFound)
+		return
+	}
+
 	// Fetch post reactions
-	post.Likes, post.Dislikes, err = reaction.FetchReactionsNumber(post.ID, false)
+	post.Likes, post.Dislikes, err = Stores.Reactions.Counts(post.ID, false)
 	if err != nil {
 		// Continue with zero likes/dislikes if fetch fails
 		post.Likes = 0
 		post.Dislikes = 0
 	}
 
-	// Fetch comments for the post
-	post.Comments, err = comment.FetchCommentsForPost(post.ID)
+	// Fetch the comment thread, nested up to the requested depth
+	maxDepth, err := strconv.Atoi(r.URL.Query().Get("depth"))
+	if err != nil || maxDepth <= 0 || maxDepth > 20 {
+		maxDepth = comment.DefaultMaxDepth
+	}
+	comments, err := Stores.Comments.ListForPost(post.ID)
 	if err != nil {
 		// Continue with empty comments if fetch fails
-		post.Comments = []model.Comment{}
+		comments = nil
+	}
+	for i, c := range comments {
+		// Hidden comments look deleted, so replies to them keep their place
+		if isHidden(hidden, c.UserID) {
+			comments[i].Content = ""
+			comments[i].Username = ""
+			comments[i].UserID = 0
+			comments[i].EditedAt = ""
+			comments[i].Deleted = true
+		}
 	}
+	post.Comments = comment.BuildTree(comments, maxDepth)
 
 	// Get username for the logged-in user
 	var username string
 	if sessionID > 0 {
-		_ = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", sessionID).Scan(&username)
+		username, _ = Stores.Users.Username(sessionID)
 	}
 
 	// Prepare and send response
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"forum/internal/database"
 	"forum/internal/session"
 	"forum/internal/websocket"
 	"net/http"
@@ -12,27 +11,28 @@ var WebSocketHub *websocket.Hub
 
 // InitWebSocketHub creates and starts the WebSocket hub
 func InitWebSocketHub() {
-	WebSocketHub = websocket.NewHub()
+	WebSocketHub = websocket.NewHub(Stores.Users, Stores.Messages, Stores.Rooms, Stores.Sanctions, Stores.Notifications)
 	go WebSocketHub.Run()
 }
 
 // WebSocketHandler manages WebSocket connection requests
 func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	// Validate user session
-	userID, err := session.GetUserIDFromSession(r)
+	userID, err := Sessions.GetUserIDFromSession(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	sessionID, _ := session.GetSessionID(r)
+
 	// Fetch username for the authenticated user
-	var username string
-	err = database.Db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+	username, err := Stores.Users.Username(userID)
 	if err != nil {
 		http.Error(w, "Failed to retrieve username", http.StatusInternalServerError)
 		return
 	}
 
 	// Serve the WebSocket connection
-	websocket.ServeWs(WebSocketHub, w, r, userID, username)
+	websocket.ServeWs(WebSocketHub, w, r, userID, username, sessionID)
 }
\ No newline at end of file
@@ -1,27 +1,60 @@
 package model
 
+import "encoding/json"
+
 // Post represents a forum post
 type Post struct {
 	ID       int
 	Username string
 	UserID   int
-	Title    string
-	Content  string
-	Category string
-	Likes    int
-	Dislikes int
-	Comments []Comment
-	Date     string
+	Title      string
+	Content    string
+	Category   string
+	Categories []Category
+	Likes      int
+	Dislikes   int
+	Comments   []Comment
+	Date       string
+	EditedAt   string
+}
+
+// Category represents a category posts can be filed under
+type Category struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+	Archived    bool   `json:"archived"`
 }
 
-// Comment represents a comment on a post
+// Comment represents a comment on a post. Replies holds nested replies up to
+// the requested depth; ReplyCount counts every reply below the comment, so
+// Collapsed comments still show how many replies are hidden.
 type Comment struct {
-	ID       int
-	Username string
-	UserID   int
-	Content  string
-	Likes    int
-	Dislikes int
+	ID         int
+	PostID     int
+	ParentID   int
+	Username   string
+	UserID     int
+	Content    string
+	Likes      int
+	Dislikes   int
+	EditedAt   string
+	Deleted    bool
+	ReplyCount int
+	Collapsed  bool
+	Replies    []Comment
+}
+
+// Revision represents a previous version of a post or comment
+type Revision struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Content  string `json:"content"`
+	Category string `json:"category,omitempty"`
+	EditorID int    `json:"editorID"`
+	Editor   string `json:"editor"`
+	EditedAt string `json:"editedAt"`
 }
 
 // PostPageData represents data for a single post page
@@ -32,20 +65,23 @@ type PostPageData struct {
 
 // HomePageData represents summary data for posts on the home page
 type HomePageData struct {
-	ID       int
-	Title    string
-	Content  string
-	Username string
-	Likes    int
-	Dislikes int
-	Date     string
+	ID           int
+	Title        string
+	Content      string
+	Username     string
+	Category     string
+	Likes        int
+	Dislikes     int
+	CommentCount int
+	Date         string
 }
 
 // Data represents the main data structure for the home page
 type Data struct {
-	Posts     []HomePageData
-	SessionID int
-	Username  string
+	Posts      []HomePageData
+	NextCursor string
+	SessionID  int
+	Username   string
 }
 
 // MsgData is a generic message response
@@ -56,6 +92,7 @@ type MsgData struct {
 // PostData is a lightweight post representation
 type PostData struct {
 	ID       int    `json:"id"`
+	UserID   int    `json:"-"`
 	Title    string `json:"title"`
 	Category string `json:"category"`
 }
@@ -69,4 +106,155 @@ type User struct {
 	LastName  string
 	Age       int
 	Gender    string
-}
\ No newline at end of file
+	Role      string
+}
+// Session represents an active login on one device
+type Session struct {
+	ID        string `json:"id"`
+	UserAgent string `json:"userAgent"`
+	IPAddress string `json:"ipAddress"`
+	CreatedAt string `json:"createdAt"`
+	LastSeen  string `json:"lastSeen"`
+	ExpiresAt string `json:"expiresAt"`
+	Current   bool   `json:"current"`
+}
+
+// Room represents a group chat room
+type Room struct {
+	ID         int          `json:"id"`
+	Name       string       `json:"name"`
+	CategoryID int          `json:"categoryId"` // 0 when the room has no category
+	Category   string       `json:"category"`
+	CreatedBy  int          `json:"createdBy"`
+	CreatedAt  string       `json:"createdAt"`
+	Members    []RoomMember `json:"members"`
+}
+
+// RoomMember represents a user's membership in a room
+type RoomMember struct {
+	UserID   int    `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// RoomMessage represents a message posted in a room
+type RoomMessage struct {
+	ID        int    `json:"id"`
+	RoomID    int    `json:"room_id"`
+	SenderID  int    `json:"sender_id"`
+	Username  string `json:"username"`
+	Content   string `json:"content"`
+	Timestamp string `json:"timestamp"`
+}
+
+// SearchResult represents one full-text search match
+type SearchResult struct {
+	Type     string  `json:"type"`
+	ID       int     `json:"id"`
+	PostID   int     `json:"postID,omitempty"`
+	Title    string  `json:"title"`
+	Snippet  string  `json:"snippet"`
+	Username string  `json:"username"`
+	Date     string  `json:"date"`
+	Rank     float64 `json:"rank"`
+}
+
+// PrivateMessage represents a stored message between two users
+type PrivateMessage struct {
+	ID         int
+	SenderID   int
+	ReceiverID int
+	Content    string
+	Timestamp  string
+	EditedAt   string
+	Deleted    bool
+	Hidden     bool
+}
+
+// Conversation summarises the chat between the current user and one partner
+type Conversation struct {
+	UserID        int    `json:"id"`
+	Username      string `json:"username"`
+	LastMessage   string `json:"last_message,omitempty"`
+	LastSenderID  int    `json:"last_sender_id,omitempty"`
+	LastTimestamp string `json:"last_timestamp,omitempty"`
+	UnreadCount   int    `json:"unread_count"`
+	Online        bool   `json:"online"`
+}
+
+// MissedSender summarises the messages one sender left while the user was offline
+type MissedSender struct {
+	SenderID        int    `json:"sender_id"`
+	Username        string `json:"username"`
+	Count           int    `json:"count"`
+	LatestMessageID int    `json:"latest_message_id"`
+	LatestPreview   string `json:"latest_preview"`
+	LatestTimestamp string `json:"latest_timestamp"`
+}
+
+// Report represents a report of abusive content as its reporter sees it
+type Report struct {
+	ID         int    `json:"id"`
+	ItemType   string `json:"itemType"`
+	ItemID     int    `json:"itemID"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+	CreatedAt  string `json:"createdAt"`
+	Status     string `json:"status"`
+	Action     string `json:"action,omitempty"`
+	ResolvedAt string `json:"resolvedAt,omitempty"`
+}
+
+// QueuedReport represents an open report with what moderators need to judge
+// it. Content is the item as it was reported; Removed tells whether it has
+// been deleted since.
+type QueuedReport struct {
+	Report
+	ReporterID      int              `json:"reporterID"`
+	Reporter        string           `json:"reporter"`
+	AuthorID        int              `json:"authorID"`
+	Author          string           `json:"author"`
+	Content         string           `json:"content"`
+	Removed         bool             `json:"removed"`
+	PostID          int              `json:"postID,omitempty"`
+	PostTitle       string           `json:"postTitle,omitempty"`
+	Conversation    []ContextMessage `json:"conversation,omitempty"`
+	AuthorSanctions int              `json:"authorSanctions"`
+}
+
+// ContextMessage represents a chat message shown around a reported one
+type ContextMessage struct {
+	ID        int    `json:"id"`
+	Sender    string `json:"sender"`
+	Content   string `json:"content"`
+	Timestamp string `json:"timestamp"`
+	Deleted   bool   `json:"deleted"`
+}
+
+// Sanction represents a measure taken against a user as moderators see it
+type Sanction struct {
+	ID        int    `json:"id"`
+	Kind      string `json:"kind"`
+	Reason    string `json:"reason"`
+	Moderator string `json:"moderator"`
+	ReportID  int    `json:"reportID,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	LiftedBy  string `json:"liftedBy,omitempty"`
+	LiftedAt  string `json:"liftedAt,omitempty"`
+	Active    bool   `json:"active"`
+}
+
+// AuditEntry represents a privileged action in the audit log. Before and
+// After are snapshots of the target around the action.
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	ActorID    int             `json:"actorID"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetID   int             `json:"targetID,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  string          `json:"createdAt"`
+}
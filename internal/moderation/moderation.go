@@ -0,0 +1,132 @@
+package moderation
+
+import (
+	"forum/internal/store"
+	"time"
+)
+
+// Kinds of content that can be reported
+const (
+	ItemPost    = "post"
+	ItemComment = "comment"
+	ItemMessage = "message"
+)
+
+// Reasons lists the report reasons users choose from, in display order
+var Reasons = []string{
+	"spam",
+	"harassment",
+	"hate",
+	"violence",
+	"sexual",
+	"misinformation",
+	"other",
+}
+
+// Actions a moderator can resolve a report with
+const (
+	// ActionDismiss closes the report without doing anything
+	ActionDismiss = "dismiss"
+	// ActionHide removes the reported content
+	ActionHide = "hide"
+	// ActionWarn records a warning and tells the author
+	ActionWarn = "warn"
+	// ActionSuspend suspends the author and logs them out everywhere
+	ActionSuspend = "suspend"
+)
+
+// Kinds of sanction recorded against users
+const (
+	// SanctionWarning only tells the user off
+	SanctionWarning = "warning"
+	// SanctionSuspension locks the user out until it expires
+	SanctionSuspension = "suspension"
+	// SanctionBan locks the user out for good
+	SanctionBan = "ban"
+	// SanctionShadowban lets the user carry on, but nobody else sees what
+	// they post
+	SanctionShadowban = "shadowban"
+)
+
+// Limits for reports and their resolution
+const (
+	MaxDetailsLength      = 1000
+	MaxNoteLength         = 1000
+	DefaultSuspensionDays = 7
+	MaxSuspensionDays     = 365
+)
+
+// ValidItemType reports whether content of this kind can be reported
+func ValidItemType(itemType string) bool {
+	return itemType == ItemPost || itemType == ItemComment || itemType == ItemMessage
+}
+
+// ValidReason reports whether reason is one of Reasons
+func ValidReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidAction reports whether a report can be resolved with action
+func ValidAction(action string) bool {
+	switch action {
+	case ActionDismiss, ActionHide, ActionWarn, ActionSuspend:
+		return true
+	}
+	return false
+}
+
+// ValidSanctionKind reports whether a moderator can impose a sanction of
+// this kind directly
+func ValidSanctionKind(kind string) bool {
+	switch kind {
+	case SanctionWarning, SanctionSuspension, SanctionBan, SanctionShadowban:
+		return true
+	}
+	return false
+}
+
+// Blocking returns the sanction that keeps a user from logging in: a ban if
+// there is one, otherwise the suspension that ends last. The sanctions are
+// expected to be active.
+func Blocking(sanctions []store.Sanction) (store.Sanction, bool) {
+	var blocking store.Sanction
+	found := false
+	for _, s := range sanctions {
+		switch s.Kind {
+		case SanctionBan:
+			return s, true
+		case SanctionSuspension:
+			if !found || s.ExpiresAt.After(blocking.ExpiresAt) {
+				blocking, found = s, true
+			}
+		}
+	}
+	return blocking, found
+}
+
+// Shadowbanned reports whether one of the active sanctions is a shadowban
+func Shadowbanned(sanctions []store.Sanction) bool {
+	for _, s := range sanctions {
+		if s.Kind == SanctionShadowban {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedMessage tells a user why they cannot use their account
+func BlockedMessage(sanction store.Sanction) string {
+	message := "Your account is banned"
+	if sanction.Kind == SanctionSuspension {
+		message = "Your account is suspended until " + sanction.ExpiresAt.UTC().Format(time.RFC1123)
+	}
+	if sanction.Reason != "" {
+		message += ": " + sanction.Reason
+	}
+	return message
+}
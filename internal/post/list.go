@@ -0,0 +1,147 @@
+package post
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"forum/internal/model"
+	"time"
+)
+
+// Sort modes for post listings
+const (
+	SortNewest   = "newest"
+	SortTop      = "top"
+	SortComments = "comments"
+	SortHot      = "hot"
+)
+
+// Page sizes for post listings
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 50
+)
+
+// ErrInvalidCursor is returned when a cursor was not issued by a listing or
+// belongs to another sort mode
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Windows maps the time windows accepted by the top sort to their length in
+// days; zero means all time
+var Windows = map[string]int{
+	"day":   1,
+	"week":  7,
+	"month": 30,
+	"year":  365,
+	"all":   0,
+}
+
+// sorts lists the known sort modes
+var sorts = map[string]bool{
+	SortNewest:   true,
+	SortTop:      true,
+	SortComments: true,
+	SortHot:      true,
+}
+
+// ListOptions selects, filters and pages the posts of a listing
+type ListOptions struct {
+	Sort       string
+	Window     string
+	CategoryID int
+	Author     string
+	Cursor     string
+	Limit      int
+	// HiddenAuthors lists users whose posts are left out
+	HiddenAuthors []int
+}
+
+// Cursor marks the last post of a page. The reference time is kept so that
+// time based scores stay the same across pages.
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Score float64 `json:"v"`
+	ID    int     `json:"i"`
+	Now   int64   `json:"t"`
+}
+
+// IsValidSort reports whether sort is a known sort mode
+func IsValidSort(sort string) bool {
+	return sorts[sort]
+}
+
+// Prepare fills in the defaults of opts and decodes its cursor. It returns
+// the position to continue after, if any, and the reference time scores are
+// computed against.
+func Prepare(opts *ListOptions) (*Cursor, time.Time, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortNewest
+	}
+	if !IsValidSort(opts.Sort) {
+		return nil, time.Time{}, errors.New("unknown sort mode: " + opts.Sort)
+	}
+	if opts.Limit <= 0 || opts.Limit > MaxPageSize {
+		opts.Limit = DefaultPageSize
+	}
+
+	now := time.Now().UTC()
+	if opts.Cursor == "" {
+		return nil, now, nil
+	}
+
+	decoded, err := decodeCursor(opts.Cursor)
+	if err != nil || decoded.Sort != opts.Sort {
+		return nil, now, ErrInvalidCursor
+	}
+	return &decoded, time.Unix(decoded.Now, 0).UTC(), nil
+}
+
+// HotScore is the net score divided by the squared age in hours, so new
+// posts rise fast and sink as they get older
+func HotScore(net int, age time.Duration) float64 {
+	hours := age.Hours() + 2
+	return (float64(net) + 1) / (hours * hours)
+}
+
+// Page trims a listing fetched with one extra row down to opts.Limit and
+// returns the cursor of the next page, which is empty on the last page.
+// scores holds the sort score of each post.
+func Page(posts []model.HomePageData, scores []float64, opts ListOptions, now time.Time) ([]model.HomePageData, string, error) {
+	// The extra row only tells whether another page exists
+	if len(posts) <= opts.Limit {
+		return posts, "", nil
+	}
+	posts = posts[:opts.Limit]
+	last := len(posts) - 1
+
+	next, err := encodeCursor(Cursor{
+		Sort:  opts.Sort,
+		Score: scores[last],
+		ID:    posts[last].ID,
+		Now:   now.Unix(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, next, nil
+}
+
+// encodeCursor serializes a cursor into an opaque URL-safe string
+func encodeCursor(c Cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(value string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
@@ -0,0 +1,216 @@
+// Package ratelimit slows down clients that send too many requests or guess
+// passwords. State is kept in memory, so it is per process and lost on
+// restart.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle keys are forgotten
+const sweepInterval = time.Minute
+
+// Limiter is a token bucket per key: each key may spend up to burst tokens
+// at once and earns them back at a steady rate. A nil Limiter allows
+// everything.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter allows count requests per key every period, all of which may
+// come at once. It returns nil, allowing everything, when count is zero.
+func NewLimiter(count int, period time.Duration) *Limiter {
+	if count <= 0 || period <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:      float64(count) / period.Seconds(),
+		burst:     float64(count),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow spends a token of key. When none is left it returns false and how
+// long until the next one.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Refund gives back a token that Allow spent on a request that was turned
+// down after all, such as by another limiter
+func (l *Limiter) Refund(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = min(l.burst, b.tokens+1)
+	}
+}
+
+// sweep forgets the buckets that have filled up again, which behave like
+// new ones. The caller must hold mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Lockout counts failures per key, such as failed logins, and locks a key
+// out once it fails too often. Each failure past the threshold doubles the
+// lockout, up to a maximum. Failures are forgotten after a quiet period as
+// long as the maximum lockout. A nil Lockout never locks anything out.
+type Lockout struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*failures
+	lastSweep time.Time
+
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// NewLockout locks a key out for base once it failed threshold times in a
+// row, and for twice as long after each further failure up to max
+func NewLockout(threshold int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		entries:   make(map[string]*failures),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Locked returns how long key stays locked out, zero when it is not
+func (l *Lockout) Locked(key string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	if f, ok := l.entries[key]; ok && f.lockedUntil.After(now) {
+		return f.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// Fail records a failure of key and returns the lockout it causes, zero
+// while key is still under the threshold
+func (l *Lockout) Fail(key string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	f, ok := l.entries[key]
+	if !ok || l.expired(f, now) {
+		f = &failures{}
+		l.entries[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if f.count < l.threshold {
+		return 0
+	}
+	lockout := l.base
+	for i := l.threshold; i < f.count && lockout < l.max; i++ {
+		lockout *= 2
+	}
+	lockout = min(lockout, l.max)
+	f.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// Reset forgets the failures of key, e.g. after a successful login
+func (l *Lockout) Reset(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// expired reports whether the failures are old enough to be forgotten
+func (l *Lockout) expired(f *failures, now time.Time) bool {
+	return !f.lockedUntil.After(now) && now.Sub(f.last) >= l.max
+}
+
+// sweep forgets the keys whose failures expired. The caller must hold mu.
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, f := range l.entries {
+		if l.expired(f, now) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a fake time source that only moves when told to
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestLimiter returns a limiter running on a fake clock
+func newTestLimiter(count int, period time.Duration) (*Limiter, *clock) {
+	l := NewLimiter(count, period)
+	c := &clock{now: l.lastSweep}
+	l.now = c.Now
+	return l, c
+}
+
+// newTestLockout returns a lockout running on a fake clock
+func newTestLockout(threshold int, base, max time.Duration) (*Lockout, *clock) {
+	l := NewLockout(threshold, base, max)
+	c := &clock{now: l.lastSweep}
+	l.now = c.Now
+	return l, c
+}
+
+func TestLimiter(t *testing.T) {
+	// Three requests every three seconds: a burst of three, then one a second
+	l, c := newTestLimiter(3, 3*time.Second)
+
+	for i, step := range []struct {
+		advance time.Duration
+		key     string
+		allowed bool
+		wait    time.Duration
+	}{
+		{0, "a", true, 0},
+		{0, "a", true, 0},
+		{0, "a", true, 0},
+		{0, "a", false, time.Second},
+		{0, "b", true, 0},
+		{500 * time.Millisecond, "a", false, 500 * time.Millisecond},
+		{500 * time.Millisecond, "a", true, 0},
+		{0, "a", false, time.Second},
+		// Tokens refill up to the burst, not beyond
+		{time.Minute, "a", true, 0},
+		{0, "a", true, 0},
+		{0, "a", true, 0},
+		{0, "a", false, time.Second},
+	} {
+		c.advance(step.advance)
+		allowed, wait := l.Allow(step.key)
+		if allowed != step.allowed || wait != step.wait {
+			t.Errorf("step %d: Allow(%q) = %v, %v, want %v, %v", i+1, step.key, allowed, wait, step.allowed, step.wait)
+		}
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Hour)
+
+	l.Allow("a")
+	l.Allow("a")
+	l.Refund("a")
+	if allowed, _ := l.Allow("a"); !allowed {
+		t.Error("the refunded token could not be spent")
+	}
+	if allowed, _ := l.Allow("a"); allowed {
+		t.Error("more than the refunded token could be spent")
+	}
+
+	// Refunds never raise a bucket above the burst
+	l.Refund("b")
+	l.Allow("c")
+	l.Refund("c")
+	l.Refund("c")
+	for i := 1; i <= 3; i++ {
+		if allowed, _ := l.Allow("c"); allowed != (i <= 2) {
+			t.Errorf("request %d after refunds allowed: %v, want %v", i, allowed, i <= 2)
+		}
+	}
+}
+
+func TestLimiterForgetsIdleKeys(t *testing.T) {
+	l, c := newTestLimiter(1, time.Hour)
+
+	l.Allow("spent")
+	c.advance(2 * sweepInterval)
+	l.Allow("new")
+	if len(l.buckets) != 2 {
+		t.Errorf("%d buckets after the first sweep, want the spent and the new one", len(l.buckets))
+	}
+
+	// Once both have filled up again they are forgotten
+	c.advance(time.Hour)
+	l.Allow("newest")
+	if _, ok := l.buckets["spent"]; ok || len(l.buckets) != 1 {
+		t.Errorf("buckets after they refilled: %v, want only the newest", l.buckets)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	l := NewLimiter(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		if allowed, _ := l.Allow("a"); !allowed {
+			t.Fatal("a limiter without a count throttled a request")
+		}
+	}
+	l.Refund("a")
+}
+
+func TestLockout(t *testing.T) {
+	l, c := newTestLockout(3, time.Minute, 8*time.Minute)
+
+	// Each failure past the threshold doubles the lockout, up to the maximum
+	for i, want := range []time.Duration{
+		0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 8 * time.Minute,
+	} {
+		if got := l.Fail("alice"); got != want {
+			t.Errorf("failure %d locks out for %v, want %v", i+1, got, want)
+		}
+	}
+
+	c.advance(30 * time.Second)
+	if got, want := l.Locked("alice"), 7*time.Minute+30*time.Second; got != want {
+		t.Errorf("alice is locked out for %v, want %v", got, want)
+	}
+	if got := l.Locked("bob"); got != 0 {
+		t.Errorf("bob is locked out for %v", got)
+	}
+
+	c.advance(8 * time.Minute)
+	if got := l.Locked("alice"); got != 0 {
+		t.Errorf("alice is still locked out for %v after the lockout ended", got)
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	l, _ := newTestLockout(2, time.Minute, time.Hour)
+
+	l.Fail("alice")
+	l.Fail("alice")
+	l.Reset("alice")
+	if got := l.Locked("alice"); got != 0 {
+		t.Errorf("alice is locked out for %v after a reset", got)
+	}
+	if got := l.Fail("alice"); got != 0 {
+		t.Errorf("the first failure after a reset locks out for %v", got)
+	}
+}
+
+func TestLockoutForgetsOldFailures(t *testing.T) {
+	l, c := newTestLockout(3, time.Minute, 10*time.Minute)
+
+	for _, test := range []struct {
+		name  string
+		quiet time.Duration
+		want  time.Duration
+	}{
+		{"failures in a row", 9 * time.Minute, time.Minute},
+		{"failures after a quiet period", 10 * time.Minute, 0},
+	} {
+		key := test.name
+		l.Fail(key)
+		l.Fail(key)
+		c.advance(test.quiet)
+		if got := l.Fail(key); got != test.want {
+			t.Errorf("%s: third failure locks out for %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLockoutForgetsIdleKeys(t *testing.T) {
+	l, c := newTestLockout(1, time.Hour, time.Hour)
+
+	l.Fail("early")
+	c.advance(30 * time.Minute)
+	l.Fail("late")
+	c.advance(40 * time.Minute)
+
+	// The early lockout ended an hour after its failure, the late one has not
+	l.Locked("other")
+	if _, ok := l.entries["late"]; !ok || len(l.entries) != 1 {
+		t.Errorf("entries after the first sweep: %v, want only the late one", l.entries)
+	}
+
+	c.advance(time.Hour)
+	l.Locked("other")
+	if len(l.entries) != 0 {
+		t.Errorf("entries after a quiet hour: %v, want none", l.entries)
+	}
+}
+
+func TestNilLockout(t *testing.T) {
+	var l *Lockout
+	if l.Fail("a") != 0 || l.Locked("a") != 0 {
+		t.Error("a nil lockout locked a key out")
+	}
+	l.Reset("a")
+}
@@ -0,0 +1,88 @@
+// Package search parses search input and formats the snippets of matches.
+// The searching itself is done by the store backends.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Markers wrapped around matched terms in snippets; Highlight turns them
+// into <mark> tags after the rest of the snippet has been HTML-escaped
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+// Scopes that can be searched
+const (
+	ScopePosts    = "posts"
+	ScopeComments = "comments"
+	ScopeMessages = "messages"
+)
+
+// Term is one word or double-quoted phrase of the search input
+type Term struct {
+	Text string
+	// Prefix is set for a word ending in *, which matches every word
+	// starting with it
+	Prefix bool
+}
+
+// ParseTerms splits user input into terms, all of which must match.
+// Double-quoted text is kept as a phrase, a trailing * on a word makes it a
+// prefix match, and any other punctuation separates words.
+func ParseTerms(input string) []Term {
+	var terms []Term
+	var current strings.Builder
+	inPhrase := false
+
+	flush := func(prefix bool) {
+		word := strings.TrimSpace(current.String())
+		current.Reset()
+		if word != "" {
+			terms = append(terms, Term{Text: word, Prefix: prefix})
+		}
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			flush(false)
+			inPhrase = !inPhrase
+		case inPhrase:
+			current.WriteRune(r)
+		case r == '*':
+			flush(true)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		default:
+			flush(false)
+		}
+	}
+	flush(false)
+
+	return terms
+}
+
+// BuildQuery turns user input into a safe FTS5 query of the terms found by
+// ParseTerms. Any FTS5 syntax in the input is treated as plain text.
+func BuildQuery(input string) string {
+	var parts []string
+	for _, term := range ParseTerms(input) {
+		part := `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if term.Prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Highlight escapes a snippet and turns the match markers into <mark> tags
+func Highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, MatchStart, "<mark>")
+	return strings.ReplaceAll(escaped, MatchEnd, "</mark>")
+}
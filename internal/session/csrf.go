@@ -0,0 +1,65 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// CSRF tokens use the double-submit pattern: the token is kept in a cookie
+// that scripts cannot read and handed to the page by /user/status. Requests
+// that change state must repeat it in the header or the form field, which a
+// page on another site cannot do.
+const (
+	csrfCookieName = "csrf_token"
+	// CSRFHeader is the request header carrying the CSRF token
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFField is the form field carrying the CSRF token when the header is
+	// not set
+	CSRFField = "csrf_token"
+)
+
+// CSRFToken returns the CSRF token of the request, issuing a new one when
+// it has none
+func CSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	return RotateCSRFToken(w)
+}
+
+// RotateCSRFToken issues a new CSRF token, replacing the one the client
+// had. Logging in rotates it so that a token learned before cannot be used
+// with the new session.
+func RotateCSRFToken(w http.ResponseWriter) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		HttpOnly: true,
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// ValidCSRFToken reports whether the request repeats the CSRF token of its
+// cookie in the CSRFHeader header or, failing that, the CSRFField form field
+func ValidCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	token := r.Header.Get(CSRFHeader)
+	if token == "" {
+		token = r.FormValue(CSRFField)
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) == 1
+}
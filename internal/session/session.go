@@ -1,23 +1,37 @@
 package session
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"forum/internal/database"
+	"forum/internal/model"
+	"forum/internal/moderation"
+	"forum/internal/store"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/gofrs/uuid"
 )
 
-// CreateSession generates a new session for a user
-func CreateSession(w http.ResponseWriter, userID int) error {
-	// Delete any existing sessions for this user
-	_, err := database.Db.Exec("DELETE FROM sessions WHERE id = ?", userID)
-	if err != nil {
-		return err
-	}
+// Manager ties login sessions to the session cookie of requests
+type Manager struct {
+	sessions store.SessionStore
+	// sanctions is checked on every request so that suspended and banned
+	// users lose their sessions
+	sanctions store.SanctionStore
+	// lifetime is how long a session stays valid without activity
+	lifetime time.Duration
+}
+
+// NewManager returns a Manager keeping sessions in the given store
+func NewManager(sessions store.SessionStore, sanctions store.SanctionStore, lifetime time.Duration) *Manager {
+	return &Manager{sessions: sessions, sanctions: sanctions, lifetime: lifetime}
+}
 
+// CreateSession generates a new session for a user on the requesting device.
+// Existing sessions on other devices are left untouched.
+func (m *Manager) CreateSession(w http.ResponseWriter, r *http.Request, userID int) error {
 	// Generate a new session ID
 	sessionID, err := uuid.NewV4()
 	if err != nil {
@@ -25,13 +39,18 @@ func CreateSession(w http.ResponseWriter, userID int) error {
 	}
 
 	// Set session expiration
-	expires := time.Now().Add(24 * time.Hour)
-	
-	// Insert new session
-	_, err = database.Db.Exec(
-		"INSERT INTO sessions (session_id, id, expires_at) VALUES (?, ?, ?)",
-		sessionID.String(), userID, expires,
-	)
+	now := time.Now()
+	expires := now.Add(m.lifetime)
+
+	err = m.sessions.Create(store.Session{
+		ID:        sessionID.String(),
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IPAddress: ClientIP(r),
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: expires,
+	})
 	if err != nil {
 		return err
 	}
@@ -45,73 +64,159 @@ func CreateSession(w http.ResponseWriter, userID int) error {
 		Path:     "/",
 		SameSite: http.SameSiteLaxMode,
 	})
-	
+
 	return nil
 }
 
-// DeleteSession removes a session from the database
-func DeleteSession(sessionID string) error {
-	_, err := database.Db.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
-	return err
+// DeleteSession removes a session
+func (m *Manager) DeleteSession(sessionID string) error {
+	return m.sessions.Delete(sessionID)
+}
+
+// GetSessionID returns the raw session ID from the request cookie
+func GetSessionID(r *http.Request) (string, error) {
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		return "", fmt.Errorf("no session cookie found")
+	}
+	return cookie.Value, nil
 }
 
 // GetUserIDFromSession retrieves the user ID for a given session
-func GetUserIDFromSession(r *http.Request) (int, error) {
+func (m *Manager) GetUserIDFromSession(r *http.Request) (int, error) {
 	// Get session cookie
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		return 0, fmt.Errorf("no session cookie found")
 	}
 
-	var userID int
-	var expiresAt time.Time
-	
 	// Retrieve session details
-	err = database.Db.QueryRow(
-		"SELECT id, expires_at FROM sessions WHERE session_id = ?", cookie.Value,
-	).Scan(&userID, &expiresAt)
-
-	if err == sql.ErrNoRows {
+	s, err := m.sessions.Get(cookie.Value)
+	if err == store.ErrNotFound {
 		return 0, fmt.Errorf("session not found")
 	} else if err != nil {
 		return 0, err
 	}
 
 	// Check session expiration
-	if time.Now().After(expiresAt) {
+	if time.Now().After(s.ExpiresAt) {
 		// Delete expired session
-		go DeleteSession(cookie.Value)
+		go m.DeleteSession(cookie.Value)
 		return 0, fmt.Errorf("session expired")
 	}
 
+	// Suspended or banned users are logged out on their next request
+	sanctions, err := m.sanctions.Active(s.UserID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if _, blocked := moderation.Blocking(sanctions); blocked {
+		_ = m.DeleteSession(cookie.Value)
+		return 0, fmt.Errorf("account is suspended or banned")
+	}
+
 	// Extend session on activity
-	go extendSession(cookie.Value)
-	
-	return userID, nil
+	go m.extendSession(cookie.Value)
+
+	return s.UserID, nil
+}
+
+// extendSession updates the session expiration and last-seen times
+func (m *Manager) extendSession(sessionID string) {
+	now := time.Now()
+	_ = m.sessions.Touch(sessionID, now, now.Add(m.lifetime))
 }
 
-// extendSession updates the session expiration time
-func extendSession(sessionID string) {
-	newExpiry := time.Now().Add(24 * time.Hour)
-	
-	_, _ = database.Db.Exec(
-		"UPDATE sessions SET expires_at = ? WHERE session_id = ?",
-		newExpiry, sessionID,
-	)
+// GetUserSessions lists the active sessions of a user, most recently used first
+func (m *Manager) GetUserSessions(userID int, currentSessionID string) ([]model.Session, error) {
+	stored, err := m.sessions.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := []model.Session{}
+	for _, s := range stored {
+		if !s.ExpiresAt.After(now) {
+			continue
+		}
+		sessions = append(sessions, model.Session{
+			ID:        PublicID(s.ID),
+			UserAgent: s.UserAgent,
+			IPAddress: s.IPAddress,
+			CreatedAt: s.CreatedAt.Format(time.RFC3339),
+			LastSeen:  s.LastSeen.Format(time.RFC3339),
+			ExpiresAt: s.ExpiresAt.Format(time.RFC3339),
+			Current:   s.ID == currentSessionID,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes one of the user's sessions by its public ID and
+// returns its raw session ID, even when deleting it failed. It returns
+// store.ErrNotFound when the user has no session with that ID.
+func (m *Manager) RevokeSession(userID int, publicID string) (string, error) {
+	sessions, err := m.sessions.ListForUser(userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, s := range sessions {
+		if PublicID(s.ID) == publicID {
+			return s.ID, m.DeleteSession(s.ID)
+		}
+	}
+	return "", store.ErrNotFound
 }
 
-// CleanupExpiredSessions removes all expired sessions from the database
-func CleanupExpiredSessions() {
-	result, _ := database.Db.Exec("DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP")
-	
-	count, _ := result.RowsAffected()
+// RevokeOtherSessions deletes every session of the user except the current one
+// and returns the raw session IDs that were removed
+func (m *Manager) RevokeOtherSessions(userID int, currentSessionID string) ([]string, error) {
+	sessions, err := m.sessions.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []string
+	for _, s := range sessions {
+		if s.ID == currentSessionID {
+			continue
+		}
+		if err := m.DeleteSession(s.ID); err != nil {
+			return revoked, err
+		}
+		revoked = append(revoked, s.ID)
+	}
+	return revoked, nil
+}
+
+// PublicID derives a stable identifier for a session that can be shown to the
+// client without exposing the cookie value itself
+func PublicID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ClientIP extracts the remote address of the request without the port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// CleanupExpiredSessions removes all expired sessions
+func (m *Manager) CleanupExpiredSessions() {
+	count, _ := m.sessions.DeleteExpired(time.Now())
 	if count > 0 {
 		fmt.Printf("Cleaned up %d expired sessions\n", count)
 	}
 }
 
 // IsAuthenticated checks if a request is from an authenticated user
-func IsAuthenticated(r *http.Request) bool {
-	userID, err := GetUserIDFromSession(r)
+func (m *Manager) IsAuthenticated(r *http.Request) bool {
+	userID, err := m.GetUserIDFromSession(r)
 	return err == nil && userID > 0
-}
\ No newline at end of file
+}
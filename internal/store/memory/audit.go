@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"forum/internal/store"
+	"time"
+)
+
+type auditStore struct {
+	*data
+}
+
+func (s *auditStore) Append(entry store.AuditEntry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.ID = len(s.auditLog) + 1
+	s.auditLog = append(s.auditLog, entry)
+	return entry.ID, nil
+}
+
+func (s *auditStore) List(filter store.AuditFilter, limit, offset int) ([]store.AuditEntry, int, error) {
+	matching := s.matching(filter)
+
+	// Newest first
+	for i, j := 0, len(matching)-1; i < j; i, j = i+1, j-1 {
+		matching[i], matching[j] = matching[j], matching[i]
+	}
+
+	total := len(matching)
+	if offset > total {
+		offset = total
+	}
+	matching = matching[offset:]
+	if len(matching) > limit {
+		matching = matching[:limit]
+	}
+	return matching, total, nil
+}
+
+func (s *auditStore) Each(filter store.AuditFilter, fn func(store.AuditEntry) error) error {
+	for _, entry := range s.matching(filter) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matching copies the entries accepted by filter, oldest first
+func (s *auditStore) matching(filter store.AuditFilter) []store.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []store.AuditEntry
+	for _, entry := range s.auditLog {
+		switch {
+		case filter.ActorID > 0 && entry.ActorID != filter.ActorID,
+			filter.Action != "" && entry.Action != filter.Action,
+			filter.TargetType != "" && entry.TargetType != filter.TargetType,
+			filter.TargetID > 0 && entry.TargetID != filter.TargetID,
+			!filter.Since.IsZero() && entry.CreatedAt.Before(filter.Since),
+			!filter.Until.IsZero() && !entry.CreatedAt.Before(filter.Until):
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
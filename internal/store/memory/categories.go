@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+	"sort"
+	"strings"
+)
+
+type categoryStore struct {
+	*data
+}
+
+func (s *categoryStore) List(includeArchived bool) ([]model.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := []model.Category{}
+	for _, c := range s.sortedCategories() {
+		if includeArchived || !c.Archived {
+			categories = append(categories, *c)
+		}
+	}
+	return categories, nil
+}
+
+func (s *categoryStore) Get(categoryID int) (model.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.category(categoryID)
+	if c == nil {
+		return model.Category{}, store.ErrCategoryNotFound
+	}
+	return *c, nil
+}
+
+func (s *categoryStore) Create(name, description string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUniqueName(name, 0); err != nil {
+		return 0, err
+	}
+
+	position := 0
+	for _, c := range s.categories {
+		if c.Position >= position {
+			position = c.Position + 1
+		}
+	}
+
+	c := &model.Category{
+		ID:          len(s.categories) + 1,
+		Name:        name,
+		Description: description,
+		Position:    position,
+	}
+	s.categories = append(s.categories, c)
+	return c.ID, nil
+}
+
+func (s *categoryStore) Update(categoryID int, name, description string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUniqueName(name, categoryID); err != nil {
+		return err
+	}
+
+	c := s.category(categoryID)
+	if c == nil {
+		return store.ErrCategoryNotFound
+	}
+	c.Name = name
+	c.Description = description
+	return nil
+}
+
+func (s *categoryStore) Reorder(categoryIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current []int
+	for _, c := range s.sortedCategories() {
+		current = append(current, c.ID)
+	}
+
+	order, err := store.ReorderIDs(current, categoryIDs)
+	if err != nil {
+		return err
+	}
+	for position, id := range order {
+		s.category(id).Position = position
+	}
+	return nil
+}
+
+func (s *categoryStore) SetArchived(categoryID int, archived bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.category(categoryID)
+	if c == nil {
+		return store.ErrCategoryNotFound
+	}
+	c.Archived = archived
+	return nil
+}
+
+func (s *categoryStore) ValidateIDs(categoryIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range categoryIDs {
+		c := s.category(id)
+		if c == nil {
+			return store.ErrCategoryNotFound
+		}
+		if c.Archived {
+			return store.ErrCategoryArchived
+		}
+	}
+	return nil
+}
+
+func (s *categoryStore) DefaultID() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.sortedCategories() {
+		if !c.Archived {
+			return c.ID, nil
+		}
+	}
+	return 0, store.ErrCategoryNotFound
+}
+
+// requireUniqueName returns ErrDuplicateCategory when another category
+// already uses name, ignoring case. Callers hold s.mu.
+func (s *categoryStore) requireUniqueName(name string, categoryID int) error {
+	for _, c := range s.categories {
+		if c.ID != categoryID && strings.EqualFold(c.Name, name) {
+			return store.ErrDuplicateCategory
+		}
+	}
+	return nil
+}
+
+// sortedCategories returns every category in display order. Callers hold d.mu.
+func (d *data) sortedCategories() []*model.Category {
+	sorted := append([]*model.Category(nil), d.categories...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Position != sorted[j].Position {
+			return sorted[i].Position < sorted[j].Position
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
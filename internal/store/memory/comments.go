@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+	"time"
+)
+
+type commentStore struct {
+	*data
+}
+
+func (s *commentStore) ListForPost(postID int) ([]model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var comments []model.Comment
+	for _, c := range s.comments {
+		if c.postID != postID {
+			continue
+		}
+
+		result := model.Comment{ID: c.id, PostID: postID, ParentID: c.parentID, Deleted: c.deleted}
+		if !c.editedAt.IsZero() {
+			result.EditedAt = c.editedAt.Format(time.RFC3339)
+		}
+		if !c.deleted {
+			result.UserID = c.userID
+			result.Username = s.username(c.userID)
+			result.Content = c.content
+			result.Likes, result.Dislikes = s.reactionCounts(c.id, true)
+		}
+		comments = append(comments, result)
+	}
+	return comments, nil
+}
+
+func (s *commentStore) Create(userID, postID, parentID int, content string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.livePost(postID) == nil {
+		return 0, store.ErrPostNotFound
+	}
+
+	// Replies must target a live comment of the same post
+	if parentID > 0 {
+		parent := s.liveComment(parentID)
+		if parent == nil || parent.postID != postID {
+			return 0, store.ErrCommentNotFound
+		}
+	}
+
+	c := &comment{
+		id:       len(s.comments) + 1,
+		postID:   postID,
+		parentID: parentID,
+		userID:   userID,
+		content:  content,
+	}
+	s.comments = append(s.comments, c)
+	return c.id, nil
+}
+
+func (s *commentStore) Get(commentID int) (model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if commentID <= 0 || commentID > len(s.comments) {
+		return model.Comment{}, store.ErrCommentNotFound
+	}
+	c := s.comments[commentID-1]
+
+	result := model.Comment{
+		ID:       c.id,
+		PostID:   c.postID,
+		ParentID: c.parentID,
+		UserID:   c.userID,
+		Username: s.username(c.userID),
+		Content:  c.content,
+		Deleted:  c.deleted,
+	}
+	if !c.editedAt.IsZero() {
+		result.EditedAt = c.editedAt.Format(time.RFC3339)
+	}
+	return result, nil
+}
+
+func (s *commentStore) AuthorID(commentID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if commentID <= 0 || commentID > len(s.comments) {
+		return 0, store.ErrCommentNotFound
+	}
+	return s.comments[commentID-1].userID, nil
+}
+
+func (s *commentStore) Update(commentID, userID int, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.ownedComment(commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.commentRevisions = append(s.commentRevisions, commentRevision{
+		commentID: commentID,
+		content:   c.content,
+		editedBy:  userID,
+		editedAt:  now,
+	})
+	c.content = content
+	c.editedAt = now
+	return nil
+}
+
+func (s *commentStore) Delete(commentID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.ownedComment(commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.clearReactions(commentID, true)
+	c.deleted = true
+	return nil
+}
+
+func (s *commentStore) Remove(commentID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.liveComment(commentID)
+	if c == nil {
+		return store.ErrCommentNotFound
+	}
+
+	s.clearReactions(commentID, true)
+	c.deleted = true
+	return nil
+}
+
+// ownedComment returns a live comment, checking that userID wrote it. Callers hold s.mu.
+func (s *commentStore) ownedComment(commentID, userID int) (*comment, error) {
+	c := s.liveComment(commentID)
+	if c == nil {
+		return nil, store.ErrCommentNotFound
+	}
+	if c.userID != userID {
+		return nil, store.ErrNotCommentOwner
+	}
+	return c, nil
+}
@@ -0,0 +1,209 @@
+// Package memory implements the forum stores in process memory. It needs no
+// database, which makes it handy for handler tests and quick local runs;
+// everything is lost when the process exits.
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+	"sync"
+	"time"
+)
+
+// NewStores returns every store backed by one shared in-memory data set
+func NewStores() store.Stores {
+	d := &data{
+		reactions: make(map[reactionKey]string),
+		sessions:  make(map[string]store.Session),
+		reads:     make(map[[2]int]int),
+	}
+	return store.Stores{
+		Users:         &userStore{d},
+		Posts:         &postStore{d},
+		Comments:      &commentStore{d},
+		Reactions:     &reactionStore{d},
+		Categories:    &categoryStore{d},
+		Sessions:      &sessionStore{d},
+		Messages:      &messageStore{d},
+		Reports:       &reportStore{d},
+		Sanctions:     &sanctionStore{d},
+		Audit:         &auditStore{d},
+		Rooms:         &roomStore{d},
+		Search:        &searchStore{d},
+		Notifications: &notificationStore{d},
+	}
+}
+
+// data holds every record. IDs are assigned from 1 in insertion order so
+// each slice can be indexed by ID-1.
+type data struct {
+	mu sync.Mutex
+
+	users            []*user
+	posts            []*post
+	postRevisions    []postRevision
+	comments         []*comment
+	commentRevisions []commentRevision
+	reactions        map[reactionKey]string
+	categories       []*model.Category
+	sessions         map[string]store.Session
+	messages         []*model.PrivateMessage
+	reads            map[[2]int]int
+	pending          []pendingNotification
+	notifications    []notification
+	reports          []*store.Report
+	sanctions        []store.Sanction
+	auditLog         []store.AuditEntry
+	rooms            []*room
+	// Room messages of deleted rooms are dropped, so their IDs are counted
+	// separately
+	roomMessages      []*roomMessage
+	lastRoomMessageID int
+}
+
+type user struct {
+	model.User
+	passwordHash string
+	role         string
+}
+
+type post struct {
+	id          int
+	userID      int
+	title       string
+	content     string
+	categoryIDs []int
+	date        time.Time
+	editedAt    time.Time
+	deleted     bool
+}
+
+type postRevision struct {
+	postID int
+	model.Revision
+}
+
+type comment struct {
+	id       int
+	postID   int
+	parentID int
+	userID   int
+	content  string
+	editedAt time.Time
+	deleted  bool
+}
+
+type commentRevision struct {
+	commentID int
+	content   string
+	editedBy  int
+	editedAt  time.Time
+}
+
+// reactionKey identifies the reaction of a user on a post or comment
+type reactionKey struct {
+	userID    int
+	itemID    int
+	isComment bool
+}
+
+type room struct {
+	id         int
+	name       string
+	categoryID int
+	createdBy  int
+	createdAt  time.Time
+	// members are kept in joining order
+	members []roomMember
+	deleted bool
+}
+
+type roomMember struct {
+	userID int
+	role   string
+}
+
+type roomMessage struct {
+	model.RoomMessage
+	hidden bool
+}
+
+type pendingNotification struct {
+	userID    int
+	senderID  int
+	messageID int
+}
+
+type notification struct {
+	userID int
+	frame  []byte
+}
+
+// user returns the user with the given ID, or nil. Callers hold d.mu.
+func (d *data) user(userID int) *user {
+	if userID <= 0 || userID > len(d.users) {
+		return nil
+	}
+	return d.users[userID-1]
+}
+
+// username returns the name of a user, or "Unknown". Callers hold d.mu.
+func (d *data) username(userID int) string {
+	if u := d.user(userID); u != nil {
+		return u.Username
+	}
+	return "Unknown"
+}
+
+// livePost returns the post with the given ID unless it is missing or
+// deleted. Callers hold d.mu.
+func (d *data) livePost(postID int) *post {
+	if postID <= 0 || postID > len(d.posts) || d.posts[postID-1].deleted {
+		return nil
+	}
+	return d.posts[postID-1]
+}
+
+// liveComment returns the comment with the given ID unless it is missing or
+// deleted. Callers hold d.mu.
+func (d *data) liveComment(commentID int) *comment {
+	if commentID <= 0 || commentID > len(d.comments) || d.comments[commentID-1].deleted {
+		return nil
+	}
+	return d.comments[commentID-1]
+}
+
+// category returns the category with the given ID, or nil. Callers hold d.mu.
+func (d *data) category(categoryID int) *model.Category {
+	for _, c := range d.categories {
+		if c.ID == categoryID {
+			return c
+		}
+	}
+	return nil
+}
+
+// reactionCounts counts the likes and dislikes of an item. Callers hold d.mu.
+func (d *data) reactionCounts(itemID int, isComment bool) (likes, dislikes int) {
+	for key, reactionType := range d.reactions {
+		if key.itemID != itemID || key.isComment != isComment {
+			continue
+		}
+		switch reactionType {
+		case "like":
+			likes++
+		case "dislike":
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+// clearReactions removes every reaction on an item. Callers hold d.mu.
+func (d *data) clearReactions(itemID int, isComment bool) {
+	for key := range d.reactions {
+		if key.itemID == itemID && key.isComment == isComment {
+			delete(d.reactions, key)
+		}
+	}
+}
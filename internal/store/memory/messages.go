@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+	"sort"
+	"strings"
+	"time"
+)
+
+type messageStore struct {
+	*data
+}
+
+func (s *messageStore) Create(senderID, receiverID int, content string, hidden bool) (model.PrivateMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := &model.PrivateMessage{
+		ID:         len(s.messages) + 1,
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    content,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Hidden:     hidden,
+	}
+	s.messages = append(s.messages, msg)
+	return *msg, nil
+}
+
+func (s *messageStore) Get(messageID int) (model.PrivateMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.message(messageID)
+	if msg == nil {
+		return model.PrivateMessage{}, store.ErrNotFound
+	}
+	return *msg, nil
+}
+
+func (s *messageStore) History(userID, partnerID, beforeID, limit int) ([]model.PrivateMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Walk back from the newest message, keeping one extra to know whether
+	// another page exists
+	var messages []model.PrivateMessage
+	for i := len(s.messages) - 1; i >= 0 && len(messages) <= limit; i-- {
+		msg := s.messages[i]
+		if beforeID > 0 && msg.ID >= beforeID {
+			continue
+		}
+		if (msg.SenderID == userID && msg.ReceiverID == partnerID) || (msg.SenderID == partnerID && msg.ReceiverID == userID && !msg.Hidden) {
+			messages = append(messages, *msg)
+		}
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Reverse order to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, hasMore, nil
+}
+
+func (s *messageStore) Update(messageID int, content, editedAt string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg := s.message(messageID); msg != nil {
+		msg.Content = content
+		msg.EditedAt = editedAt
+	}
+	return nil
+}
+
+func (s *messageStore) Delete(messageID int, deletedAt string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg := s.message(messageID); msg != nil {
+		msg.Content = ""
+		msg.Deleted = true
+	}
+
+	// A deleted message is no longer worth announcing to an offline receiver
+	kept := s.pending[:0]
+	for _, n := range s.pending {
+		if n.messageID != messageID {
+			kept = append(kept, n)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+func (s *messageStore) Conversations(userID int) ([]model.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastIDs := make(map[int]int)
+	for _, msg := range s.messages {
+		switch userID {
+		case msg.SenderID:
+			lastIDs[msg.ReceiverID] = msg.ID
+		case msg.ReceiverID:
+			if !msg.Hidden {
+				lastIDs[msg.SenderID] = msg.ID
+			}
+		}
+	}
+
+	conversations := []model.Conversation{}
+	for _, u := range s.users {
+		if u.ID == userID {
+			continue
+		}
+		conv := model.Conversation{UserID: u.ID, Username: u.Username, UnreadCount: s.unread(userID, u.ID)}
+		if last := s.message(lastIDs[u.ID]); last != nil {
+			conv.LastMessage = last.Content
+			conv.LastSenderID = last.SenderID
+			conv.LastTimestamp = last.Timestamp
+		}
+		conversations = append(conversations, conv)
+	}
+
+	sort.SliceStable(conversations, func(i, j int) bool {
+		a, b := lastIDs[conversations[i].UserID], lastIDs[conversations[j].UserID]
+		if a != b {
+			return a > b
+		}
+		return strings.ToLower(conversations[i].Username) < strings.ToLower(conversations[j].Username)
+	})
+	return conversations, nil
+}
+
+func (s *messageStore) MarkRead(userID, partnerID, messageID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if messageID <= 0 {
+		for _, msg := range s.messages {
+			if msg.SenderID == partnerID && msg.ReceiverID == userID && !msg.Hidden {
+				messageID = msg.ID
+			}
+		}
+	}
+
+	// Never move the marker backwards
+	key := [2]int{userID, partnerID}
+	if messageID > s.reads[key] {
+		s.reads[key] = messageID
+	}
+	return s.reads[key], nil
+}
+
+func (s *messageStore) LastReadID(userID, partnerID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reads[[2]int{userID, partnerID}], nil
+}
+
+func (s *messageStore) UnreadCounts(userID int) (map[int]int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[int]int)
+	total := 0
+	for _, msg := range s.messages {
+		if msg.ReceiverID == userID && !msg.Deleted && !msg.Hidden && msg.ID > s.reads[[2]int{userID, msg.SenderID}] {
+			counts[msg.SenderID]++
+			total++
+		}
+	}
+	return counts, total, nil
+}
+
+func (s *messageStore) AddPendingNotification(userID, senderID, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, pendingNotification{userID, senderID, messageID})
+	return nil
+}
+
+func (s *messageStore) MissedMessages(userID int) ([]model.MissedSender, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bySender := make(map[int]*model.MissedSender)
+	for _, n := range s.pending {
+		if n.userID != userID {
+			continue
+		}
+		sender, ok := bySender[n.senderID]
+		if !ok {
+			sender = &model.MissedSender{SenderID: n.senderID, Username: s.username(n.senderID)}
+			bySender[n.senderID] = sender
+		}
+		sender.Count++
+		if n.messageID > sender.LatestMessageID {
+			sender.LatestMessageID = n.messageID
+		}
+	}
+
+	senders := []model.MissedSender{}
+	for _, sender := range bySender {
+		if latest := s.message(sender.LatestMessageID); latest != nil {
+			sender.LatestPreview = latest.Content
+			sender.LatestTimestamp = latest.Timestamp
+		}
+		senders = append(senders, *sender)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return senders[i].LatestMessageID > senders[j].LatestMessageID
+	})
+	return senders, nil
+}
+
+func (s *messageStore) ClearPendingNotifications(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.pending[:0]
+	for _, n := range s.pending {
+		if n.userID != userID {
+			kept = append(kept, n)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+// message returns the message with the given ID, or nil. Callers hold s.mu.
+func (s *messageStore) message(messageID int) *model.PrivateMessage {
+	if messageID <= 0 || messageID > len(s.messages) {
+		return nil
+	}
+	return s.messages[messageID-1]
+}
+
+// unread counts the live messages from partnerID that userID has not read
+// yet. Callers hold s.mu.
+func (s *messageStore) unread(userID, partnerID int) int {
+	count := 0
+	for _, msg := range s.messages {
+		if msg.SenderID == partnerID && msg.ReceiverID == userID && !msg.Deleted && !msg.Hidden && msg.ID > s.reads[[2]int{userID, partnerID}] {
+			count++
+		}
+	}
+	return count
+}
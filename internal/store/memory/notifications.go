@@ -0,0 +1,30 @@
+package memory
+
+type notificationStore struct {
+	*data
+}
+
+func (s *notificationStore) Add(userID int, frame []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notifications = append(s.notifications, notification{userID, append([]byte(nil), frame...)})
+	return nil
+}
+
+func (s *notificationStore) Take(userID int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := [][]byte{}
+	kept := s.notifications[:0]
+	for _, n := range s.notifications {
+		if n.userID == userID {
+			frames = append(frames, n.frame)
+		} else {
+			kept = append(kept, n)
+		}
+	}
+	s.notifications = kept
+	return frames, nil
+}
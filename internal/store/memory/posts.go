@@ -0,0 +1,323 @@
+package memory
+
+import (
+	"forum/internal/model"
+	forumpost "forum/internal/post"
+	"forum/internal/store"
+	"sort"
+	"strings"
+	"time"
+)
+
+type postStore struct {
+	*data
+}
+
+func (s *postStore) Create(userID int, title, content string, categoryIDs []int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &post{
+		id:          len(s.posts) + 1,
+		userID:      userID,
+		title:       title,
+		content:     content,
+		categoryIDs: uniqueIDs(categoryIDs),
+		date:        time.Now().UTC().Truncate(time.Second),
+	}
+	s.posts = append(s.posts, p)
+	return p.id, nil
+}
+
+func (s *postStore) Get(postID int) (model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.livePost(postID)
+	if p == nil {
+		return model.Post{}, store.ErrPostNotFound
+	}
+
+	result := model.Post{
+		ID:         p.id,
+		UserID:     p.userID,
+		Username:   s.username(p.userID),
+		Title:      p.title,
+		Content:    p.content,
+		Category:   s.categoryNames(p),
+		Categories: s.postCategories(p),
+		Date:       p.date.Format(time.RFC3339),
+	}
+	if !p.editedAt.IsZero() {
+		result.EditedAt = p.editedAt.Format(time.RFC3339)
+	}
+	return result, nil
+}
+
+func (s *postStore) Update(postID, userID int, title, content string, categoryIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.ownedPost(postID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.postRevisions = append(s.postRevisions, postRevision{
+		postID: postID,
+		Revision: model.Revision{
+			ID:       len(s.postRevisions) + 1,
+			Title:    p.title,
+			Content:  p.content,
+			Category: s.categoryNames(p),
+			EditorID: userID,
+			EditedAt: now.Format(time.RFC3339),
+		},
+	})
+
+	p.title = title
+	p.content = content
+	p.categoryIDs = uniqueIDs(categoryIDs)
+	p.editedAt = now
+	return nil
+}
+
+func (s *postStore) Delete(postID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.ownedPost(postID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.removePost(p)
+	return nil
+}
+
+func (s *postStore) Remove(postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.livePost(postID)
+	if p == nil {
+		return store.ErrPostNotFound
+	}
+	s.removePost(p)
+	return nil
+}
+
+// removePost deletes a post with its comments and removes their reactions.
+// Callers hold s.mu.
+func (s *postStore) removePost(p *post) {
+	postID := p.id
+	for _, c := range s.comments {
+		if c.postID == postID {
+			s.clearReactions(c.id, true)
+			c.deleted = true
+		}
+	}
+	s.clearReactions(postID, false)
+	p.deleted = true
+}
+
+func (s *postStore) Revisions(postID int) ([]model.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.livePost(postID) == nil {
+		return nil, store.ErrPostNotFound
+	}
+
+	revisions := []model.Revision{}
+	for i := len(s.postRevisions) - 1; i >= 0; i-- {
+		if s.postRevisions[i].postID != postID {
+			continue
+		}
+		revision := s.postRevisions[i].Revision
+		revision.Editor = s.username(revision.EditorID)
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (s *postStore) List(opts forumpost.ListOptions) ([]model.HomePageData, string, error) {
+	after, now, err := forumpost.Prepare(&opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type scored struct {
+		post  model.HomePageData
+		score float64
+	}
+	var listed []scored
+	days := forumpost.Windows[opts.Window]
+	for _, p := range s.posts {
+		if p.deleted || !s.matches(p, opts) {
+			continue
+		}
+		if opts.Sort == forumpost.SortTop && days > 0 && p.date.Before(now.AddDate(0, 0, -days)) {
+			continue
+		}
+
+		summary := s.summary(p)
+		var score float64
+		switch opts.Sort {
+		case forumpost.SortNewest:
+			score = float64(p.id)
+		case forumpost.SortTop:
+			score = float64(summary.Likes - summary.Dislikes)
+		case forumpost.SortComments:
+			score = float64(summary.CommentCount)
+		case forumpost.SortHot:
+			score = forumpost.HotScore(summary.Likes-summary.Dislikes, now.Sub(p.date))
+		}
+
+		if after != nil && (score > after.Score || (score == after.Score && p.id >= after.ID)) {
+			continue
+		}
+		listed = append(listed, scored{summary, score})
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		if listed[i].score != listed[j].score {
+			return listed[i].score > listed[j].score
+		}
+		return listed[i].post.ID > listed[j].post.ID
+	})
+	if len(listed) > opts.Limit+1 {
+		listed = listed[:opts.Limit+1]
+	}
+
+	posts := []model.HomePageData{}
+	var scores []float64
+	for _, entry := range listed {
+		posts = append(posts, entry.post)
+		scores = append(scores, entry.score)
+	}
+	return forumpost.Page(posts, scores, opts, now)
+}
+
+func (s *postStore) ListByAuthor(userID int) ([]model.PostData, error) {
+	return s.listPostData(func(p *post) bool {
+		return p.userID == userID
+	})
+}
+
+func (s *postStore) ListLikedBy(userID int) ([]model.PostData, error) {
+	return s.listPostData(func(p *post) bool {
+		return s.reactions[reactionKey{userID, p.id, false}] == "like"
+	})
+}
+
+func (s *postStore) ListByCategory(categoryID int) ([]model.PostData, error) {
+	return s.listPostData(func(p *post) bool {
+		return containsID(p.categoryIDs, categoryID)
+	})
+}
+
+// listPostData returns the live posts accepted by keep
+func (s *postStore) listPostData(keep func(p *post) bool) ([]model.PostData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var posts []model.PostData
+	for _, p := range s.posts {
+		if !p.deleted && keep(p) {
+			posts = append(posts, model.PostData{ID: p.id, UserID: p.userID, Title: p.title, Category: s.categoryNames(p)})
+		}
+	}
+	return posts, nil
+}
+
+// ownedPost returns a live post, checking that userID wrote it. Callers hold s.mu.
+func (s *postStore) ownedPost(postID, userID int) (*post, error) {
+	p := s.livePost(postID)
+	if p == nil {
+		return nil, store.ErrPostNotFound
+	}
+	if p.userID != userID {
+		return nil, store.ErrNotPostOwner
+	}
+	return p, nil
+}
+
+// matches applies the category, author and hidden author filters of a listing. Callers hold s.mu.
+func (s *postStore) matches(p *post, opts forumpost.ListOptions) bool {
+	if opts.CategoryID > 0 && !containsID(p.categoryIDs, opts.CategoryID) {
+		return false
+	}
+	if opts.Author != "" && !strings.EqualFold(s.username(p.userID), opts.Author) {
+		return false
+	}
+	return !containsID(opts.HiddenAuthors, p.userID)
+}
+
+// summary builds the listing entry of a post. Callers hold s.mu.
+func (s *postStore) summary(p *post) model.HomePageData {
+	likes, dislikes := s.reactionCounts(p.id, false)
+	comments := 0
+	for _, c := range s.comments {
+		if c.postID == p.id && !c.deleted {
+			comments++
+		}
+	}
+
+	return model.HomePageData{
+		ID:           p.id,
+		Title:        p.title,
+		Content:      p.content,
+		Username:     s.username(p.userID),
+		Category:     s.categoryNames(p),
+		Likes:        likes,
+		Dislikes:     dislikes,
+		CommentCount: comments,
+		Date:         p.date.Format(time.RFC3339),
+	}
+}
+
+// postCategories returns the categories of a post in display order. Callers hold s.mu.
+func (s *postStore) postCategories(p *post) []model.Category {
+	categories := []model.Category{}
+	for _, c := range s.sortedCategories() {
+		if containsID(p.categoryIDs, c.ID) {
+			categories = append(categories, *c)
+		}
+	}
+	return categories
+}
+
+// categoryNames joins the category names of a post in display order. Callers hold s.mu.
+func (s *postStore) categoryNames(p *post) string {
+	var names []string
+	for _, c := range s.postCategories(p) {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []int) []int {
+	var unique []int
+	for _, id := range ids {
+		if !containsID(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// containsID reports whether ids contains id
+func containsID(ids []int, id int) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"forum/internal/store"
+)
+
+type reactionStore struct {
+	*data
+}
+
+func (s *reactionStore) Counts(itemID int, isComment bool) (likes, dislikes int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	likes, dislikes = s.reactionCounts(itemID, isComment)
+	return likes, dislikes, nil
+}
+
+func (s *reactionStore) Toggle(userID, itemID int, isComment bool, reactionType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Deleted posts and comments can no longer be reacted to
+	if (isComment && s.liveComment(itemID) == nil) || (!isComment && s.livePost(itemID) == nil) {
+		return store.ErrItemNotFound
+	}
+
+	key := reactionKey{userID, itemID, isComment}
+	if s.reactions[key] == reactionType {
+		delete(s.reactions, key)
+	} else {
+		s.reactions[key] = reactionType
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"forum/internal/store"
+	"time"
+)
+
+type reportStore struct {
+	*data
+}
+
+func (s *reportStore) Create(report store.Report) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.reports {
+		if r.Open() && r.ReporterID == report.ReporterID && r.ItemType == report.ItemType && r.ItemID == report.ItemID {
+			return 0, store.ErrDuplicateReport
+		}
+	}
+
+	report.ID = len(s.reports) + 1
+	report.CreatedAt = time.Now()
+	s.reports = append(s.reports, &report)
+	return report.ID, nil
+}
+
+func (s *reportStore) Get(reportID int) (store.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reportID <= 0 || reportID > len(s.reports) {
+		return store.Report{}, store.ErrReportNotFound
+	}
+	return *s.reports[reportID-1], nil
+}
+
+func (s *reportStore) ListOpen(limit, offset int) ([]store.Report, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var open []store.Report
+	for _, r := range s.reports {
+		if r.Open() {
+			open = append(open, *r)
+		}
+	}
+
+	total := len(open)
+	if offset > total {
+		offset = total
+	}
+	open = open[offset:]
+	if len(open) > limit {
+		open = open[:limit]
+	}
+	return open, total, nil
+}
+
+func (s *reportStore) ListByReporter(userID int) ([]store.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reports []store.Report
+	for i := len(s.reports) - 1; i >= 0; i-- {
+		if s.reports[i].ReporterID == userID {
+			reports = append(reports, *s.reports[i])
+		}
+	}
+	return reports, nil
+}
+
+func (s *reportStore) Resolve(reportID, moderatorID int, action, note string) ([]store.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reportID <= 0 || reportID > len(s.reports) {
+		return nil, store.ErrReportNotFound
+	}
+	target := s.reports[reportID-1]
+	if !target.Open() {
+		return nil, store.ErrReportResolved
+	}
+
+	itemType, itemID := target.ItemType, target.ItemID
+	now := time.Now()
+	var resolved []store.Report
+	for _, r := range s.reports {
+		if r.Open() && r.ItemType == itemType && r.ItemID == itemID {
+			r.Action = action
+			r.Note = note
+			r.ResolvedBy = moderatorID
+			r.ResolvedAt = now
+			resolved = append(resolved, *r)
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+	"sort"
+	"strings"
+	"time"
+)
+
+type roomStore struct {
+	*data
+}
+
+func (s *roomStore) Create(userID int, name string, categoryID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &room{
+		id:         len(s.rooms) + 1,
+		name:       name,
+		categoryID: categoryID,
+		createdBy:  userID,
+		createdAt:  time.Now(),
+		members:    []roomMember{{userID: userID, role: "owner"}},
+	}
+	s.rooms = append(s.rooms, r)
+	return r.id, nil
+}
+
+func (s *roomStore) Get(roomID int) (model.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.room(roomID)
+	if r == nil {
+		return model.Room{}, store.ErrRoomNotFound
+	}
+	return s.roomModel(r), nil
+}
+
+func (s *roomStore) ListForUser(userID int) ([]model.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Latest message the user can see in each room
+	lastIDs := make(map[int]int)
+	for _, msg := range s.roomMessages {
+		if !msg.hidden || msg.SenderID == userID {
+			lastIDs[msg.RoomID] = msg.ID
+		}
+	}
+
+	var joined []*room
+	for _, r := range s.rooms {
+		if !r.deleted && r.member(userID) != nil {
+			joined = append(joined, r)
+		}
+	}
+	sort.SliceStable(joined, func(i, j int) bool {
+		if lastIDs[joined[i].id] != lastIDs[joined[j].id] {
+			return lastIDs[joined[i].id] > lastIDs[joined[j].id]
+		}
+		return joined[i].name < joined[j].name
+	})
+
+	rooms := []model.Room{}
+	for _, r := range joined {
+		rooms = append(rooms, s.roomModel(r))
+	}
+	return rooms, nil
+}
+
+func (s *roomStore) IsMember(roomID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.room(roomID)
+	return r != nil && r.member(userID) != nil, nil
+}
+
+func (s *roomStore) MemberIDs(roomID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var userIDs []int
+	if r := s.room(roomID); r != nil {
+		for _, m := range r.members {
+			userIDs = append(userIDs, m.userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (s *roomStore) Invite(roomID, inviterID, inviteeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.room(roomID)
+	if r == nil || r.member(inviterID) == nil {
+		return store.ErrNotRoomMember
+	}
+	if r.member(inviteeID) != nil {
+		return store.ErrAlreadyRoomMember
+	}
+	r.members = append(r.members, roomMember{userID: inviteeID, role: "member"})
+	return nil
+}
+
+func (s *roomStore) Leave(roomID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.room(roomID)
+	if r == nil || r.member(userID) == nil {
+		return store.ErrNotRoomMember
+	}
+
+	kept := r.members[:0]
+	hasOwner := false
+	for _, m := range r.members {
+		if m.userID != userID {
+			kept = append(kept, m)
+			hasOwner = hasOwner || m.role == "owner"
+		}
+	}
+	r.members = kept
+
+	switch {
+	case len(r.members) == 0:
+		r.deleted = true
+		messages := s.roomMessages[:0]
+		for _, msg := range s.roomMessages {
+			if msg.RoomID != roomID {
+				messages = append(messages, msg)
+			}
+		}
+		s.roomMessages = messages
+	case !hasOwner:
+		// Members are kept in joining order
+		r.members[0].role = "owner"
+	}
+	return nil
+}
+
+func (s *roomStore) Rename(roomID, userID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.room(roomID)
+	if r == nil || r.member(userID) == nil {
+		return store.ErrNotRoomMember
+	}
+	if r.member(userID).role != "owner" {
+		return store.ErrNotRoomOwner
+	}
+	r.name = name
+	return nil
+}
+
+func (s *roomStore) AddMessage(roomID, senderID int, content string, hidden bool) (model.RoomMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastRoomMessageID++
+	msg := &roomMessage{
+		RoomMessage: model.RoomMessage{
+			ID:        s.lastRoomMessageID,
+			RoomID:    roomID,
+			SenderID:  senderID,
+			Content:   content,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		hidden: hidden,
+	}
+	s.roomMessages = append(s.roomMessages, msg)
+	return msg.RoomMessage, nil
+}
+
+func (s *roomStore) History(roomID, viewerID, beforeID, limit int) ([]model.RoomMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Walk back from the newest message, keeping one extra to know whether
+	// another page exists
+	messages := []model.RoomMessage{}
+	for i := len(s.roomMessages) - 1; i >= 0 && len(messages) <= limit; i-- {
+		msg := s.roomMessages[i]
+		if msg.RoomID != roomID || (beforeID > 0 && msg.ID >= beforeID) {
+			continue
+		}
+		if !msg.hidden || msg.SenderID == viewerID {
+			m := msg.RoomMessage
+			m.Username = s.username(m.SenderID)
+			messages = append(messages, m)
+		}
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Reverse order to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, hasMore, nil
+}
+
+// room returns the room with the given ID unless it is missing or deleted.
+// Callers hold d.mu.
+func (d *data) room(roomID int) *room {
+	if roomID <= 0 || roomID > len(d.rooms) || d.rooms[roomID-1].deleted {
+		return nil
+	}
+	return d.rooms[roomID-1]
+}
+
+// roomModel converts a room with its members, owner first, then by name.
+// Callers hold d.mu.
+func (d *data) roomModel(r *room) model.Room {
+	result := model.Room{
+		ID:         r.id,
+		Name:       r.name,
+		CategoryID: r.categoryID,
+		CreatedBy:  r.createdBy,
+		CreatedAt:  r.createdAt.Format(time.RFC3339),
+		Members:    []model.RoomMember{},
+	}
+	if c := d.category(r.categoryID); c != nil {
+		result.Category = c.Name
+	}
+	for _, m := range r.members {
+		result.Members = append(result.Members, model.RoomMember{
+			UserID:   m.userID,
+			Username: d.username(m.userID),
+			Role:     m.role,
+		})
+	}
+	sort.SliceStable(result.Members, func(i, j int) bool {
+		a, b := result.Members[i], result.Members[j]
+		if (a.Role == "owner") != (b.Role == "owner") {
+			return a.Role == "owner"
+		}
+		return strings.ToLower(a.Username) < strings.ToLower(b.Username)
+	})
+	return result
+}
+
+// member returns the membership of a user, or nil
+func (r *room) member(userID int) *roomMember {
+	for i := range r.members {
+		if r.members[i].userID == userID {
+			return &r.members[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"forum/internal/store"
+	"time"
+)
+
+type sanctionStore struct {
+	*data
+}
+
+func (s *sanctionStore) Create(sanction store.Sanction) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sanction.ID = len(s.sanctions) + 1
+	s.sanctions = append(s.sanctions, sanction)
+	return sanction.ID, nil
+}
+
+func (s *sanctionStore) Get(sanctionID int) (store.Sanction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sanctionID <= 0 || sanctionID > len(s.sanctions) {
+		return store.Sanction{}, store.ErrSanctionNotFound
+	}
+	return s.sanctions[sanctionID-1], nil
+}
+
+func (s *sanctionStore) ListForUser(userID int) ([]store.Sanction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sanctions []store.Sanction
+	for i := len(s.sanctions) - 1; i >= 0; i-- {
+		if s.sanctions[i].UserID == userID {
+			sanctions = append(sanctions, s.sanctions[i])
+		}
+	}
+	return sanctions, nil
+}
+
+func (s *sanctionStore) Active(userID int, now time.Time) ([]store.Sanction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sanctions []store.Sanction
+	for i := len(s.sanctions) - 1; i >= 0; i-- {
+		if s.sanctions[i].UserID == userID && s.sanctions[i].ActiveAt(now) {
+			sanctions = append(sanctions, s.sanctions[i])
+		}
+	}
+	return sanctions, nil
+}
+
+func (s *sanctionStore) ActiveUserIDs(kind string, now time.Time) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[int]bool{}
+	var userIDs []int
+	for _, sanction := range s.sanctions {
+		if sanction.Kind == kind && sanction.ActiveAt(now) && !seen[sanction.UserID] {
+			seen[sanction.UserID] = true
+			userIDs = append(userIDs, sanction.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (s *sanctionStore) Lift(sanctionID, moderatorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if sanctionID <= 0 || sanctionID > len(s.sanctions) || !s.sanctions[sanctionID-1].ActiveAt(now) {
+		return store.ErrSanctionNotFound
+	}
+	s.sanctions[sanctionID-1].LiftedBy = moderatorID
+	s.sanctions[sanctionID-1].LiftedAt = now
+	return nil
+}
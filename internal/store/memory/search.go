@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/search"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type searchStore struct {
+	*data
+}
+
+func (s *searchStore) Available() bool {
+	return true
+}
+
+// Search scans every item, newest first. Ranks are all zero and snippets
+// are the whole matching text.
+func (s *searchStore) Search(userID int, input string, scopes []string, limit int, hiddenAuthors []int) (map[string][]model.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	terms := search.ParseTerms(input)
+	hidden := make(map[int]bool)
+	for _, id := range hiddenAuthors {
+		hidden[id] = true
+	}
+
+	results := make(map[string][]model.SearchResult)
+	for _, scope := range scopes {
+		found := []model.SearchResult{}
+
+		switch scope {
+		case search.ScopePosts:
+			for i := len(s.posts) - 1; i >= 0 && len(found) < limit; i-- {
+				p := s.posts[i]
+				if p.deleted || hidden[p.userID] || !matches(p.title+" "+p.content, terms) {
+					continue
+				}
+				found = append(found, model.SearchResult{
+					Type:     scope,
+					ID:       p.id,
+					PostID:   p.id,
+					Title:    p.title,
+					Snippet:  snippet(p.content, terms),
+					Username: s.username(p.userID),
+					Date:     p.date.Format(time.RFC3339),
+				})
+			}
+		case search.ScopeComments:
+			for i := len(s.comments) - 1; i >= 0 && len(found) < limit; i-- {
+				c := s.comments[i]
+				if c.deleted || hidden[c.userID] || !matches(c.content, terms) {
+					continue
+				}
+				result := model.SearchResult{
+					Type:     scope,
+					ID:       c.id,
+					PostID:   c.postID,
+					Snippet:  snippet(c.content, terms),
+					Username: s.username(c.userID),
+				}
+				if p := s.livePost(c.postID); p != nil {
+					result.Title = p.title
+					result.Date = p.date.Format(time.RFC3339)
+				}
+				found = append(found, result)
+			}
+		case search.ScopeMessages:
+			for i := len(s.messages) - 1; i >= 0 && len(found) < limit; i-- {
+				msg := s.messages[i]
+				partnerID := msg.SenderID
+				switch {
+				case msg.Deleted:
+					continue
+				case msg.SenderID == userID:
+					partnerID = msg.ReceiverID
+				case msg.ReceiverID != userID || msg.Hidden:
+					continue
+				}
+				if !matches(msg.Content, terms) {
+					continue
+				}
+				found = append(found, model.SearchResult{
+					Type:     scope,
+					ID:       msg.ID,
+					Title:    s.username(partnerID),
+					Snippet:  snippet(msg.Content, terms),
+					Username: s.username(msg.SenderID),
+					Date:     msg.Timestamp,
+				})
+			}
+		default:
+			continue
+		}
+		results[scope] = found
+	}
+
+	return results, nil
+}
+
+// matches reports whether text contains every term, ignoring case. Words
+// must match whole words of text unless they are prefixes.
+func matches(text string, terms []search.Term) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	lower := strings.ToLower(text)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, term := range terms {
+		if !termMatches(lower, words, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// termMatches reports whether one term is found in text, given its words
+func termMatches(text string, words []string, term search.Term) bool {
+	want := strings.ToLower(term.Text)
+	if strings.ContainsFunc(want, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		// A phrase of several words
+		return strings.Contains(text, want)
+	}
+	for _, word := range words {
+		if word == want || (term.Prefix && strings.HasPrefix(word, want)) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet highlights the words of text that match a term
+func snippet(text string, terms []search.Term) string {
+	var b strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := string(word)
+		word = word[:0]
+		for _, term := range terms {
+			if termMatches(strings.ToLower(w), []string{strings.ToLower(w)}, term) {
+				b.WriteString(search.MatchStart + w + search.MatchEnd)
+				return
+			}
+		}
+		b.WriteString(w)
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return search.Highlight(b.String())
+}
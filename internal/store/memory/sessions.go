@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"forum/internal/store"
+	"sort"
+	"time"
+)
+
+type sessionStore struct {
+	*data
+}
+
+func (s *sessionStore) Create(session store.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *sessionStore) Get(sessionID string) (store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return store.Session{}, store.ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *sessionStore) Touch(sessionID string, lastSeen, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[sessionID]; ok {
+		session.LastSeen = lastSeen
+		session.ExpiresAt = expiresAt
+		s.sessions[sessionID] = session
+	}
+	return nil
+}
+
+func (s *sessionStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *sessionStore) ListForUser(userID int) ([]store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []store.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+func (s *sessionStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for id, session := range s.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
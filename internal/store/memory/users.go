@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"forum/internal/model"
+	"forum/internal/store"
+)
+
+type userStore struct {
+	*data
+}
+
+func (s *userStore) Create(u model.User, passwordHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == u.Username || existing.Email == u.Email {
+			return 0, store.ErrDuplicateUser
+		}
+	}
+
+	u.ID = len(s.users) + 1
+	s.users = append(s.users, &user{User: u, passwordHash: passwordHash, role: "user"})
+	return u.ID, nil
+}
+
+func (s *userStore) EmailExists(email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *userStore) UsernameExists(username string) (bool, error) {
+	_, err := s.IDByUsername(username)
+	return err == nil, nil
+}
+
+func (s *userStore) Credentials(identifier string) (int, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Try the email first, then the username
+	for _, u := range s.users {
+		if u.Email == identifier {
+			return u.ID, u.passwordHash, nil
+		}
+	}
+	for _, u := range s.users {
+		if u.Username == identifier {
+			return u.ID, u.passwordHash, nil
+		}
+	}
+	return 0, "", store.ErrNotFound
+}
+
+func (s *userStore) Username(userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(userID)
+	if u == nil {
+		return "", store.ErrNotFound
+	}
+	return u.Username, nil
+}
+
+func (s *userStore) IDByUsername(username string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return u.ID, nil
+		}
+	}
+	return 0, store.ErrNotFound
+}
+
+func (s *userStore) Role(userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u := s.user(userID); u != nil {
+		return u.role, nil
+	}
+	return "", nil
+}
+
+func (s *userStore) SetRole(userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(userID)
+	if u == nil {
+		return store.ErrNotFound
+	}
+	u.role = role
+	return nil
+}
+
+func (s *userStore) List() ([]model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, model.User{ID: u.ID, Username: u.Username, Role: u.role})
+	}
+	return users, nil
+}
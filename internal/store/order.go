@@ -0,0 +1,29 @@
+package store
+
+// ReorderIDs moves the requested IDs to the front of current in the given
+// order, keeping the relative order of the rest. It returns
+// ErrCategoryNotFound when a requested ID is not in current.
+func ReorderIDs(current, requested []int) ([]int, error) {
+	known := make(map[int]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+
+	order := make([]int, 0, len(current))
+	placed := make(map[int]bool, len(current))
+	for _, id := range requested {
+		if !known[id] {
+			return nil, ErrCategoryNotFound
+		}
+		if !placed[id] {
+			order = append(order, id)
+			placed[id] = true
+		}
+	}
+	for _, id := range current {
+		if !placed[id] {
+			order = append(order, id)
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"strings"
+	"time"
+)
+
+type auditStore struct {
+	db *sql.DB
+}
+
+func (s *auditStore) Append(entry store.AuditEntry) (int, error) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	before := sql.NullString{String: entry.Before, Valid: entry.Before != ""}
+	after := sql.NullString{String: entry.After, Valid: entry.After != ""}
+
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, before_state, after_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, before, after, entry.CreatedAt).Scan(&id)
+	return id, err
+}
+
+func (s *auditStore) List(filter store.AuditFilter, limit, offset int) ([]store.AuditEntry, int, error) {
+	var args queryArgs
+	where := auditConditions(filter, &args)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := auditColumns + where + " ORDER BY id DESC LIMIT " + args.add(limit) + " OFFSET " + args.add(offset)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []store.AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+func (s *auditStore) Each(filter store.AuditFilter, fn func(store.AuditEntry) error) error {
+	var args queryArgs
+	where := auditConditions(filter, &args)
+
+	rows, err := s.db.Query(auditColumns+where+" ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// auditColumns selects the columns read by scanAuditEntry
+const auditColumns = `SELECT id, actor_id, action, target_type, target_id,
+		COALESCE(before_state, ''), COALESCE(after_state, ''), created_at
+	FROM audit_log`
+
+// auditConditions turns a filter into a WHERE clause, adding its arguments
+func auditConditions(filter store.AuditFilter, args *queryArgs) string {
+	var conditions []string
+	if filter.ActorID > 0 {
+		conditions = append(conditions, "actor_id = "+args.add(filter.ActorID))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+args.add(filter.Action))
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = "+args.add(filter.TargetType))
+	}
+	if filter.TargetID > 0 {
+		conditions = append(conditions, "target_id = "+args.add(filter.TargetID))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= "+args.add(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < "+args.add(filter.Until))
+	}
+
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// scanAuditEntry reads a row selected with auditColumns
+func scanAuditEntry(rows *sql.Rows) (store.AuditEntry, error) {
+	var entry store.AuditEntry
+	err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID,
+		&entry.Before, &entry.After, &entry.CreatedAt)
+	return entry, err
+}
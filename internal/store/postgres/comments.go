@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"time"
+)
+
+type commentStore struct {
+	db *sql.DB
+}
+
+func (s *commentStore) ListForPost(postID int) ([]model.Comment, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, COALESCE(c.parent_comment_id, 0), c.user_id, c.content, u.username, c.edited_at, c.deleted_at IS NOT NULL,
+			COUNT(CASE WHEN r.type = 'like' THEN 1 END),
+			COUNT(CASE WHEN r.type = 'dislike' THEN 1 END)
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN reactions r ON r.comment_id = c.id
+		WHERE c.post_id = $1
+		GROUP BY c.id, u.username
+		ORDER BY c.id`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []model.Comment
+	for rows.Next() {
+		comment := model.Comment{PostID: postID}
+		var editedAt sql.NullTime
+		err := rows.Scan(&comment.ID, &comment.ParentID, &comment.UserID, &comment.Content, &comment.Username,
+			&editedAt, &comment.Deleted, &comment.Likes, &comment.Dislikes)
+		if err != nil {
+			return nil, err
+		}
+		if editedAt.Valid {
+			comment.EditedAt = formatTime(editedAt.Time)
+		}
+
+		if comment.Deleted {
+			comment.Content = ""
+			comment.Username = ""
+			comment.UserID = 0
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (s *commentStore) Create(userID, postID, parentID int, content string) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = $1 AND deleted_at IS NULL", postID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, store.ErrPostNotFound
+	}
+
+	// Replies must target a live comment of the same post
+	var parent sql.NullInt64
+	if parentID > 0 {
+		err := s.db.QueryRow(
+			"SELECT COUNT(*) FROM comments WHERE id = $1 AND post_id = $2 AND deleted_at IS NULL", parentID, postID,
+		).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, store.ErrCommentNotFound
+		}
+		parent = sql.NullInt64{Int64: int64(parentID), Valid: true}
+	}
+
+	var id int
+	err = s.db.QueryRow(
+		"INSERT INTO comments (user_id, post_id, parent_comment_id, content) VALUES ($1, $2, $3, $4) RETURNING id",
+		userID, postID, parent, content,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *commentStore) Get(commentID int) (model.Comment, error) {
+	var comment model.Comment
+	var editedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT c.id, c.post_id, COALESCE(c.parent_comment_id, 0), c.user_id, c.content, u.username,
+			c.edited_at, c.deleted_at IS NOT NULL
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1`, commentID,
+	).Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.UserID, &comment.Content, &comment.Username,
+		&editedAt, &comment.Deleted)
+	if err == sql.ErrNoRows {
+		return model.Comment{}, store.ErrCommentNotFound
+	} else if err != nil {
+		return model.Comment{}, err
+	}
+	if editedAt.Valid {
+		comment.EditedAt = formatTime(editedAt.Time)
+	}
+	return comment, nil
+}
+
+func (s *commentStore) AuthorID(commentID int) (int, error) {
+	var userID int
+	err := s.db.QueryRow("SELECT user_id FROM comments WHERE id = $1", commentID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrCommentNotFound
+	}
+	return userID, err
+}
+
+func (s *commentStore) Update(commentID, userID int, content string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	oldContent, err := ownedComment(tx, commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"INSERT INTO comment_revisions (comment_id, content, edited_by, edited_at) VALUES ($1, $2, $3, $4)",
+		commentID, oldContent, userID, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE comments SET content = $1, edited_at = $2 WHERE id = $3", content, now, commentID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *commentStore) Delete(commentID, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedComment(tx, commentID, userID); err != nil {
+		return err
+	}
+
+	if err := removeComment(tx, commentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *commentStore) Remove(commentID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", commentID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return store.ErrCommentNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := removeComment(tx, commentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removeComment soft-deletes a comment and removes its reactions
+func removeComment(tx *sql.Tx, commentID int) error {
+	if _, err := tx.Exec("DELETE FROM reactions WHERE comment_id = $1", commentID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE comments SET deleted_at = $1 WHERE id = $2", time.Now(), commentID)
+	return err
+}
+
+// ownedComment locks a comment that is not deleted and returns its content,
+// checking that userID wrote it
+func ownedComment(tx *sql.Tx, commentID, userID int) (string, error) {
+	var ownerID int
+	var content string
+	err := tx.QueryRow(
+		"SELECT user_id, content FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", commentID,
+	).Scan(&ownerID, &content)
+	if err == sql.ErrNoRows {
+		return "", store.ErrCommentNotFound
+	} else if err != nil {
+		return "", err
+	}
+	if ownerID != userID {
+		return "", store.ErrNotCommentOwner
+	}
+	return content, nil
+}
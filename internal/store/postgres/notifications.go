@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"database/sql"
+)
+
+type notificationStore struct {
+	db *sql.DB
+}
+
+func (s *notificationStore) Add(userID int, frame []byte) error {
+	_, err := s.db.Exec("INSERT INTO notifications (user_id, frame) VALUES ($1, $2)", userID, string(frame))
+	return err
+}
+
+func (s *notificationStore) Take(userID int) ([][]byte, error) {
+	rows, err := s.db.Query(`
+		WITH taken AS (DELETE FROM notifications WHERE user_id = $1 RETURNING id, frame)
+		SELECT frame FROM taken ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	frames := [][]byte{}
+	for rows.Next() {
+		var frame string
+		if err := rows.Scan(&frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, []byte(frame))
+	}
+	return frames, rows.Err()
+}
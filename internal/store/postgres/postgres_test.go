@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/model"
+	"forum/internal/search"
+	"forum/internal/store"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// openTestDB connects to the database given by FORUM_TEST_POSTGRES_DSN and
+// returns the stores of a freshly migrated schema of their own, dropped when
+// the test ends. Tests are skipped when the variable is not set.
+func openTestDB(t *testing.T) store.Stores {
+	t.Helper()
+	dsn := os.Getenv("FORUM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("set FORUM_TEST_POSTGRES_DSN to run the PostgreSQL tests")
+	}
+
+	admin, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	schema := fmt.Sprintf("forum_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("creating schema %s: %v", schema, err)
+	}
+
+	cfg, err := pq.NewConfig(dsn)
+	if err != nil {
+		t.Fatalf("parsing the DSN: %v", err)
+	}
+	if cfg.Runtime == nil {
+		cfg.Runtime = make(map[string]string)
+	}
+	cfg.Runtime["search_path"] = schema
+	connector, err := pq.NewConnectorConfig(cfg)
+	if err != nil {
+		t.Fatalf("configuring the connection: %v", err)
+	}
+	db := sql.OpenDB(connector)
+
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return NewStores(db)
+}
+
+// newUser registers a user and returns their ID
+func newUser(t *testing.T, stores store.Stores, username string) int {
+	t.Helper()
+	userID, err := stores.Users.Create(model.User{Username: username, Email: username + "@example.com"}, "hash")
+	if err != nil {
+		t.Fatalf("creating %s: %v", username, err)
+	}
+	return userID
+}
+
+func TestMigrateTwice(t *testing.T) {
+	stores := openTestDB(t)
+	db := stores.Users.(*userStore).db
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrating an up to date database: %v", err)
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		t.Fatalf("reading the schema version: %v", err)
+	}
+	if want := migrations[len(migrations)-1].version; version != want {
+		t.Errorf("schema version is %d, want %d", version, want)
+	}
+}
+
+func TestRooms(t *testing.T) {
+	stores := openTestDB(t)
+	rooms := stores.Rooms
+	alice := newUser(t, stores, "alice")
+	bob := newUser(t, stores, "bob")
+	carol := newUser(t, stores, "carol")
+
+	categoryID, err := stores.Categories.DefaultID()
+	if err != nil {
+		t.Fatalf("loading the default category: %v", err)
+	}
+	roomID, err := rooms.Create(alice, "Lobby", categoryID)
+	if err != nil {
+		t.Fatalf("creating a room: %v", err)
+	}
+	if room, err := rooms.Get(roomID); err != nil || room.CategoryID != categoryID || room.Category == "" {
+		t.Errorf("room is %+v (%v), want it in category %d", room, err, categoryID)
+	}
+
+	if err := rooms.Invite(roomID, bob, carol); !errors.Is(err, store.ErrNotRoomMember) {
+		t.Errorf("inviting as an outsider: got %v, want %v", err, store.ErrNotRoomMember)
+	}
+	if err := rooms.Invite(roomID, alice, bob); err != nil {
+		t.Fatalf("inviting bob: %v", err)
+	}
+	if err := rooms.Invite(roomID, alice, bob); !errors.Is(err, store.ErrAlreadyRoomMember) {
+		t.Errorf("inviting bob twice: got %v, want %v", err, store.ErrAlreadyRoomMember)
+	}
+	if err := rooms.Rename(roomID, bob, "Mine"); !errors.Is(err, store.ErrNotRoomOwner) {
+		t.Errorf("renaming as a member: got %v, want %v", err, store.ErrNotRoomOwner)
+	}
+	if err := rooms.Rename(roomID, alice, "Hall"); err != nil {
+		t.Fatalf("renaming: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if _, err := rooms.AddMessage(roomID, bob, fmt.Sprintf("message %d", i), false); err != nil {
+			t.Fatalf("adding a message: %v", err)
+		}
+	}
+	if _, err := rooms.AddMessage(roomID, bob, "only bob sees this", true); err != nil {
+		t.Fatalf("adding a hidden message: %v", err)
+	}
+
+	page, hasMore, err := rooms.History(roomID, alice, 0, 2)
+	if err != nil {
+		t.Fatalf("loading the history: %v", err)
+	}
+	if len(page) != 2 || !hasMore || page[0].Content != "message 2" || page[1].Username != "bob" {
+		t.Errorf("latest page is %+v (more: %v), want messages 2 and 3", page, hasMore)
+	}
+	older, hasMore, err := rooms.History(roomID, alice, page[0].ID, 2)
+	if err != nil || len(older) != 1 || hasMore || older[0].Content != "message 1" {
+		t.Errorf("older page is %+v (more: %v, %v), want message 1 alone", older, hasMore, err)
+	}
+	page, _, _ = rooms.History(roomID, bob, 0, 10)
+	if len(page) != 4 {
+		t.Errorf("bob sees %d messages, want 4 including his hidden one", len(page))
+	}
+
+	// The owner leaving hands the room to the remaining member
+	if err := rooms.Leave(roomID, alice); err != nil {
+		t.Fatalf("leaving: %v", err)
+	}
+	room, err := rooms.Get(roomID)
+	if err != nil {
+		t.Fatalf("loading the room: %v", err)
+	}
+	if room.Name != "Hall" || len(room.Members) != 1 || room.Members[0].UserID != bob || room.Members[0].Role != "owner" {
+		t.Errorf("room after alice left is %+v", room)
+	}
+	if listed, err := rooms.ListForUser(alice); err != nil || len(listed) != 0 {
+		t.Errorf("rooms of alice are %+v (%v), want none", listed, err)
+	}
+
+	// The last member leaving deletes the room and its messages
+	if err := rooms.Leave(roomID, bob); err != nil {
+		t.Fatalf("leaving as the last member: %v", err)
+	}
+	if _, err := rooms.Get(roomID); !errors.Is(err, store.ErrRoomNotFound) {
+		t.Errorf("loading the emptied room: got %v, want %v", err, store.ErrRoomNotFound)
+	}
+	if err := rooms.Leave(roomID, bob); !errors.Is(err, store.ErrNotRoomMember) {
+		t.Errorf("leaving twice: got %v, want %v", err, store.ErrNotRoomMember)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	stores := openTestDB(t)
+	alice := newUser(t, stores, "alice")
+	bob := newUser(t, stores, "bob")
+	carol := newUser(t, stores, "carol")
+
+	categoryID, err := stores.Categories.DefaultID()
+	if err != nil {
+		t.Fatalf("finding a category: %v", err)
+	}
+	postID, err := stores.Posts.Create(alice, "Gardening tips", "Water the tomatoes every morning", []int{categoryID})
+	if err != nil {
+		t.Fatalf("creating a post: %v", err)
+	}
+	if _, err := stores.Posts.Create(bob, "Tomatoes", "Mine never ripen", []int{categoryID}); err != nil {
+		t.Fatalf("creating a post: %v", err)
+	}
+	if _, err := stores.Comments.Create(bob, postID, 0, "Morning watering works for tomatoes"); err != nil {
+		t.Fatalf("creating a comment: %v", err)
+	}
+	if _, err := stores.Messages.Create(alice, bob, "Want some tomato seeds?", false); err != nil {
+		t.Fatalf("creating a message: %v", err)
+	}
+
+	scopes := []string{search.ScopePosts, search.ScopeComments, search.ScopeMessages}
+	results, err := stores.Search.Search(carol, "tomatoes", scopes, 10, nil)
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	posts := results[search.ScopePosts]
+	if len(posts) != 2 || posts[0].Title != "Tomatoes" {
+		t.Errorf("posts found are %+v, want both with the title match first", posts)
+	} else if snippet := posts[1].Snippet; !strings.Contains(snippet, "<mark>tomatoes</mark>") {
+		t.Errorf("snippet %q does not mark the match", snippet)
+	}
+	if len(results[search.ScopeComments]) != 1 {
+		t.Errorf("comments found are %+v, want one", results[search.ScopeComments])
+	}
+	if len(results[search.ScopeMessages]) != 0 {
+		t.Errorf("carol found messages %+v of others", results[search.ScopeMessages])
+	}
+
+	// Hidden authors, phrases and prefixes
+	results, err = stores.Search.Search(carol, "tomatoes", scopes, 10, []int{bob})
+	if err != nil || len(results[search.ScopePosts]) != 1 || len(results[search.ScopeComments]) != 0 {
+		t.Errorf("search hiding bob found %+v (%v)", results, err)
+	}
+	results, err = stores.Search.Search(carol, `"the tomatoes"`, scopes, 10, nil)
+	if err != nil || len(results[search.ScopePosts]) != 1 {
+		t.Errorf("phrase search found %+v (%v)", results, err)
+	}
+	results, err = stores.Search.Search(bob, "tom* seed*", scopes, 10, nil)
+	if err != nil || len(results[search.ScopeMessages]) != 1 || results[search.ScopeMessages][0].Title != "alice" {
+		t.Errorf("prefix search of bob's messages found %+v (%v)", results, err)
+	}
+
+	// Operators in the input are searched as text
+	if _, err := stores.Search.Search(carol, `tomatoes & !( :* <->`, scopes, 10, nil); err != nil {
+		t.Errorf("searching for operators: %v", err)
+	}
+}
+
+func TestTSQuery(t *testing.T) {
+	for input, want := range map[string]string{
+		"tomato":               "tomato",
+		"Tomato Seeds":         "tomato & seeds",
+		`"ripe tomato" seed*`:  "(ripe <-> tomato) & seed:*",
+		"it's":                 "it & s",
+		`a & !b | c:* <-> (d)`: "a & b & c & d",
+		"naïve café":           "naïve & café",
+		`"!!" ++`:              "",
+	} {
+		if got := tsQuery(input); got != want {
+			t.Errorf("tsQuery(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
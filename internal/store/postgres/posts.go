@@ -0,0 +1,398 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/post"
+	"forum/internal/store"
+	"strings"
+	"time"
+)
+
+// postCategoryNames is an SQL expression selecting the comma-joined category
+// names of the post aliased p, in display order
+const postCategoryNames = `(
+	SELECT COALESCE(string_agg(c.name, ', ' ORDER BY c.position, c.id), '')
+	FROM post_categories pc
+	JOIN categories c ON c.id = pc.category_id
+	WHERE pc.post_id = p.id
+)`
+
+// scores holds the SQL expression each sort mode orders by; see
+// post.HotScore. List binds the listing time in place of :now.
+var scores = map[string]string{
+	post.SortNewest:   "p.id",
+	post.SortTop:      "COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0)",
+	post.SortComments: "COALESCE(c.comment_count, 0)",
+	post.SortHot: `(COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0) + 1.0) /
+		POWER(EXTRACT(EPOCH FROM (:now - p.date)) / 3600 + 2, 2)`,
+}
+
+type postStore struct {
+	db *sql.DB
+}
+
+func (s *postStore) Create(userID int, title, content string, categoryIDs []int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var postID int
+	err = tx.QueryRow(
+		"INSERT INTO posts (user_id, title, content, date) VALUES ($1, $2, $3, NOW()) RETURNING id",
+		userID, title, content,
+	).Scan(&postID)
+	if err != nil {
+		return 0, err
+	}
+	if err := setPostCategories(tx, postID, categoryIDs); err != nil {
+		return 0, err
+	}
+
+	return postID, tx.Commit()
+}
+
+func (s *postStore) Get(postID int) (model.Post, error) {
+	var p model.Post
+	var date time.Time
+	var editedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT p.id, p.user_id, COALESCE(u.username, 'Unknown'), p.title, p.content, `+postCategoryNames+`, p.date, p.edited_at
+		FROM posts p
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE p.id = $1 AND p.deleted_at IS NULL
+	`, postID).Scan(&p.ID, &p.UserID, &p.Username, &p.Title, &p.Content, &p.Category, &date, &editedAt)
+	if err == sql.ErrNoRows {
+		return model.Post{}, store.ErrPostNotFound
+	} else if err != nil {
+		return model.Post{}, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT c.id, c.name, c.description, c.position, c.archived_at IS NOT NULL
+		FROM categories c
+		JOIN post_categories pc ON pc.category_id = c.id
+		WHERE pc.post_id = $1
+		ORDER BY c.position, c.id
+	`, postID)
+	if err != nil {
+		return model.Post{}, err
+	}
+	p.Categories, err = scanCategories(rows)
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	p.Date = formatTime(date)
+	if editedAt.Valid {
+		p.EditedAt = formatTime(editedAt.Time)
+	}
+	return p, nil
+}
+
+func (s *postStore) Update(postID, userID int, title, content string, categoryIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	var oldTitle, oldContent, oldCategory string
+	err = tx.QueryRow(
+		"SELECT p.user_id, p.title, p.content, "+postCategoryNames+" FROM posts p WHERE p.id = $1 AND p.deleted_at IS NULL FOR UPDATE",
+		postID,
+	).Scan(&ownerID, &oldTitle, &oldContent, &oldCategory)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return store.ErrNotPostOwner
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"INSERT INTO post_revisions (post_id, title, content, category, edited_by, edited_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		postID, oldTitle, oldContent, oldCategory, userID, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE posts SET title = $1, content = $2, edited_at = $3 WHERE id = $4",
+		title, content, now, postID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setPostCategories(tx, postID, categoryIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *postStore) Delete(postID, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRow("SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", postID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return store.ErrNotPostOwner
+	}
+
+	if err := removePost(tx, postID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *postStore) Remove(postID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", postID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := removePost(tx, postID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removePost soft-deletes a post with its comments and removes their reactions
+func removePost(tx *sql.Tx, postID int) error {
+	now := time.Now()
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM reactions WHERE comment_id IN (SELECT id FROM comments WHERE post_id = $1)", []interface{}{postID}},
+		{"DELETE FROM reactions WHERE post_id = $1", []interface{}{postID}},
+		{"UPDATE comments SET deleted_at = $1 WHERE post_id = $2 AND deleted_at IS NULL", []interface{}{now, postID}},
+		{"UPDATE posts SET deleted_at = $1 WHERE id = $2", []interface{}{now, postID}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *postStore) Revisions(postID int) ([]model.Revision, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = $1 AND deleted_at IS NULL", postID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, store.ErrPostNotFound
+	}
+
+	rows, err := s.db.Query(`
+		SELECT r.id, r.title, r.content, r.category, COALESCE(r.edited_by, 0), COALESCE(u.username, 'Unknown'), r.edited_at
+		FROM post_revisions r
+		LEFT JOIN users u ON u.id = r.edited_by
+		WHERE r.post_id = $1
+		ORDER BY r.id DESC
+	`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		var revision model.Revision
+		var editedAt time.Time
+		err := rows.Scan(&revision.ID, &revision.Title, &revision.Content, &revision.Category, &revision.EditorID, &revision.Editor, &editedAt)
+		if err != nil {
+			return nil, err
+		}
+		revision.EditedAt = formatTime(editedAt)
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *postStore) List(opts post.ListOptions) ([]model.HomePageData, string, error) {
+	after, now, err := post.Prepare(&opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Postgres rejects unused parameters, so the listing time is only bound
+	// where the query needs it
+	var args queryArgs
+	score := scores[opts.Sort]
+	if strings.Contains(score, ":now") {
+		score = strings.ReplaceAll(score, ":now", args.add(now)+"::timestamptz")
+	}
+
+	var where []string
+	if days := post.Windows[opts.Window]; opts.Sort == post.SortTop && days > 0 {
+		where = append(where, "p.date >= "+args.add(now)+"::timestamptz - make_interval(days => "+args.add(days)+"::integer)")
+	}
+	if opts.CategoryID > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM post_categories WHERE post_id = p.id AND category_id = "+args.add(opts.CategoryID)+")")
+	}
+	if opts.Author != "" {
+		where = append(where, "LOWER(u.username) = LOWER("+args.add(opts.Author)+")")
+	}
+	if len(opts.HiddenAuthors) > 0 {
+		placeholders := make([]string, len(opts.HiddenAuthors))
+		for i, userID := range opts.HiddenAuthors {
+			placeholders[i] = args.add(userID)
+		}
+		where = append(where, "p.user_id NOT IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	query := `
+		WITH listed AS (
+			SELECT
+				p.id,
+				p.title,
+				p.content,
+				COALESCE(u.username, 'Unknown') AS username,
+				` + postCategoryNames + ` AS category,
+				COALESCE(r.likes, 0) AS likes,
+				COALESCE(r.dislikes, 0) AS dislikes,
+				COALESCE(c.comment_count, 0) AS comment_count,
+				p.date,
+				(` + score + `)::float8 AS score
+			FROM posts p
+			LEFT JOIN users u ON p.user_id = u.id
+			LEFT JOIN (
+				SELECT post_id,
+					SUM(CASE WHEN type = 'like' THEN 1 ELSE 0 END) AS likes,
+					SUM(CASE WHEN type = 'dislike' THEN 1 ELSE 0 END) AS dislikes
+				FROM reactions
+				WHERE comment_id IS NULL
+				GROUP BY post_id
+			) r ON r.post_id = p.id
+			LEFT JOIN (
+				SELECT post_id, COUNT(*) AS comment_count
+				FROM comments
+				WHERE deleted_at IS NULL
+				GROUP BY post_id
+			) c ON c.post_id = p.id
+			WHERE p.deleted_at IS NULL` + andAll(where) + `
+		)
+		SELECT id, title, content, username, category, likes, dislikes, comment_count, date, score
+		FROM listed`
+	if after != nil {
+		lastScore, lastID := args.add(after.Score), args.add(after.ID)
+		query += " WHERE score < " + lastScore + "::float8 OR (score = " + lastScore + "::float8 AND id < " + lastID + "::integer)"
+	}
+	query += " ORDER BY score DESC, id DESC LIMIT " + args.add(opts.Limit+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	posts := []model.HomePageData{}
+	var postScores []float64
+	for rows.Next() {
+		var p model.HomePageData
+		var date time.Time
+		var score float64
+		err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Username, &p.Category,
+			&p.Likes, &p.Dislikes, &p.CommentCount, &date, &score)
+		if err != nil {
+			return nil, "", err
+		}
+		p.Date = formatTime(date)
+		posts = append(posts, p)
+		postScores = append(postScores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return post.Page(posts, postScores, opts, now)
+}
+
+func (s *postStore) ListByAuthor(userID int) ([]model.PostData, error) {
+	return s.listPostData("SELECT p.id, p.user_id, p.title, "+postCategoryNames+" FROM posts p WHERE p.user_id = $1 AND p.deleted_at IS NULL ORDER BY p.id", userID)
+}
+
+func (s *postStore) ListLikedBy(userID int) ([]model.PostData, error) {
+	return s.listPostData(`
+		SELECT p.id, p.user_id, p.title, `+postCategoryNames+`
+		FROM posts p
+		JOIN reactions r ON p.id = r.post_id
+		WHERE r.user_id = $1 AND r.type = 'like' AND p.deleted_at IS NULL
+		ORDER BY p.id
+	`, userID)
+}
+
+func (s *postStore) ListByCategory(categoryID int) ([]model.PostData, error) {
+	return s.listPostData(`
+		SELECT p.id, p.user_id, p.title, `+postCategoryNames+`
+		FROM posts p
+		JOIN post_categories pc ON pc.post_id = p.id
+		WHERE pc.category_id = $1 AND p.deleted_at IS NULL
+		ORDER BY p.id
+	`, categoryID)
+}
+
+// listPostData runs a query selecting (id, user_id, title, category names)
+func (s *postStore) listPostData(query string, args ...interface{}) ([]model.PostData, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []model.PostData
+	for rows.Next() {
+		var p model.PostData
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// setPostCategories replaces the categories of a post inside tx
+func setPostCategories(tx *sql.Tx, postID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM post_categories WHERE post_id = $1", postID); err != nil {
+		return err
+	}
+	for _, id := range categoryIDs {
+		_, err := tx.Exec(
+			"INSERT INTO post_categories (post_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			postID, id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/store"
+)
+
+type reactionStore struct {
+	db *sql.DB
+}
+
+// reactionTarget returns the reactions column and the table of the item
+func reactionTarget(isComment bool) (string, string) {
+	if isComment {
+		return "comment_id", "comments"
+	}
+	return "post_id", "posts"
+}
+
+func (s *reactionStore) Counts(itemID int, isComment bool) (likes, dislikes int, err error) {
+	idColumn, _ := reactionTarget(isComment)
+	err = s.db.QueryRow(`
+		SELECT
+			COUNT(CASE WHEN type = 'like' THEN 1 END) AS likes,
+			COUNT(CASE WHEN type = 'dislike' THEN 1 END) AS dislikes
+		FROM reactions
+		WHERE `+idColumn+` = $1`, itemID).Scan(&likes, &dislikes)
+	return
+}
+
+func (s *reactionStore) Toggle(userID, itemID int, isComment bool, reactionType string) error {
+	idColumn, itemTable := reactionTarget(isComment)
+
+	// Deleted posts and comments can no longer be reacted to
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM "+itemTable+" WHERE id = $1 AND deleted_at IS NULL", itemID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return store.ErrItemNotFound
+	}
+
+	var existing string
+	err = s.db.QueryRow("SELECT type FROM reactions WHERE user_id = $1 AND "+idColumn+" = $2", userID, itemID).Scan(&existing)
+	if err == sql.ErrNoRows {
+		_, err := s.db.Exec("INSERT INTO reactions ("+idColumn+", user_id, type) VALUES ($1, $2, $3)", itemID, userID, reactionType)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if existing != reactionType {
+		_, err := s.db.Exec("UPDATE reactions SET type = $1 WHERE user_id = $2 AND "+idColumn+" = $3", reactionType, userID, itemID)
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM reactions WHERE user_id = $1 AND "+idColumn+" = $2", userID, itemID)
+	return err
+}
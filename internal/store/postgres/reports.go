@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"time"
+)
+
+type reportStore struct {
+	db *sql.DB
+}
+
+func (s *reportStore) Create(report store.Report) (int, error) {
+	// The partial unique index on open reports rejects a second one
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO reports (reporter_id, item_type, item_id, author_id, content, reason, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+		RETURNING id`,
+		report.ReporterID, report.ItemType, report.ItemID, report.AuthorID, report.Content, report.Reason,
+		report.Details, time.Now(),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrDuplicateReport
+	}
+	return id, err
+}
+
+func (s *reportStore) Get(reportID int) (store.Report, error) {
+	rows, err := s.db.Query(reportColumns+" WHERE id = $1", reportID)
+	if err != nil {
+		return store.Report{}, err
+	}
+	reports, err := scanReports(rows)
+	if err != nil {
+		return store.Report{}, err
+	}
+	if len(reports) == 0 {
+		return store.Report{}, store.ErrReportNotFound
+	}
+	return reports[0], nil
+}
+
+func (s *reportStore) ListOpen(limit, offset int) ([]store.Report, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM reports WHERE resolved_at IS NULL").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(reportColumns+" WHERE resolved_at IS NULL ORDER BY id LIMIT $1 OFFSET $2", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	reports, err := scanReports(rows)
+	return reports, total, err
+}
+
+func (s *reportStore) ListByReporter(userID int) ([]store.Report, error) {
+	rows, err := s.db.Query(reportColumns+" WHERE reporter_id = $1 ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReports(rows)
+}
+
+func (s *reportStore) Resolve(reportID, moderatorID int, action, note string) ([]store.Report, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var itemType string
+	var itemID int
+	var resolvedAt sql.NullTime
+	err = tx.QueryRow("SELECT item_type, item_id, resolved_at FROM reports WHERE id = $1 FOR UPDATE", reportID).
+		Scan(&itemType, &itemID, &resolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrReportNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		return nil, store.ErrReportResolved
+	}
+
+	rows, err := tx.Query(`
+		UPDATE reports SET action = $1, note = $2, resolved_by = $3, resolved_at = $4
+		WHERE item_type = $5 AND item_id = $6 AND resolved_at IS NULL
+		RETURNING `+reportFields,
+		action, note, moderatorID, time.Now(), itemType, itemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := scanReports(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, tx.Commit()
+}
+
+// reportFields are the columns read by scanReports
+const reportFields = `id, reporter_id, item_type, item_id, author_id, content, reason, details, created_at,
+		COALESCE(action, ''), COALESCE(note, ''), COALESCE(resolved_by, 0), resolved_at`
+
+// reportColumns selects the columns read by scanReports
+const reportColumns = "SELECT " + reportFields + " FROM reports"
+
+// scanReports reads rows selected with reportColumns
+func scanReports(rows *sql.Rows) ([]store.Report, error) {
+	defer rows.Close()
+
+	var reports []store.Report
+	for rows.Next() {
+		var report store.Report
+		var resolvedAt sql.NullTime
+		err := rows.Scan(&report.ID, &report.ReporterID, &report.ItemType, &report.ItemID, &report.AuthorID,
+			&report.Content, &report.Reason, &report.Details, &report.CreatedAt,
+			&report.Action, &report.Note, &report.ResolvedBy, &resolvedAt)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedAt.Valid {
+			report.ResolvedAt = resolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
@@ -0,0 +1,312 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"math"
+	"time"
+)
+
+type roomStore struct {
+	db *sql.DB
+}
+
+func (s *roomStore) Create(userID int, name string, categoryID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var roomID int
+	err = tx.QueryRow(
+		"INSERT INTO rooms (name, category_id, created_by) VALUES ($1, $2, $3) RETURNING id",
+		name, sql.NullInt64{Int64: int64(categoryID), Valid: categoryID > 0}, userID,
+	).Scan(&roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO room_members (room_id, user_id, role) VALUES ($1, $2, 'owner')",
+		roomID, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return roomID, tx.Commit()
+}
+
+func (s *roomStore) Get(roomID int) (model.Room, error) {
+	var room model.Room
+	var createdAt time.Time
+	err := s.db.QueryRow(
+		`SELECT r.id, r.name, COALESCE(r.category_id, 0), COALESCE(c.name, ''), COALESCE(r.created_by, 0), r.created_at
+		FROM rooms r
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.id = $1`,
+		roomID,
+	).Scan(&room.ID, &room.Name, &room.CategoryID, &room.Category, &room.CreatedBy, &createdAt)
+	if err == sql.ErrNoRows {
+		return model.Room{}, store.ErrRoomNotFound
+	} else if err != nil {
+		return model.Room{}, err
+	}
+	room.CreatedAt = formatTime(createdAt)
+
+	room.Members, err = s.members(roomID)
+	if err != nil {
+		return model.Room{}, err
+	}
+	return room, nil
+}
+
+func (s *roomStore) ListForUser(userID int) ([]model.Room, error) {
+	rows, err := s.db.Query(`
+		SELECT r.id
+		FROM rooms r
+		JOIN room_members m ON m.room_id = r.id
+		WHERE m.user_id = $1
+		ORDER BY COALESCE((SELECT MAX(id) FROM room_messages
+			WHERE room_id = r.id AND (NOT hidden OR sender_id = m.user_id)), 0) DESC, r.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roomIDs []int
+	for rows.Next() {
+		var roomID int
+		if err := rows.Scan(&roomID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rooms := []model.Room{}
+	for _, roomID := range roomIDs {
+		room, err := s.Get(roomID)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (s *roomStore) IsMember(roomID, userID int) (bool, error) {
+	var isMember bool
+	err := s.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM room_members WHERE room_id = $1 AND user_id = $2)",
+		roomID, userID,
+	).Scan(&isMember)
+	return isMember, err
+}
+
+func (s *roomStore) MemberIDs(roomID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT user_id FROM room_members WHERE room_id = $1", roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (s *roomStore) Invite(roomID, inviterID, inviteeID int) error {
+	if err := s.requireMember(roomID, inviterID); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO room_members (room_id, user_id, role) VALUES ($1, $2, 'member') ON CONFLICT DO NOTHING",
+		roomID, inviteeID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, store.ErrAlreadyRoomMember)
+}
+
+func (s *roomStore) Leave(roomID, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result, store.ErrNotRoomMember); err != nil {
+		return err
+	}
+
+	var remaining, owners int
+	err = tx.QueryRow(
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE role = 'owner') FROM room_members WHERE room_id = $1",
+		roomID,
+	).Scan(&remaining, &owners)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case remaining == 0:
+		// Messages go with the room
+		if _, err := tx.Exec("DELETE FROM rooms WHERE id = $1", roomID); err != nil {
+			return err
+		}
+	case owners == 0:
+		_, err = tx.Exec(`
+			UPDATE room_members SET role = 'owner'
+			WHERE room_id = $1 AND user_id = (
+				SELECT user_id FROM room_members WHERE room_id = $1 ORDER BY joined_at, user_id LIMIT 1
+			)`, roomID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *roomStore) Rename(roomID, userID int, name string) error {
+	var role string
+	err := s.db.QueryRow(
+		"SELECT role FROM room_members WHERE room_id = $1 AND user_id = $2",
+		roomID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return store.ErrNotRoomMember
+	} else if err != nil {
+		return err
+	}
+	if role != "owner" {
+		return store.ErrNotRoomOwner
+	}
+
+	_, err = s.db.Exec("UPDATE rooms SET name = $1 WHERE id = $2", name, roomID)
+	return err
+}
+
+func (s *roomStore) AddMessage(roomID, senderID int, content string, hidden bool) (model.RoomMessage, error) {
+	now := time.Now()
+
+	var id int
+	err := s.db.QueryRow(
+		"INSERT INTO room_messages (room_id, sender_id, content, timestamp, hidden) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		roomID, senderID, content, now, hidden,
+	).Scan(&id)
+	if err != nil {
+		return model.RoomMessage{}, err
+	}
+
+	return model.RoomMessage{
+		ID:        id,
+		RoomID:    roomID,
+		SenderID:  senderID,
+		Content:   content,
+		Timestamp: formatTime(now),
+	}, nil
+}
+
+func (s *roomStore) History(roomID, viewerID, beforeID, limit int) ([]model.RoomMessage, bool, error) {
+	if beforeID <= 0 {
+		beforeID = math.MaxInt32
+	}
+
+	// Fetch one extra row to know whether another page exists
+	rows, err := s.db.Query(`
+		SELECT m.id, m.room_id, m.sender_id, COALESCE(u.username, 'Unknown'), m.content, m.timestamp
+		FROM room_messages m
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE m.room_id = $1 AND m.id < $2 AND (NOT m.hidden OR m.sender_id = $3)
+		ORDER BY m.id DESC
+		LIMIT $4
+	`, roomID, beforeID, viewerID, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	messages := []model.RoomMessage{}
+	for rows.Next() {
+		var msg model.RoomMessage
+		var timestamp time.Time
+		err := rows.Scan(&msg.ID, &msg.RoomID, &msg.SenderID, &msg.Username, &msg.Content, &timestamp)
+		if err != nil {
+			return nil, false, err
+		}
+		msg.Timestamp = formatTime(timestamp)
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Reverse order to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, hasMore, nil
+}
+
+// members lists the members of a room, owner first, then by name
+func (s *roomStore) members(roomID int) ([]model.RoomMember, error) {
+	rows, err := s.db.Query(`
+		SELECT m.user_id, COALESCE(u.username, 'Unknown'), m.role
+		FROM room_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.room_id = $1
+		ORDER BY m.role != 'owner', LOWER(COALESCE(u.username, 'Unknown'))
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.RoomMember{}
+	for rows.Next() {
+		var member model.RoomMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// requireMember returns ErrNotRoomMember unless the user belongs to the room
+func (s *roomStore) requireMember(roomID, userID int) error {
+	isMember, err := s.IsMember(roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return store.ErrNotRoomMember
+	}
+	return nil
+}
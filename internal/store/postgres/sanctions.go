@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"time"
+)
+
+type sanctionStore struct {
+	db *sql.DB
+}
+
+func (s *sanctionStore) Create(sanction store.Sanction) (int, error) {
+	reportID := sql.NullInt64{Int64: int64(sanction.ReportID), Valid: sanction.ReportID > 0}
+	expiresAt := sql.NullTime{Time: sanction.ExpiresAt, Valid: !sanction.ExpiresAt.IsZero()}
+
+	var id int
+	err := s.db.QueryRow(
+		"INSERT INTO sanctions (user_id, kind, reason, moderator_id, report_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		sanction.UserID, sanction.Kind, sanction.Reason, sanction.ModeratorID, reportID, sanction.CreatedAt, expiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *sanctionStore) Get(sanctionID int) (store.Sanction, error) {
+	rows, err := s.db.Query(sanctionColumns+" WHERE id = $1", sanctionID)
+	if err != nil {
+		return store.Sanction{}, err
+	}
+	sanctions, err := scanSanctions(rows)
+	if err != nil {
+		return store.Sanction{}, err
+	}
+	if len(sanctions) == 0 {
+		return store.Sanction{}, store.ErrSanctionNotFound
+	}
+	return sanctions[0], nil
+}
+
+func (s *sanctionStore) ListForUser(userID int) ([]store.Sanction, error) {
+	rows, err := s.db.Query(sanctionColumns+" WHERE user_id = $1 ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSanctions(rows)
+}
+
+func (s *sanctionStore) Active(userID int, now time.Time) ([]store.Sanction, error) {
+	rows, err := s.db.Query(sanctionColumns+`
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY id DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanSanctions(rows)
+}
+
+func (s *sanctionStore) ActiveUserIDs(kind string, now time.Time) ([]int, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT user_id FROM sanctions
+		WHERE kind = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+	`, kind, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (s *sanctionStore) Lift(sanctionID, moderatorID int) error {
+	result, err := s.db.Exec(`
+		UPDATE sanctions SET lifted_by = $1, lifted_at = NOW()
+		WHERE id = $2 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, moderatorID, sanctionID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, store.ErrSanctionNotFound)
+}
+
+// sanctionColumns selects the columns read by scanSanctions
+const sanctionColumns = `SELECT id, user_id, kind, reason, moderator_id, COALESCE(report_id, 0), created_at, expires_at,
+		COALESCE(lifted_by, 0), lifted_at
+	FROM sanctions`
+
+// scanSanctions reads rows selected with sanctionColumns
+func scanSanctions(rows *sql.Rows) ([]store.Sanction, error) {
+	defer rows.Close()
+
+	var sanctions []store.Sanction
+	for rows.Next() {
+		var sanction store.Sanction
+		var expiresAt, liftedAt sql.NullTime
+		err := rows.Scan(&sanction.ID, &sanction.UserID, &sanction.Kind, &sanction.Reason, &sanction.ModeratorID,
+			&sanction.ReportID, &sanction.CreatedAt, &expiresAt, &sanction.LiftedBy, &liftedAt)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			sanction.ExpiresAt = expiresAt.Time
+		}
+		if liftedAt.Valid {
+			sanction.LiftedAt = liftedAt.Time
+		}
+		sanctions = append(sanctions, sanction)
+	}
+	return sanctions, rows.Err()
+}
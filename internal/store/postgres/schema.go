@@ -0,0 +1,356 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/database"
+	"log"
+)
+
+// migration is one numbered step of the PostgreSQL schema. The numbering is
+// separate from the SQLite migrations: version 1 already matches the SQLite
+// schema after its categories migration.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS users (
+					id SERIAL PRIMARY KEY,
+					username TEXT UNIQUE,
+					email TEXT UNIQUE,
+					password TEXT,
+					first_name TEXT,
+					last_name TEXT,
+					age INTEGER,
+					gender TEXT,
+					role TEXT NOT NULL DEFAULT 'user'
+				);`,
+				`CREATE TABLE IF NOT EXISTS categories (
+					id SERIAL PRIMARY KEY,
+					name TEXT NOT NULL,
+					description TEXT NOT NULL DEFAULT '',
+					position INTEGER NOT NULL DEFAULT 0,
+					archived_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories (LOWER(name));`,
+				`CREATE TABLE IF NOT EXISTS posts (
+					id SERIAL PRIMARY KEY,
+					user_id INTEGER REFERENCES users(id),
+					title TEXT,
+					content TEXT,
+					date TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					edited_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ
+				);`,
+				`CREATE TABLE IF NOT EXISTS post_categories (
+					post_id INTEGER NOT NULL REFERENCES posts(id),
+					category_id INTEGER NOT NULL REFERENCES categories(id),
+					PRIMARY KEY (post_id, category_id)
+				);`,
+				`CREATE TABLE IF NOT EXISTS comments (
+					id SERIAL PRIMARY KEY,
+					post_id INTEGER REFERENCES posts(id),
+					user_id INTEGER REFERENCES users(id),
+					parent_comment_id INTEGER REFERENCES comments(id),
+					content TEXT,
+					edited_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ
+				);`,
+				`CREATE TABLE IF NOT EXISTS reactions (
+					id SERIAL PRIMARY KEY,
+					post_id INTEGER REFERENCES posts(id),
+					user_id INTEGER REFERENCES users(id),
+					comment_id INTEGER REFERENCES comments(id),
+					type TEXT CHECK(type IN ('like', 'dislike')),
+					UNIQUE (user_id, post_id, comment_id)
+				);`,
+				`CREATE TABLE IF NOT EXISTS post_revisions (
+					id SERIAL PRIMARY KEY,
+					post_id INTEGER NOT NULL REFERENCES posts(id),
+					title TEXT,
+					content TEXT,
+					category TEXT,
+					edited_by INTEGER REFERENCES users(id),
+					edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE TABLE IF NOT EXISTS comment_revisions (
+					id SERIAL PRIMARY KEY,
+					comment_id INTEGER NOT NULL REFERENCES comments(id),
+					content TEXT,
+					edited_by INTEGER REFERENCES users(id),
+					edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE TABLE IF NOT EXISTS sessions (
+					session_id TEXT PRIMARY KEY,
+					id INTEGER NOT NULL REFERENCES users(id),
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					expires_at TIMESTAMPTZ,
+					user_agent TEXT NOT NULL DEFAULT '',
+					ip_address TEXT NOT NULL DEFAULT '',
+					last_seen TIMESTAMPTZ
+				);`,
+				`CREATE TABLE IF NOT EXISTS private_messages (
+					id SERIAL PRIMARY KEY,
+					sender_id INTEGER REFERENCES users(id),
+					receiver_id INTEGER REFERENCES users(id),
+					content TEXT,
+					timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					edited_at TIMESTAMPTZ,
+					deleted_at TIMESTAMPTZ
+				);`,
+				`CREATE TABLE IF NOT EXISTS conversation_reads (
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					partner_id INTEGER NOT NULL REFERENCES users(id),
+					last_read_id INTEGER NOT NULL DEFAULT 0,
+					read_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					PRIMARY KEY (user_id, partner_id)
+				);`,
+				`CREATE TABLE IF NOT EXISTS pending_notifications (
+					id SERIAL PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					sender_id INTEGER NOT NULL REFERENCES users(id),
+					message_id INTEGER NOT NULL REFERENCES private_messages(id),
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_post_categories_category_id ON post_categories(category_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON post_revisions(post_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_id ON comment_revisions(comment_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_sessions_id ON sessions(id);`,
+				`CREATE INDEX IF NOT EXISTS idx_private_messages_pair ON private_messages(sender_id, receiver_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_pending_notifications_user_id ON pending_notifications(user_id);`,
+			)
+			if err != nil {
+				return err
+			}
+
+			for position, name := range database.DefaultCategories {
+				_, err := tx.Exec("INSERT INTO categories (name, position) VALUES ($1, $2)", name, position)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 2,
+		name:    "reports and sanctions",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS reports (
+					id SERIAL PRIMARY KEY,
+					reporter_id INTEGER NOT NULL REFERENCES users(id),
+					item_type TEXT NOT NULL,
+					item_id INTEGER NOT NULL,
+					author_id INTEGER NOT NULL REFERENCES users(id),
+					content TEXT NOT NULL DEFAULT '',
+					reason TEXT NOT NULL,
+					details TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL,
+					action TEXT,
+					note TEXT,
+					resolved_by INTEGER REFERENCES users(id),
+					resolved_at TIMESTAMPTZ
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_reports_item ON reports(item_type, item_id);`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open ON reports(reporter_id, item_type, item_id)
+					WHERE resolved_at IS NULL;`,
+				`CREATE TABLE IF NOT EXISTS sanctions (
+					id SERIAL PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					kind TEXT NOT NULL,
+					reason TEXT NOT NULL DEFAULT '',
+					moderator_id INTEGER NOT NULL REFERENCES users(id),
+					report_id INTEGER REFERENCES reports(id),
+					created_at TIMESTAMPTZ NOT NULL,
+					expires_at TIMESTAMPTZ
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_sanctions_user_id ON sanctions(user_id);`,
+			)
+		},
+	},
+	{
+		version: 3,
+		name:    "lifted sanctions and hidden messages",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE sanctions ADD COLUMN IF NOT EXISTS lifted_by INTEGER REFERENCES users(id);`,
+				`ALTER TABLE sanctions ADD COLUMN IF NOT EXISTS lifted_at TIMESTAMPTZ;`,
+				`ALTER TABLE private_messages ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;`,
+			)
+		},
+	},
+	{
+		version: 4,
+		name:    "audit log",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS audit_log (
+					id SERIAL PRIMARY KEY,
+					actor_id INTEGER NOT NULL REFERENCES users(id),
+					action TEXT NOT NULL,
+					target_type TEXT NOT NULL,
+					target_id INTEGER NOT NULL DEFAULT 0,
+					before_state TEXT,
+					after_state TEXT,
+					created_at TIMESTAMPTZ NOT NULL
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);`,
+				// The log is append-only, even for code that bypasses the store
+				`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+				BEGIN
+					RAISE EXCEPTION 'audit log is append-only';
+				END;
+				$$ LANGUAGE plpgsql;`,
+				`DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;`,
+				`CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+					FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();`,
+			)
+		},
+	},
+	{
+		version: 5,
+		name:    "chat rooms",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS rooms (
+					id SERIAL PRIMARY KEY,
+					name TEXT NOT NULL,
+					category TEXT NOT NULL DEFAULT '',
+					created_by INTEGER REFERENCES users(id),
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE TABLE IF NOT EXISTS room_members (
+					room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'member')),
+					joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					PRIMARY KEY (room_id, user_id)
+				);`,
+				`CREATE TABLE IF NOT EXISTS room_messages (
+					id SERIAL PRIMARY KEY,
+					room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+					sender_id INTEGER NOT NULL REFERENCES users(id),
+					content TEXT NOT NULL,
+					timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+					hidden BOOLEAN NOT NULL DEFAULT FALSE
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_room_members_user_id ON room_members(user_id);`,
+				`CREATE INDEX IF NOT EXISTS idx_room_messages_room_id ON room_messages(room_id);`,
+			)
+		},
+	},
+	{
+		version: 6,
+		name:    "search index",
+		up: func(tx *sql.Tx) error {
+			// The indexed expressions are the ones searchStore queries with,
+			// or the indexes would not be used
+			return execAll(tx,
+				`CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (`+postDocument("")+`);`,
+				`CREATE INDEX IF NOT EXISTS idx_comments_search ON comments USING GIN (`+contentDocument("")+`);`,
+				`CREATE INDEX IF NOT EXISTS idx_private_messages_search ON private_messages USING GIN (`+contentDocument("")+`);`,
+			)
+		},
+	},
+	{
+		version: 7,
+		name:    "stored notifications",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS notifications (
+					id SERIAL PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					frame TEXT NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+				);`,
+				`CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);`,
+			)
+		},
+	},
+	{
+		version: 8,
+		name:    "room categories",
+		up: func(tx *sql.Tx) error {
+			// Rooms whose category no longer exists are left without one
+			return execAll(tx,
+				`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id);`,
+				`UPDATE rooms SET category_id = c.id
+					FROM categories c
+					WHERE LOWER(c.name) = LOWER(TRIM(rooms.category));`,
+				`ALTER TABLE rooms DROP COLUMN IF EXISTS category;`,
+			)
+		},
+	},
+}
+
+// Migrate applies every migration newer than the schema_version table
+// records, each in its own transaction
+func Migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := apply(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		log.Printf("Applied PostgreSQL migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+// apply runs one migration and records it in the same transaction
+func apply(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO schema_version (version, name, applied_at) VALUES ($1, $2, NOW())",
+		m.version, m.name,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execAll runs the statements in order
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/search"
+	"strings"
+	"unicode"
+)
+
+// postDocument is what the search index holds of a post, whose columns are
+// qualified with alias. It is parenthesized to be usable as an index
+// expression. The simple configuration neither stems nor drops
+// stop words, like the SQLite index.
+func postDocument(alias string) string {
+	return `(setweight(to_tsvector('simple', COALESCE(` + alias + `title, '')), 'A') || ` +
+		`setweight(to_tsvector('simple', COALESCE(` + alias + `content, '')), 'D'))`
+}
+
+// contentDocument is what the search index holds of a comment or message
+func contentDocument(alias string) string {
+	return `to_tsvector('simple', COALESCE(` + alias + `content, ''))`
+}
+
+// headlineOptions makes ts_headline mark matches the way snippets are
+// expected to, and keep them about as long as the SQLite ones
+const headlineOptions = `StartSel="` + search.MatchStart + `", StopSel="` + search.MatchEnd + `", MinWords=8, MaxWords=16`
+
+type searchStore struct {
+	db *sql.DB
+}
+
+// Available is always true: the search index is created by a migration
+func (s *searchStore) Available() bool {
+	return true
+}
+
+// Search ranks the matches of each scope by ts_rank. Ranks are negated so
+// that, as with the SQLite backend, better matches have lower ranks.
+func (s *searchStore) Search(userID int, input string, scopes []string, limit int, hiddenAuthors []int) (map[string][]model.SearchResult, error) {
+	query := tsQuery(input)
+
+	results := make(map[string][]model.SearchResult)
+	for _, scope := range scopes {
+		var found []model.SearchResult
+		var err error
+
+		switch {
+		case scope != search.ScopePosts && scope != search.ScopeComments && scope != search.ScopeMessages:
+			continue
+		case query == "":
+			found = []model.SearchResult{}
+		case scope == search.ScopePosts:
+			found, err = s.posts(query, limit, hiddenAuthors)
+		case scope == search.ScopeComments:
+			found, err = s.comments(query, limit, hiddenAuthors)
+		case scope == search.ScopeMessages:
+			found, err = s.messages(userID, query, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[scope] = found
+	}
+
+	return results, nil
+}
+
+// posts matches post titles and contents, weighting titles higher
+func (s *searchStore) posts(query string, limit int, hiddenAuthors []int) ([]model.SearchResult, error) {
+	var args queryArgs
+	q := args.add(query)
+	return s.collect(`
+		SELECT p.id, p.id, COALESCE(p.title, ''),
+			ts_headline('simple', COALESCE(p.content, ''), q.query, `+args.add(headlineOptions)+`),
+			COALESCE(u.username, 'Unknown'), p.date, -ts_rank(`+postDocument("p.")+`, q.query)
+		FROM posts p
+		CROSS JOIN to_tsquery('simple', `+q+`) AS q(query)
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE `+postDocument("p.")+` @@ q.query AND p.deleted_at IS NULL`+notIn("p.user_id", hiddenAuthors, &args)+`
+		ORDER BY 7, p.id DESC
+		LIMIT `+args.add(limit),
+		search.ScopePosts, args...)
+}
+
+// comments matches comment contents and links back to their post
+func (s *searchStore) comments(query string, limit int, hiddenAuthors []int) ([]model.SearchResult, error) {
+	var args queryArgs
+	q := args.add(query)
+	return s.collect(`
+		SELECT c.id, c.post_id, COALESCE(p.title, ''),
+			ts_headline('simple', COALESCE(c.content, ''), q.query, `+args.add(headlineOptions)+`),
+			COALESCE(u.username, 'Unknown'), p.date, -ts_rank(`+contentDocument("c.")+`, q.query)
+		FROM comments c
+		CROSS JOIN to_tsquery('simple', `+q+`) AS q(query)
+		LEFT JOIN posts p ON p.id = c.post_id
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE `+contentDocument("c.")+` @@ q.query AND c.deleted_at IS NULL`+notIn("c.user_id", hiddenAuthors, &args)+`
+		ORDER BY 7, c.id DESC
+		LIMIT `+args.add(limit),
+		search.ScopeComments, args...)
+}
+
+// messages matches private messages the user sent or received
+func (s *searchStore) messages(userID int, query string, limit int) ([]model.SearchResult, error) {
+	return s.collect(`
+		SELECT m.id, 0, COALESCE(u.username, 'Unknown'),
+			ts_headline('simple', COALESCE(m.content, ''), q.query, $2),
+			COALESCE(s.username, 'Unknown'), m.timestamp, -ts_rank(`+contentDocument("m.")+`, q.query)
+		FROM private_messages m
+		CROSS JOIN to_tsquery('simple', $1) AS q(query)
+		LEFT JOIN users u ON u.id = CASE WHEN m.sender_id = $3 THEN m.receiver_id ELSE m.sender_id END
+		LEFT JOIN users s ON s.id = m.sender_id
+		WHERE `+contentDocument("m.")+` @@ q.query
+			AND (m.sender_id = $3 OR m.receiver_id = $3)
+			AND (NOT m.hidden OR m.sender_id = $3)
+			AND m.deleted_at IS NULL
+		ORDER BY 7, m.id DESC
+		LIMIT $4
+	`, search.ScopeMessages, query, headlineOptions, userID, limit)
+}
+
+// collect scans search rows of the shape (id, post id, title, snippet,
+// username, date, rank) into results of the given type
+func (s *searchStore) collect(query, resultType string, args ...interface{}) ([]model.SearchResult, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []model.SearchResult{}
+	for rows.Next() {
+		result := model.SearchResult{Type: resultType}
+		var date sql.NullTime
+		err := rows.Scan(&result.ID, &result.PostID, &result.Title, &result.Snippet, &result.Username, &date, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		if date.Valid {
+			result.Date = formatTime(date.Time)
+		}
+		result.Snippet = search.Highlight(result.Snippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// notIn builds a condition, to be appended to a WHERE clause, excluding rows
+// whose column holds one of ids
+func notIn(column string, ids []int, args *queryArgs) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = args.add(id)
+	}
+	return " AND " + column + " NOT IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// tsQuery turns user input into a tsquery of the terms found by
+// search.ParseTerms, all of which must match. Phrases become words that
+// follow each other and prefix terms match every word starting with them.
+// Only letters and digits are kept, so the input cannot inject tsquery
+// operators. It returns "" when nothing is left to search for.
+func tsQuery(input string) string {
+	var parts []string
+	for _, term := range search.ParseTerms(input) {
+		words := strings.FieldsFunc(strings.ToLower(term.Text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		part := strings.Join(words, " <-> ")
+		if term.Prefix {
+			part += ":*"
+		}
+		if len(words) > 1 {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " & ")
+}
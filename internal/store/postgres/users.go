@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+)
+
+type userStore struct {
+	db *sql.DB
+}
+
+func (s *userStore) Create(user model.User, passwordHash string) (int, error) {
+	var id int
+	err := s.db.QueryRow(
+		"INSERT INTO users (username, email, password, first_name, last_name, age, gender) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		user.Username, user.Email, passwordHash, user.FirstName, user.LastName, user.Age, user.Gender,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *userStore) EmailExists(email string) (bool, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email).Scan(&count)
+	return count > 0, err
+}
+
+func (s *userStore) UsernameExists(username string) (bool, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", username).Scan(&count)
+	return count > 0, err
+}
+
+func (s *userStore) Credentials(identifier string) (int, string, error) {
+	var userID int
+	var hash string
+
+	// Try the email first, then the username
+	err := s.db.QueryRow("SELECT id, password FROM users WHERE email = $1", identifier).Scan(&userID, &hash)
+	if err == sql.ErrNoRows {
+		err = s.db.QueryRow("SELECT id, password FROM users WHERE username = $1", identifier).Scan(&userID, &hash)
+	}
+	if err == sql.ErrNoRows {
+		return 0, "", store.ErrNotFound
+	}
+	return userID, hash, err
+}
+
+func (s *userStore) Username(userID int) (string, error) {
+	var username string
+	err := s.db.QueryRow("SELECT username FROM users WHERE id = $1", userID).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", store.ErrNotFound
+	}
+	return username, err
+}
+
+func (s *userStore) IDByUsername(username string) (int, error) {
+	var userID int
+	err := s.db.QueryRow("SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrNotFound
+	}
+	return userID, err
+}
+
+func (s *userStore) Role(userID int) (string, error) {
+	var role string
+	err := s.db.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func (s *userStore) SetRole(userID int, role string) error {
+	result, err := s.db.Exec("UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, store.ErrNotFound)
+}
+
+func (s *userStore) List() ([]model.User, error) {
+	rows, err := s.db.Query("SELECT id, username, role FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
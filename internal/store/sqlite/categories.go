@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"time"
+)
+
+type categoryStore struct {
+	db *sql.DB
+}
+
+func (s *categoryStore) List(includeArchived bool) ([]model.Category, error) {
+	query := "SELECT id, name, description, position, archived_at IS NOT NULL FROM categories"
+	if !includeArchived {
+		query += " WHERE archived_at IS NULL"
+	}
+	query += " ORDER BY position, id"
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return scanCategories(rows)
+}
+
+func (s *categoryStore) Get(categoryID int) (model.Category, error) {
+	var category model.Category
+	err := s.db.QueryRow(
+		"SELECT id, name, description, position, archived_at IS NOT NULL FROM categories WHERE id = ?",
+		categoryID,
+	).Scan(&category.ID, &category.Name, &category.Description, &category.Position, &category.Archived)
+	if err == sql.ErrNoRows {
+		return model.Category{}, store.ErrCategoryNotFound
+	}
+	return category, err
+}
+
+func (s *categoryStore) Create(name, description string) (int, error) {
+	if err := s.requireUniqueName(name, 0); err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO categories (name, description, position)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM categories))
+	`, name, description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *categoryStore) Update(categoryID int, name, description string) error {
+	if err := s.requireUniqueName(name, categoryID); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(
+		"UPDATE categories SET name = ?, description = ? WHERE id = ?",
+		name, description, categoryID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, store.ErrCategoryNotFound)
+}
+
+func (s *categoryStore) Reorder(categoryIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id FROM categories ORDER BY position, id")
+	if err != nil {
+		return err
+	}
+	var current []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	order, err := store.ReorderIDs(current, categoryIDs)
+	if err != nil {
+		return err
+	}
+	for position, id := range order {
+		if _, err := tx.Exec("UPDATE categories SET position = ? WHERE id = ?", position, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *categoryStore) SetArchived(categoryID int, archived bool) error {
+	query := "UPDATE categories SET archived_at = NULL WHERE id = ?"
+	args := []interface{}{categoryID}
+	if archived {
+		query = "UPDATE categories SET archived_at = COALESCE(archived_at, ?) WHERE id = ?"
+		args = []interface{}{time.Now(), categoryID}
+	}
+
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, store.ErrCategoryNotFound)
+}
+
+func (s *categoryStore) ValidateIDs(categoryIDs []int) error {
+	for _, id := range categoryIDs {
+		category, err := s.Get(id)
+		if err != nil {
+			return err
+		}
+		if category.Archived {
+			return store.ErrCategoryArchived
+		}
+	}
+	return nil
+}
+
+func (s *categoryStore) DefaultID() (int, error) {
+	var id int
+	err := s.db.QueryRow(
+		"SELECT id FROM categories WHERE archived_at IS NULL ORDER BY position, id LIMIT 1",
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrCategoryNotFound
+	}
+	return id, err
+}
+
+// requireUniqueName returns ErrDuplicateCategory when another category
+// already uses name, ignoring case
+func (s *categoryStore) requireUniqueName(name string, categoryID int) error {
+	var count int
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM categories WHERE name = ? AND id != ?",
+		name, categoryID,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return store.ErrDuplicateCategory
+	}
+	return nil
+}
+
+// scanCategories reads rows of (id, name, description, position, archived)
+func scanCategories(rows *sql.Rows) ([]model.Category, error) {
+	defer rows.Close()
+
+	categories := []model.Category{}
+	for rows.Next() {
+		var category model.Category
+		err := rows.Scan(&category.ID, &category.Name, &category.Description, &category.Position, &category.Archived)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"time"
+)
+
+type commentStore struct {
+	db *sql.DB
+}
+
+func (s *commentStore) ListForPost(postID int) ([]model.Comment, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, COALESCE(c.parent_comment_id, 0), c.user_id, c.content, u.username, c.edited_at, c.deleted_at IS NOT NULL,
+			COALESCE(SUM(CASE WHEN r.type = 'like' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN r.type = 'dislike' THEN 1 ELSE 0 END), 0)
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN reactions r ON r.comment_id = c.id
+		WHERE c.post_id = ?
+		GROUP BY c.id
+		ORDER BY c.id`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []model.Comment
+	for rows.Next() {
+		comment := model.Comment{PostID: postID}
+		var editedAt sql.NullTime
+		err := rows.Scan(&comment.ID, &comment.ParentID, &comment.UserID, &comment.Content, &comment.Username,
+			&editedAt, &comment.Deleted, &comment.Likes, &comment.Dislikes)
+		if err != nil {
+			return nil, err
+		}
+		if editedAt.Valid {
+			comment.EditedAt = editedAt.Time.Format(time.RFC3339)
+		}
+
+		if comment.Deleted {
+			comment.Content = ""
+			comment.Username = ""
+			comment.UserID = 0
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (s *commentStore) Create(userID, postID, parentID int, content string) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, store.ErrPostNotFound
+	}
+
+	// Replies must target a live comment of the same post
+	var parent interface{}
+	if parentID > 0 {
+		err := s.db.QueryRow(
+			"SELECT COUNT(*) FROM comments WHERE id = ? AND post_id = ? AND deleted_at IS NULL", parentID, postID,
+		).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, store.ErrCommentNotFound
+		}
+		parent = parentID
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO comments (user_id, post_id, parent_comment_id, content) VALUES (?, ?, ?, ?)",
+		userID, postID, parent, content,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *commentStore) Get(commentID int) (model.Comment, error) {
+	var comment model.Comment
+	var editedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT c.id, c.post_id, COALESCE(c.parent_comment_id, 0), c.user_id, c.content, u.username,
+			c.edited_at, c.deleted_at IS NOT NULL
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = ?`, commentID,
+	).Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.UserID, &comment.Content, &comment.Username,
+		&editedAt, &comment.Deleted)
+	if err == sql.ErrNoRows {
+		return model.Comment{}, store.ErrCommentNotFound
+	} else if err != nil {
+		return model.Comment{}, err
+	}
+	if editedAt.Valid {
+		comment.EditedAt = editedAt.Time.Format(time.RFC3339)
+	}
+	return comment, nil
+}
+
+func (s *commentStore) AuthorID(commentID int) (int, error) {
+	var userID int
+	err := s.db.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrCommentNotFound
+	}
+	return userID, err
+}
+
+func (s *commentStore) Update(commentID, userID int, content string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	oldContent, err := ownedComment(tx, commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"INSERT INTO comment_revisions (comment_id, content, edited_by, edited_at) VALUES (?, ?, ?, ?)",
+		commentID, oldContent, userID, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE comments SET content = ?, edited_at = ? WHERE id = ?", content, now, commentID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *commentStore) Delete(commentID, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedComment(tx, commentID, userID); err != nil {
+		return err
+	}
+
+	if err := removeComment(tx, commentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *commentStore) Remove(commentID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM comments WHERE id = ? AND deleted_at IS NULL", commentID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return store.ErrCommentNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := removeComment(tx, commentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removeComment soft-deletes a comment and removes its reactions
+func removeComment(tx *sql.Tx, commentID int) error {
+	if _, err := tx.Exec("DELETE FROM reactions WHERE comment_id = ?", commentID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE comments SET deleted_at = ? WHERE id = ?", time.Now(), commentID)
+	return err
+}
+
+// ownedComment returns the content of a comment that is not deleted, checking
+// that userID wrote it
+func ownedComment(tx *sql.Tx, commentID, userID int) (string, error) {
+	var ownerID int
+	var content string
+	err := tx.QueryRow(
+		"SELECT user_id, content FROM comments WHERE id = ? AND deleted_at IS NULL", commentID,
+	).Scan(&ownerID, &content)
+	if err == sql.ErrNoRows {
+		return "", store.ErrCommentNotFound
+	} else if err != nil {
+		return "", err
+	}
+	if ownerID != userID {
+		return "", store.ErrNotCommentOwner
+	}
+	return content, nil
+}
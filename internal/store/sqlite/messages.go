@@ -0,0 +1,288 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"math"
+	"time"
+)
+
+type messageStore struct {
+	db *sql.DB
+}
+
+func (s *messageStore) Create(senderID, receiverID int, content string, hidden bool) (model.PrivateMessage, error) {
+	timestamp := time.Now().Format(time.RFC3339)
+
+	result, err := s.db.Exec(
+		"INSERT INTO private_messages (sender_id, receiver_id, content, timestamp, hidden) VALUES (?, ?, ?, ?, ?)",
+		senderID, receiverID, content, timestamp, hidden,
+	)
+	if err != nil {
+		return model.PrivateMessage{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return model.PrivateMessage{}, err
+	}
+
+	return model.PrivateMessage{
+		ID:         int(id),
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    content,
+		Timestamp:  timestamp,
+		Hidden:     hidden,
+	}, nil
+}
+
+func (s *messageStore) Get(messageID int) (model.PrivateMessage, error) {
+	rows, err := s.db.Query(messageColumns+" WHERE id = ?", messageID)
+	if err != nil {
+		return model.PrivateMessage{}, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return model.PrivateMessage{}, err
+	}
+	if len(messages) == 0 {
+		return model.PrivateMessage{}, store.ErrNotFound
+	}
+	return messages[0], nil
+}
+
+func (s *messageStore) History(userID, partnerID, beforeID, limit int) ([]model.PrivateMessage, bool, error) {
+	if beforeID <= 0 {
+		beforeID = math.MaxInt
+	}
+
+	// Fetch one extra row to know whether another page exists
+	rows, err := s.db.Query(messageColumns+`
+		WHERE ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ? AND hidden = 0))
+		AND id < ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, partnerID, partnerID, userID, beforeID, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Reverse order to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, hasMore, nil
+}
+
+func (s *messageStore) Update(messageID int, content, editedAt string) error {
+	_, err := s.db.Exec(
+		"UPDATE private_messages SET content = ?, edited_at = ? WHERE id = ?",
+		content, editedAt, messageID,
+	)
+	return err
+}
+
+func (s *messageStore) Delete(messageID int, deletedAt string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE private_messages SET content = '', deleted_at = ? WHERE id = ?", deletedAt, messageID)
+	if err != nil {
+		return err
+	}
+
+	// A deleted message is no longer worth announcing to an offline receiver
+	_, err = tx.Exec("DELETE FROM pending_notifications WHERE message_id = ?", messageID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *messageStore) Conversations(userID int) ([]model.Conversation, error) {
+	rows, err := s.db.Query(`
+		WITH pm AS (
+			SELECT id, sender_id, content, timestamp,
+				CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END AS partner_id
+			FROM private_messages
+			WHERE sender_id = ? OR (receiver_id = ? AND hidden = 0)
+		), last AS (
+			SELECT partner_id, MAX(id) AS last_id FROM pm GROUP BY partner_id
+		), unread AS (
+			SELECT m.sender_id AS partner_id, COUNT(*) AS count
+			FROM private_messages m
+			LEFT JOIN conversation_reads cr ON cr.user_id = m.receiver_id AND cr.partner_id = m.sender_id
+			WHERE m.receiver_id = ? AND m.deleted_at IS NULL AND m.hidden = 0 AND m.id > COALESCE(cr.last_read_id, 0)
+			GROUP BY m.sender_id
+		)
+		SELECT u.id, u.username,
+			COALESCE(pm.content, ''), COALESCE(pm.sender_id, 0), COALESCE(pm.timestamp, ''),
+			COALESCE(unread.count, 0)
+		FROM users u
+		LEFT JOIN last ON last.partner_id = u.id
+		LEFT JOIN pm ON pm.id = last.last_id
+		LEFT JOIN unread ON unread.partner_id = u.id
+		WHERE u.id != ?
+		ORDER BY last.last_id IS NULL, last.last_id DESC, u.username COLLATE NOCASE
+	`, userID, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []model.Conversation{}
+	for rows.Next() {
+		var conv model.Conversation
+		err := rows.Scan(&conv.UserID, &conv.Username, &conv.LastMessage, &conv.LastSenderID, &conv.LastTimestamp, &conv.UnreadCount)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conv)
+	}
+
+	return conversations, rows.Err()
+}
+
+func (s *messageStore) MarkRead(userID, partnerID, messageID int) (int, error) {
+	if messageID <= 0 {
+		err := s.db.QueryRow(
+			"SELECT COALESCE(MAX(id), 0) FROM private_messages WHERE sender_id = ? AND receiver_id = ? AND hidden = 0",
+			partnerID, userID,
+		).Scan(&messageID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Never move the marker backwards
+	_, err := s.db.Exec(`
+		INSERT INTO conversation_reads (user_id, partner_id, last_read_id, read_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, partner_id) DO UPDATE SET
+			last_read_id = MAX(last_read_id, excluded.last_read_id),
+			read_at = excluded.read_at
+	`, userID, partnerID, messageID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.LastReadID(userID, partnerID)
+}
+
+func (s *messageStore) LastReadID(userID, partnerID int) (int, error) {
+	var lastReadID int
+	err := s.db.QueryRow(
+		"SELECT COALESCE(MAX(last_read_id), 0) FROM conversation_reads WHERE user_id = ? AND partner_id = ?",
+		userID, partnerID,
+	).Scan(&lastReadID)
+	return lastReadID, err
+}
+
+func (s *messageStore) UnreadCounts(userID int) (map[int]int, int, error) {
+	rows, err := s.db.Query(`
+		SELECT m.sender_id, COUNT(*)
+		FROM private_messages m
+		LEFT JOIN conversation_reads cr ON cr.user_id = m.receiver_id AND cr.partner_id = m.sender_id
+		WHERE m.receiver_id = ? AND m.deleted_at IS NULL AND m.hidden = 0 AND m.id > COALESCE(cr.last_read_id, 0)
+		GROUP BY m.sender_id
+	`, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	total := 0
+	for rows.Next() {
+		var senderID, count int
+		if err := rows.Scan(&senderID, &count); err != nil {
+			return nil, 0, err
+		}
+		counts[senderID] = count
+		total += count
+	}
+
+	return counts, total, rows.Err()
+}
+
+func (s *messageStore) AddPendingNotification(userID, senderID, messageID int) error {
+	_, err := s.db.Exec(
+		"INSERT INTO pending_notifications (user_id, sender_id, message_id) VALUES (?, ?, ?)",
+		userID, senderID, messageID,
+	)
+	return err
+}
+
+func (s *messageStore) MissedMessages(userID int) ([]model.MissedSender, error) {
+	rows, err := s.db.Query(`
+		WITH missed AS (
+			SELECT sender_id, COUNT(*) AS count, MAX(message_id) AS latest_id
+			FROM pending_notifications
+			WHERE user_id = ?
+			GROUP BY sender_id
+		)
+		SELECT missed.sender_id, COALESCE(u.username, 'Unknown'), missed.count, missed.latest_id,
+			COALESCE(m.content, ''), COALESCE(m.timestamp, '')
+		FROM missed
+		LEFT JOIN users u ON u.id = missed.sender_id
+		LEFT JOIN private_messages m ON m.id = missed.latest_id
+		ORDER BY missed.latest_id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	senders := []model.MissedSender{}
+	for rows.Next() {
+		var sender model.MissedSender
+		err := rows.Scan(&sender.SenderID, &sender.Username, &sender.Count, &sender.LatestMessageID,
+			&sender.LatestPreview, &sender.LatestTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, sender)
+	}
+	return senders, rows.Err()
+}
+
+func (s *messageStore) ClearPendingNotifications(userID int) error {
+	_, err := s.db.Exec("DELETE FROM pending_notifications WHERE user_id = ?", userID)
+	return err
+}
+
+// messageColumns selects the columns read by scanMessages
+const messageColumns = `SELECT id, sender_id, receiver_id, content, timestamp, COALESCE(edited_at, ''), deleted_at IS NOT NULL, hidden
+	FROM private_messages`
+
+// scanMessages reads rows selected with messageColumns
+func scanMessages(rows *sql.Rows) ([]model.PrivateMessage, error) {
+	defer rows.Close()
+
+	var messages []model.PrivateMessage
+	for rows.Next() {
+		var msg model.PrivateMessage
+		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Content, &msg.Timestamp, &msg.EditedAt, &msg.Deleted, &msg.Hidden)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
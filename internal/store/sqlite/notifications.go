@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"database/sql"
+)
+
+type notificationStore struct {
+	db *sql.DB
+}
+
+func (s *notificationStore) Add(userID int, frame []byte) error {
+	_, err := s.db.Exec("INSERT INTO notifications (user_id, frame) VALUES (?, ?)", userID, string(frame))
+	return err
+}
+
+func (s *notificationStore) Take(userID int) ([][]byte, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, frame FROM notifications WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := [][]byte{}
+	lastID := 0
+	for rows.Next() {
+		var frame string
+		if err := rows.Scan(&lastID, &frame); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		frames = append(frames, []byte(frame))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Leave notifications added since the query for the next connection
+	if _, err := tx.Exec("DELETE FROM notifications WHERE user_id = ? AND id <= ?", userID, lastID); err != nil {
+		return nil, err
+	}
+	return frames, tx.Commit()
+}
@@ -0,0 +1,393 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/post"
+	"forum/internal/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postCategoryNames is an SQL expression selecting the comma-joined category
+// names of the post aliased p, in display order
+const postCategoryNames = `(
+	SELECT COALESCE(group_concat(name, ', '), '') FROM (
+		SELECT c.name FROM post_categories pc
+		JOIN categories c ON c.id = pc.category_id
+		WHERE pc.post_id = p.id
+		ORDER BY c.position, c.id
+	)
+)`
+
+// scores holds the SQL expression each sort mode orders by; see post.HotScore
+var scores = map[string]string{
+	post.SortNewest:   "p.id",
+	post.SortTop:      "COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0)",
+	post.SortComments: "COALESCE(c.comment_count, 0)",
+	post.SortHot: `(COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0) + 1.0) /
+		(((julianday(:now) - julianday(p.date)) * 24 + 2) * ((julianday(:now) - julianday(p.date)) * 24 + 2))`,
+}
+
+type postStore struct {
+	db *sql.DB
+}
+
+func (s *postStore) Create(userID int, title, content string, categoryIDs []int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO posts (user_id, title, content, date) VALUES (?, ?, ?, datetime('now'))",
+		userID, title, content,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	postID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := setPostCategories(tx, int(postID), categoryIDs); err != nil {
+		return 0, err
+	}
+
+	return int(postID), tx.Commit()
+}
+
+func (s *postStore) Get(postID int) (model.Post, error) {
+	var p model.Post
+	var editedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT p.id, p.user_id, COALESCE(u.username, 'Unknown'), p.title, p.content, `+postCategoryNames+`, p.date, p.edited_at
+		FROM posts p
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE p.id = ? AND p.deleted_at IS NULL
+	`, postID).Scan(&p.ID, &p.UserID, &p.Username, &p.Title, &p.Content, &p.Category, &p.Date, &editedAt)
+	if err == sql.ErrNoRows {
+		return model.Post{}, store.ErrPostNotFound
+	} else if err != nil {
+		return model.Post{}, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT c.id, c.name, c.description, c.position, c.archived_at IS NOT NULL
+		FROM categories c
+		JOIN post_categories pc ON pc.category_id = c.id
+		WHERE pc.post_id = ?
+		ORDER BY c.position, c.id
+	`, postID)
+	if err != nil {
+		return model.Post{}, err
+	}
+	p.Categories, err = scanCategories(rows)
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	if editedAt.Valid {
+		p.EditedAt = editedAt.Time.Format(time.RFC3339)
+	}
+	return p, nil
+}
+
+func (s *postStore) Update(postID, userID int, title, content string, categoryIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	var oldTitle, oldContent, oldCategory string
+	err = tx.QueryRow(
+		"SELECT p.user_id, p.title, p.content, "+postCategoryNames+" FROM posts p WHERE p.id = ? AND p.deleted_at IS NULL", postID,
+	).Scan(&ownerID, &oldTitle, &oldContent, &oldCategory)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return store.ErrNotPostOwner
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"INSERT INTO post_revisions (post_id, title, content, category, edited_by, edited_at) VALUES (?, ?, ?, ?, ?, ?)",
+		postID, oldTitle, oldContent, oldCategory, userID, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE posts SET title = ?, content = ?, edited_at = ? WHERE id = ?",
+		title, content, now, postID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setPostCategories(tx, postID, categoryIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *postStore) Delete(postID, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRow("SELECT user_id FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return store.ErrNotPostOwner
+	}
+
+	if err := removePost(tx, postID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *postStore) Remove(postID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return store.ErrPostNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := removePost(tx, postID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removePost soft-deletes a post with its comments and removes their reactions
+func removePost(tx *sql.Tx, postID int) error {
+	now := time.Now()
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM reactions WHERE comment_id IN (SELECT id FROM comments WHERE post_id = ?)", []interface{}{postID}},
+		{"DELETE FROM reactions WHERE post_id = ?", []interface{}{postID}},
+		{"UPDATE comments SET deleted_at = ? WHERE post_id = ? AND deleted_at IS NULL", []interface{}{now, postID}},
+		{"UPDATE posts SET deleted_at = ? WHERE id = ?", []interface{}{now, postID}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *postStore) Revisions(postID int) ([]model.Revision, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, store.ErrPostNotFound
+	}
+
+	rows, err := s.db.Query(`
+		SELECT r.id, r.title, r.content, r.category, COALESCE(r.edited_by, 0), COALESCE(u.username, 'Unknown'), r.edited_at
+		FROM post_revisions r
+		LEFT JOIN users u ON u.id = r.edited_by
+		WHERE r.post_id = ?
+		ORDER BY r.id DESC
+	`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		var revision model.Revision
+		var editedAt time.Time
+		err := rows.Scan(&revision.ID, &revision.Title, &revision.Content, &revision.Category, &revision.EditorID, &revision.Editor, &editedAt)
+		if err != nil {
+			return nil, err
+		}
+		revision.EditedAt = editedAt.Format(time.RFC3339)
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *postStore) List(opts post.ListOptions) ([]model.HomePageData, string, error) {
+	after, now, err := post.Prepare(&opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	args := []interface{}{}
+	if opts.Sort == post.SortTop && post.Windows[opts.Window] > 0 {
+		where = append(where, "julianday(p.date) >= julianday(:now) - :days")
+		args = append(args, sql.Named("days", post.Windows[opts.Window]))
+	}
+	if opts.CategoryID > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM post_categories WHERE post_id = p.id AND category_id = :category)")
+		args = append(args, sql.Named("category", opts.CategoryID))
+	}
+	if opts.Author != "" {
+		where = append(where, "u.username = :author COLLATE NOCASE")
+		args = append(args, sql.Named("author", opts.Author))
+	}
+	if len(opts.HiddenAuthors) > 0 {
+		placeholders := make([]string, len(opts.HiddenAuthors))
+		for i, userID := range opts.HiddenAuthors {
+			name := "hidden" + strconv.Itoa(i)
+			placeholders[i] = ":" + name
+			args = append(args, sql.Named(name, userID))
+		}
+		where = append(where, "p.user_id NOT IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	query := `
+		WITH listed AS (
+			SELECT
+				p.id,
+				p.title,
+				p.content,
+				COALESCE(u.username, 'Unknown') AS username,
+				` + postCategoryNames + ` AS category,
+				COALESCE(r.likes, 0) AS likes,
+				COALESCE(r.dislikes, 0) AS dislikes,
+				COALESCE(c.comment_count, 0) AS comment_count,
+				p.date,
+				` + scores[opts.Sort] + ` AS score
+			FROM posts p
+			LEFT JOIN users u ON p.user_id = u.id
+			LEFT JOIN (
+				SELECT post_id,
+					SUM(CASE WHEN type = 'like' THEN 1 ELSE 0 END) AS likes,
+					SUM(CASE WHEN type = 'dislike' THEN 1 ELSE 0 END) AS dislikes
+				FROM reactions
+				WHERE comment_id IS NULL
+				GROUP BY post_id
+			) r ON r.post_id = p.id
+			LEFT JOIN (
+				SELECT post_id, COUNT(*) AS comment_count
+				FROM comments
+				WHERE deleted_at IS NULL
+				GROUP BY post_id
+			) c ON c.post_id = p.id
+			WHERE p.deleted_at IS NULL` + andAll(where) + `
+		)
+		SELECT id, title, content, username, category, likes, dislikes, comment_count, date, score
+		FROM listed`
+	if after != nil {
+		query += " WHERE score < :score OR (score = :score AND id < :id)"
+		args = append(args, sql.Named("score", after.Score), sql.Named("id", after.ID))
+	}
+	query += " ORDER BY score DESC, id DESC LIMIT :limit"
+	args = append(args, sql.Named("now", now.Format("2006-01-02 15:04:05")), sql.Named("limit", opts.Limit+1))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	posts := []model.HomePageData{}
+	var postScores []float64
+	for rows.Next() {
+		var p model.HomePageData
+		var score float64
+		err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Username, &p.Category,
+			&p.Likes, &p.Dislikes, &p.CommentCount, &p.Date, &score)
+		if err != nil {
+			return nil, "", err
+		}
+		posts = append(posts, p)
+		postScores = append(postScores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return post.Page(posts, postScores, opts, now)
+}
+
+func (s *postStore) ListByAuthor(userID int) ([]model.PostData, error) {
+	return s.listPostData("SELECT p.id, p.user_id, p.title, "+postCategoryNames+" FROM posts p WHERE p.user_id = ? AND p.deleted_at IS NULL", userID)
+}
+
+func (s *postStore) ListLikedBy(userID int) ([]model.PostData, error) {
+	return s.listPostData(`
+		SELECT p.id, p.user_id, p.title, `+postCategoryNames+`
+		FROM posts p
+		JOIN reactions r ON p.id = r.post_id
+		WHERE r.user_id = ? AND r.type = 'like' AND p.deleted_at IS NULL
+	`, userID)
+}
+
+func (s *postStore) ListByCategory(categoryID int) ([]model.PostData, error) {
+	return s.listPostData(`
+		SELECT p.id, p.user_id, p.title, `+postCategoryNames+`
+		FROM posts p
+		JOIN post_categories pc ON pc.post_id = p.id
+		WHERE pc.category_id = ? AND p.deleted_at IS NULL
+	`, categoryID)
+}
+
+// listPostData runs a query selecting (id, user_id, title, category names)
+func (s *postStore) listPostData(query string, args ...interface{}) ([]model.PostData, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []model.PostData
+	for rows.Next() {
+		var p model.PostData
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// setPostCategories replaces the categories of a post inside tx
+func setPostCategories(tx *sql.Tx, postID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM post_categories WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	for _, id := range categoryIDs {
+		_, err := tx.Exec("INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?, ?)", postID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"time"
+)
+
+type reportStore struct {
+	db *sql.DB
+}
+
+func (s *reportStore) Create(report store.Report) (int, error) {
+	// Only file the report when the user has no open one on the item
+	result, err := s.db.Exec(`
+		INSERT INTO reports (reporter_id, item_type, item_id, author_id, content, reason, details, created_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reports
+			WHERE reporter_id = ? AND item_type = ? AND item_id = ? AND resolved_at IS NULL
+		)`,
+		report.ReporterID, report.ItemType, report.ItemID, report.AuthorID, report.Content, report.Reason,
+		report.Details, time.Now(), report.ReporterID, report.ItemType, report.ItemID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err := requireAffected(result, store.ErrDuplicateReport); err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *reportStore) Get(reportID int) (store.Report, error) {
+	rows, err := s.db.Query(reportColumns+" WHERE id = ?", reportID)
+	if err != nil {
+		return store.Report{}, err
+	}
+	reports, err := scanReports(rows)
+	if err != nil {
+		return store.Report{}, err
+	}
+	if len(reports) == 0 {
+		return store.Report{}, store.ErrReportNotFound
+	}
+	return reports[0], nil
+}
+
+func (s *reportStore) ListOpen(limit, offset int) ([]store.Report, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM reports WHERE resolved_at IS NULL").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(reportColumns+" WHERE resolved_at IS NULL ORDER BY id LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	reports, err := scanReports(rows)
+	return reports, total, err
+}
+
+func (s *reportStore) ListByReporter(userID int) ([]store.Report, error) {
+	rows, err := s.db.Query(reportColumns+" WHERE reporter_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReports(rows)
+}
+
+func (s *reportStore) Resolve(reportID, moderatorID int, action, note string) ([]store.Report, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var itemType string
+	var itemID int
+	var resolvedAt sql.NullTime
+	err = tx.QueryRow("SELECT item_type, item_id, resolved_at FROM reports WHERE id = ?", reportID).
+		Scan(&itemType, &itemID, &resolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrReportNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		return nil, store.ErrReportResolved
+	}
+
+	// Read the open reports first so that exactly the closed ones are returned
+	rows, err := tx.Query(reportColumns+" WHERE item_type = ? AND item_id = ? AND resolved_at IS NULL ORDER BY id",
+		itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := scanReports(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range reports {
+		_, err := tx.Exec(
+			"UPDATE reports SET action = ?, note = ?, resolved_by = ?, resolved_at = ? WHERE id = ?",
+			action, note, moderatorID, now, reports[i].ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reports[i].Action = action
+		reports[i].Note = note
+		reports[i].ResolvedBy = moderatorID
+		reports[i].ResolvedAt = now
+	}
+
+	return reports, tx.Commit()
+}
+
+// reportColumns selects the columns read by scanReports
+const reportColumns = `SELECT id, reporter_id, item_type, item_id, author_id, content, reason, details, created_at,
+		COALESCE(action, ''), COALESCE(note, ''), COALESCE(resolved_by, 0), resolved_at
+	FROM reports`
+
+// scanReports reads rows selected with reportColumns
+func scanReports(rows *sql.Rows) ([]store.Report, error) {
+	defer rows.Close()
+
+	var reports []store.Report
+	for rows.Next() {
+		var report store.Report
+		var resolvedAt sql.NullTime
+		err := rows.Scan(&report.ID, &report.ReporterID, &report.ItemType, &report.ItemID, &report.AuthorID,
+			&report.Content, &report.Reason, &report.Details, &report.CreatedAt,
+			&report.Action, &report.Note, &report.ResolvedBy, &resolvedAt)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedAt.Valid {
+			report.ResolvedAt = resolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
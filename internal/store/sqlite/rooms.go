@@ -0,0 +1,326 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/model"
+	"forum/internal/store"
+	"math"
+	"time"
+)
+
+type roomStore struct {
+	db *sql.DB
+}
+
+func (s *roomStore) Create(userID int, name string, categoryID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO rooms (name, category_id, created_by) VALUES (?, ?, ?)",
+		name, sql.NullInt64{Int64: int64(categoryID), Valid: categoryID > 0}, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	roomID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO room_members (room_id, user_id, role) VALUES (?, ?, 'owner')",
+		roomID, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(roomID), tx.Commit()
+}
+
+func (s *roomStore) Get(roomID int) (model.Room, error) {
+	var room model.Room
+	var createdAt time.Time
+	err := s.db.QueryRow(
+		`SELECT r.id, r.name, COALESCE(r.category_id, 0), COALESCE(c.name, ''), COALESCE(r.created_by, 0), r.created_at
+		FROM rooms r
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.id = ?`,
+		roomID,
+	).Scan(&room.ID, &room.Name, &room.CategoryID, &room.Category, &room.CreatedBy, &createdAt)
+	if err == sql.ErrNoRows {
+		return model.Room{}, store.ErrRoomNotFound
+	} else if err != nil {
+		return model.Room{}, err
+	}
+	room.CreatedAt = createdAt.Format(time.RFC3339)
+
+	room.Members, err = s.members(roomID)
+	if err != nil {
+		return model.Room{}, err
+	}
+	return room, nil
+}
+
+func (s *roomStore) ListForUser(userID int) ([]model.Room, error) {
+	rows, err := s.db.Query(`
+		SELECT r.id
+		FROM rooms r
+		JOIN room_members m ON m.room_id = r.id
+		WHERE m.user_id = ?
+		ORDER BY COALESCE((SELECT MAX(id) FROM room_messages
+			WHERE room_id = r.id AND (hidden = 0 OR sender_id = m.user_id)), 0) DESC, r.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roomIDs []int
+	for rows.Next() {
+		var roomID int
+		if err := rows.Scan(&roomID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rooms := []model.Room{}
+	for _, roomID := range roomIDs {
+		room, err := s.Get(roomID)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (s *roomStore) IsMember(roomID, userID int) (bool, error) {
+	var count int
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM room_members WHERE room_id = ? AND user_id = ?",
+		roomID, userID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (s *roomStore) MemberIDs(roomID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT user_id FROM room_members WHERE room_id = ?", roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (s *roomStore) Invite(roomID, inviterID, inviteeID int) error {
+	if err := s.requireMember(roomID, inviterID); err != nil {
+		return err
+	}
+
+	isMember, err := s.IsMember(roomID, inviteeID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return store.ErrAlreadyRoomMember
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO room_members (room_id, user_id, role) VALUES (?, ?, 'member')",
+		roomID, inviteeID,
+	)
+	return err
+}
+
+func (s *roomStore) Leave(roomID, userID int) error {
+	if err := s.requireMember(roomID, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM room_members WHERE room_id = ? AND user_id = ?", roomID, userID)
+	if err != nil {
+		return err
+	}
+
+	var remaining, owners int
+	err = tx.QueryRow(
+		"SELECT COUNT(*), COUNT(CASE WHEN role = 'owner' THEN 1 END) FROM room_members WHERE room_id = ?",
+		roomID,
+	).Scan(&remaining, &owners)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case remaining == 0:
+		if _, err := tx.Exec("DELETE FROM room_messages WHERE room_id = ?", roomID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM rooms WHERE id = ?", roomID); err != nil {
+			return err
+		}
+	case owners == 0:
+		_, err = tx.Exec(`
+			UPDATE room_members SET role = 'owner'
+			WHERE room_id = ? AND user_id = (
+				SELECT user_id FROM room_members WHERE room_id = ? ORDER BY joined_at, user_id LIMIT 1
+			)`, roomID, roomID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *roomStore) Rename(roomID, userID int, name string) error {
+	var role string
+	err := s.db.QueryRow(
+		"SELECT role FROM room_members WHERE room_id = ? AND user_id = ?",
+		roomID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return store.ErrNotRoomMember
+	} else if err != nil {
+		return err
+	}
+	if role != "owner" {
+		return store.ErrNotRoomOwner
+	}
+
+	_, err = s.db.Exec("UPDATE rooms SET name = ? WHERE id = ?", name, roomID)
+	return err
+}
+
+func (s *roomStore) AddMessage(roomID, senderID int, content string, hidden bool) (model.RoomMessage, error) {
+	timestamp := time.Now().Format(time.RFC3339)
+
+	result, err := s.db.Exec(
+		"INSERT INTO room_messages (room_id, sender_id, content, timestamp, hidden) VALUES (?, ?, ?, ?, ?)",
+		roomID, senderID, content, timestamp, hidden,
+	)
+	if err != nil {
+		return model.RoomMessage{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return model.RoomMessage{}, err
+	}
+
+	return model.RoomMessage{
+		ID:        int(id),
+		RoomID:    roomID,
+		SenderID:  senderID,
+		Content:   content,
+		Timestamp: timestamp,
+	}, nil
+}
+
+func (s *roomStore) History(roomID, viewerID, beforeID, limit int) ([]model.RoomMessage, bool, error) {
+	if beforeID <= 0 {
+		beforeID = math.MaxInt
+	}
+
+	// Fetch one extra row to know whether another page exists
+	rows, err := s.db.Query(`
+		SELECT m.id, m.room_id, m.sender_id, COALESCE(u.username, 'Unknown'), m.content, m.timestamp
+		FROM room_messages m
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE m.room_id = ? AND m.id < ? AND (m.hidden = 0 OR m.sender_id = ?)
+		ORDER BY m.id DESC
+		LIMIT ?
+	`, roomID, beforeID, viewerID, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	messages := []model.RoomMessage{}
+	for rows.Next() {
+		var msg model.RoomMessage
+		err := rows.Scan(&msg.ID, &msg.RoomID, &msg.SenderID, &msg.Username, &msg.Content, &msg.Timestamp)
+		if err != nil {
+			return nil, false, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Reverse order to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, hasMore, nil
+}
+
+// members lists the members of a room, owner first, then by name
+func (s *roomStore) members(roomID int) ([]model.RoomMember, error) {
+	rows, err := s.db.Query(`
+		SELECT m.user_id, COALESCE(u.username, 'Unknown'), m.role
+		FROM room_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.room_id = ?
+		ORDER BY m.role != 'owner', LOWER(COALESCE(u.username, 'Unknown'))
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.RoomMember{}
+	for rows.Next() {
+		var member model.RoomMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// requireMember returns ErrNotRoomMember unless the user belongs to the room
+func (s *roomStore) requireMember(roomID, userID int) error {
+	isMember, err := s.IsMember(roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return store.ErrNotRoomMember
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/database"
+	"forum/internal/model"
+	"forum/internal/search"
+	"forum/internal/store"
+	"log"
+	"strings"
+	"sync"
+)
+
+// searchIndexes lists the full-text tables created by the search index
+// migration
+var searchIndexes = []string{"posts_fts", "comments_fts", "messages_fts"}
+
+type searchStore struct {
+	db *sql.DB
+
+	once      sync.Once
+	available bool
+}
+
+// Available checks once for FTS5 (build with -tags sqlite_fts5) and the
+// search index, and logs why search stays disabled when either is missing
+func (s *searchStore) Available() bool {
+	s.once.Do(func() {
+		s.available = s.checkIndex()
+	})
+	return s.available
+}
+
+func (s *searchStore) checkIndex() bool {
+	available, err := database.FTS5Available(s.db)
+	if err != nil || !available {
+		log.Println("WARNING: search is disabled because this binary was built without SQLite FTS5; rebuild with -tags sqlite_fts5 to enable it")
+		return false
+	}
+
+	for _, name := range searchIndexes {
+		exists, err := database.TableExists(s.db, name)
+		if err != nil {
+			log.Printf("WARNING: search is disabled, failed to look up the search index: %v", err)
+			return false
+		}
+		if !exists {
+			log.Printf("WARNING: search is disabled because the %s index is missing", name)
+			return false
+		}
+	}
+
+	log.Println("Search index ready")
+	return true
+}
+
+// Search ranks the matches of each scope by bm25
+func (s *searchStore) Search(userID int, input string, scopes []string, limit int, hiddenAuthors []int) (map[string][]model.SearchResult, error) {
+	if !s.Available() {
+		return nil, store.ErrSearchUnavailable
+	}
+	query := search.BuildQuery(input)
+
+	results := make(map[string][]model.SearchResult)
+	for _, scope := range scopes {
+		var found []model.SearchResult
+		var err error
+
+		switch scope {
+		case search.ScopePosts:
+			found, err = s.posts(query, limit, hiddenAuthors)
+		case search.ScopeComments:
+			found, err = s.comments(query, limit, hiddenAuthors)
+		case search.ScopeMessages:
+			found, err = s.messages(userID, query, limit)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[scope] = found
+	}
+
+	return results, nil
+}
+
+// posts matches post titles and contents, weighting titles higher
+func (s *searchStore) posts(query string, limit int, hiddenAuthors []int) ([]model.SearchResult, error) {
+	hidden, hiddenArgs := notIn("p.user_id", hiddenAuthors)
+	args := append([]interface{}{search.MatchStart, search.MatchEnd, query}, hiddenArgs...)
+	return s.collect(`
+		SELECT p.id, p.id, p.title,
+			snippet(posts_fts, -1, ?, ?, '…', 16),
+			COALESCE(u.username, 'Unknown'), p.date, bm25(posts_fts, 10.0, 1.0)
+		FROM posts_fts
+		JOIN posts p ON p.id = posts_fts.rowid
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE posts_fts MATCH ? AND p.deleted_at IS NULL`+hidden+`
+		ORDER BY bm25(posts_fts, 10.0, 1.0)
+		LIMIT ?
+	`, search.ScopePosts, append(args, limit)...)
+}
+
+// comments matches comment contents and links back to their post
+func (s *searchStore) comments(query string, limit int, hiddenAuthors []int) ([]model.SearchResult, error) {
+	hidden, hiddenArgs := notIn("c.user_id", hiddenAuthors)
+	args := append([]interface{}{search.MatchStart, search.MatchEnd, query}, hiddenArgs...)
+	return s.collect(`
+		SELECT c.id, c.post_id, COALESCE(p.title, ''),
+			snippet(comments_fts, 0, ?, ?, '…', 16),
+			COALESCE(u.username, 'Unknown'), p.date, bm25(comments_fts)
+		FROM comments_fts
+		JOIN comments c ON c.id = comments_fts.rowid
+		LEFT JOIN posts p ON p.id = c.post_id
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE comments_fts MATCH ? AND c.deleted_at IS NULL`+hidden+`
+		ORDER BY bm25(comments_fts)
+		LIMIT ?
+	`, search.ScopeComments, append(args, limit)...)
+}
+
+// messages matches private messages the user sent or received
+func (s *searchStore) messages(userID int, query string, limit int) ([]model.SearchResult, error) {
+	return s.collect(`
+		SELECT m.id, 0, COALESCE(u.username, 'Unknown'),
+			snippet(messages_fts, 0, ?, ?, '…', 16),
+			COALESCE(s.username, 'Unknown'), m.timestamp, bm25(messages_fts)
+		FROM messages_fts
+		JOIN private_messages m ON m.id = messages_fts.rowid
+		LEFT JOIN users u ON u.id = CASE WHEN m.sender_id = ? THEN m.receiver_id ELSE m.sender_id END
+		LEFT JOIN users s ON s.id = m.sender_id
+		WHERE messages_fts MATCH ?
+			AND (m.sender_id = ? OR m.receiver_id = ?)
+			AND (m.hidden = 0 OR m.sender_id = ?)
+			AND m.deleted_at IS NULL
+		ORDER BY bm25(messages_fts)
+		LIMIT ?
+	`, search.ScopeMessages, search.MatchStart, search.MatchEnd, userID, query, userID, userID, userID, limit)
+}
+
+// collect scans search rows of the shape (id, post id, title, snippet,
+// username, date, rank) into results of the given type
+func (s *searchStore) collect(query, resultType string, args ...interface{}) ([]model.SearchResult, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []model.SearchResult{}
+	for rows.Next() {
+		result := model.SearchResult{Type: resultType}
+		var date sql.NullString
+		err := rows.Scan(&result.ID, &result.PostID, &result.Title, &result.Snippet, &result.Username, &date, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		result.Date = date.String
+		result.Snippet = search.Highlight(result.Snippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// notIn builds a condition, to be appended to a WHERE clause, excluding rows
+// whose column holds one of ids
+func notIn(column string, ids []int) (string, []interface{}) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return " AND " + column + " NOT IN (?" + strings.Repeat(", ?", len(ids)-1) + ")", args
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"time"
+)
+
+type sessionStore struct {
+	db *sql.DB
+}
+
+func (s *sessionStore) Create(session store.Session) error {
+	_, err := s.db.Exec(
+		"INSERT INTO sessions (session_id, id, created_at, expires_at, user_agent, ip_address, last_seen) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		session.ID, session.UserID, session.CreatedAt, session.ExpiresAt, session.UserAgent, session.IPAddress, session.LastSeen,
+	)
+	return err
+}
+
+func (s *sessionStore) Get(sessionID string) (store.Session, error) {
+	rows, err := s.db.Query(sessionColumns+" WHERE session_id = ?", sessionID)
+	if err != nil {
+		return store.Session{}, err
+	}
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return store.Session{}, err
+	}
+	if len(sessions) == 0 {
+		return store.Session{}, store.ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+func (s *sessionStore) Touch(sessionID string, lastSeen, expiresAt time.Time) error {
+	_, err := s.db.Exec(
+		"UPDATE sessions SET expires_at = ?, last_seen = ? WHERE session_id = ?",
+		expiresAt, lastSeen, sessionID,
+	)
+	return err
+}
+
+func (s *sessionStore) Delete(sessionID string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
+	return err
+}
+
+func (s *sessionStore) ListForUser(userID int) ([]store.Session, error) {
+	rows, err := s.db.Query(sessionColumns+" WHERE id = ? ORDER BY COALESCE(last_seen, created_at) DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessions(rows)
+}
+
+func (s *sessionStore) DeleteExpired(now time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// sessionColumns selects the columns read by scanSessions
+const sessionColumns = `SELECT session_id, id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_seen, expires_at
+	FROM sessions`
+
+// scanSessions reads rows selected with sessionColumns. Sessions that were
+// never used since login report their creation time as last seen.
+func scanSessions(rows *sql.Rows) ([]store.Session, error) {
+	defer rows.Close()
+
+	var sessions []store.Session
+	for rows.Next() {
+		var session store.Session
+		var lastSeen sql.NullTime
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &lastSeen, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		session.LastSeen = session.CreatedAt
+		if lastSeen.Valid {
+			session.LastSeen = lastSeen.Time
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,48 @@
+// Package sqlite implements the forum stores on top of the SQLite schema
+// managed by the database package's migrations.
+package sqlite
+
+import (
+	"database/sql"
+	"forum/internal/store"
+	"strings"
+)
+
+// NewStores returns every store backed by db
+func NewStores(db *sql.DB) store.Stores {
+	return store.Stores{
+		Users:         &userStore{db: db},
+		Posts:         &postStore{db: db},
+		Comments:      &commentStore{db: db},
+		Reactions:     &reactionStore{db: db},
+		Categories:    &categoryStore{db: db},
+		Sessions:      &sessionStore{db: db},
+		Messages:      &messageStore{db: db},
+		Reports:       &reportStore{db: db},
+		Sanctions:     &sanctionStore{db: db},
+		Audit:         &auditStore{db: db},
+		Rooms:         &roomStore{db: db},
+		Search:        &searchStore{db: db},
+		Notifications: &notificationStore{db: db},
+	}
+}
+
+// andAll joins extra WHERE conditions onto an existing clause
+func andAll(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(conditions, " AND ")
+}
+
+// requireAffected returns notFound when a statement matched no row
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
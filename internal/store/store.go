@@ -0,0 +1,382 @@
+// Package store defines the data layer used by the handlers and the chat
+// hub. Each backend (see the sqlite, postgres and memory subpackages)
+// provides every interface so the rest of the forum does not depend on a
+// particular database.
+package store
+
+import (
+	"errors"
+	"forum/internal/model"
+	"forum/internal/post"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrDuplicateUser = errors.New("username or email already taken")
+
+	ErrPostNotFound    = errors.New("post not found")
+	ErrNotPostOwner    = errors.New("only the author can change this post")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrNotCommentOwner = errors.New("only the author can change this comment")
+	ErrItemNotFound    = errors.New("item not found")
+
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrCategoryArchived  = errors.New("category is archived")
+	ErrDuplicateCategory = errors.New("a category with this name already exists")
+
+	ErrReportNotFound  = errors.New("report not found")
+	ErrDuplicateReport = errors.New("you have already reported this")
+	ErrReportResolved  = errors.New("report is already resolved")
+
+	ErrSanctionNotFound = errors.New("sanction not found or already lifted")
+
+	ErrRoomNotFound      = errors.New("room not found")
+	ErrNotRoomMember     = errors.New("not a member of this room")
+	ErrNotRoomOwner      = errors.New("only the room owner can do this")
+	ErrAlreadyRoomMember = errors.New("user is already a member")
+
+	ErrSearchUnavailable = errors.New("search is unavailable")
+)
+
+// Stores bundles one implementation of every store
+type Stores struct {
+	Users         UserStore
+	Posts         PostStore
+	Comments      CommentStore
+	Reactions     ReactionStore
+	Categories    CategoryStore
+	Sessions      SessionStore
+	Messages      MessageStore
+	Reports       ReportStore
+	Sanctions     SanctionStore
+	Audit         AuditStore
+	Rooms         RoomStore
+	Search        SearchStore
+	Notifications NotificationStore
+}
+
+// UserStore keeps registered users. Lookups of a missing user return ErrNotFound.
+type UserStore interface {
+	// Create registers a user with an already hashed password
+	Create(user model.User, passwordHash string) (int, error)
+	EmailExists(email string) (bool, error)
+	UsernameExists(username string) (bool, error)
+	// Credentials returns the ID and password hash of the user whose email
+	// or, failing that, username matches identifier
+	Credentials(identifier string) (int, string, error)
+	Username(userID int) (string, error)
+	IDByUsername(username string) (int, error)
+	// Role returns the role of a user, or an empty string for unknown users
+	Role(userID int) (string, error)
+	SetRole(userID int, role string) error
+	// List returns the ID, username and role of every user
+	List() ([]model.User, error)
+}
+
+// PostStore keeps posts and their edit history. Changes are only allowed to
+// the post's author; deleted posts behave as if they did not exist.
+type PostStore interface {
+	// Create stores a new post filed under the given categories and returns its ID
+	Create(userID int, title, content string, categoryIDs []int) (int, error)
+	// Get loads a post with its author and categories, without reactions or comments
+	Get(postID int) (model.Post, error)
+	// Update changes a post, keeping the previous version as a revision
+	Update(postID, userID int, title, content string, categoryIDs []int) error
+	// Delete soft-deletes a post with its comments and removes their reactions
+	Delete(postID, userID int) error
+	// Remove deletes a post like Delete, whoever wrote it. Moderators use it
+	// to hide reported posts.
+	Remove(postID int) error
+	// Revisions lists the previous versions of a post, newest first
+	Revisions(postID int) ([]model.Revision, error)
+	// List returns one page of posts and the cursor of the next page
+	List(opts post.ListOptions) ([]model.HomePageData, string, error)
+	ListByAuthor(userID int) ([]model.PostData, error)
+	ListLikedBy(userID int) ([]model.PostData, error)
+	ListByCategory(categoryID int) ([]model.PostData, error)
+}
+
+// CommentStore keeps the comments of posts
+type CommentStore interface {
+	// ListForPost returns every comment of a post in posting order, with
+	// reaction counts. Deleted comments are kept with their content removed
+	// so that their replies can still be placed in the thread.
+	ListForPost(postID int) ([]model.Comment, error)
+	// Create adds a comment, optionally as a reply to parentID, and returns its ID
+	Create(userID, postID, parentID int, content string) (int, error)
+	// Get loads a single comment with its post ID, deleted or not, without
+	// reactions or replies
+	Get(commentID int) (model.Comment, error)
+	AuthorID(commentID int) (int, error)
+	// Update changes a comment, keeping the previous version as a revision
+	Update(commentID, userID int, content string) error
+	// Delete soft-deletes a comment and removes its reactions
+	Delete(commentID, userID int) error
+	// Remove deletes a comment like Delete, whoever wrote it. Moderators use
+	// it to hide reported comments.
+	Remove(commentID int) error
+}
+
+// ReactionStore keeps likes and dislikes on posts and comments
+type ReactionStore interface {
+	Counts(itemID int, isComment bool) (likes, dislikes int, err error)
+	// Toggle sets the user's reaction on an item, switching it to the new
+	// type or removing it when the same type is given again
+	Toggle(userID, itemID int, isComment bool, reactionType string) error
+}
+
+// CategoryStore keeps the categories posts are filed under
+type CategoryStore interface {
+	// List returns categories in display order, leaving out archived ones
+	// unless includeArchived is set
+	List(includeArchived bool) ([]model.Category, error)
+	Get(categoryID int) (model.Category, error)
+	// Create adds a category at the end of the list and returns its ID
+	Create(name, description string) (int, error)
+	Update(categoryID int, name, description string) error
+	// Reorder moves the given categories to the top of the list in the given
+	// order; categories left out keep their relative order after them
+	Reorder(categoryIDs []int) error
+	// SetArchived archives or restores a category. Archived categories keep
+	// their posts but can no longer be chosen for new ones.
+	SetArchived(categoryID int, archived bool) error
+	// ValidateIDs checks that every ID belongs to a category that is not archived
+	ValidateIDs(categoryIDs []int) error
+	// DefaultID returns the first category in display order that is not archived
+	DefaultID() (int, error)
+}
+
+// Session is a stored login on one device
+type Session struct {
+	ID        string
+	UserID    int
+	UserAgent string
+	IPAddress string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	ExpiresAt time.Time
+}
+
+// SessionStore keeps login sessions. Lookups of a missing session return ErrNotFound.
+type SessionStore interface {
+	Create(session Session) error
+	Get(sessionID string) (Session, error)
+	// Touch records activity on a session and moves its expiry
+	Touch(sessionID string, lastSeen, expiresAt time.Time) error
+	Delete(sessionID string) error
+	// ListForUser returns every session of a user, most recently used first
+	ListForUser(userID int) ([]Session, error)
+	// DeleteExpired removes the sessions that expired before now and
+	// returns how many were removed
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// MessageStore keeps private messages, read markers and the notifications
+// of messages sent while the receiver was offline
+type MessageStore interface {
+	// Create stores a message and returns it with its ID and timestamp filled
+	// in. Hidden messages are only ever shown to their sender.
+	Create(senderID, receiverID int, content string, hidden bool) (model.PrivateMessage, error)
+	// Get loads a single message, including deleted ones
+	Get(messageID int) (model.PrivateMessage, error)
+	// History returns up to limit messages between userID and partnerID
+	// older than beforeID (or the latest ones when zero), oldest first, as
+	// userID sees them, and reports whether older ones exist
+	History(userID, partnerID, beforeID, limit int) ([]model.PrivateMessage, bool, error)
+	Update(messageID int, content, editedAt string) error
+	// Delete replaces a message with a tombstone and drops its pending notification
+	Delete(messageID int, deletedAt string) error
+	// Conversations lists every other user as a conversation partner, most
+	// recently active first and alphabetically for users never messaged
+	Conversations(userID int) ([]model.Conversation, error)
+	// MarkRead moves the read marker of userID for partnerID forward to
+	// messageID (or the latest message when zero) and returns the marker
+	MarkRead(userID, partnerID, messageID int) (int, error)
+	LastReadID(userID, partnerID int) (int, error)
+	// UnreadCounts returns the unread messages per sender and their total
+	UnreadCounts(userID int) (map[int]int, int, error)
+	AddPendingNotification(userID, senderID, messageID int) error
+	// MissedMessages groups the pending notifications of a user by sender,
+	// most recent sender first
+	MissedMessages(userID int) ([]model.MissedSender, error)
+	ClearPendingNotifications(userID int) error
+}
+
+// NotificationStore keeps the notifications of users who were offline when
+// they happened, as the websocket frames they would have been sent, until
+// their next chat connection
+type NotificationStore interface {
+	Add(userID int, frame []byte) error
+	// Take returns the stored notifications of a user, oldest first, and
+	// removes them
+	Take(userID int) ([][]byte, error)
+}
+
+// Report is a user's complaint about a post, comment or private message.
+// AuthorID and Content are copied from the item when it is reported, so
+// moderators see what was reported even if it changed since.
+type Report struct {
+	ID         int
+	ReporterID int
+	ItemType   string
+	ItemID     int
+	AuthorID   int
+	Content    string
+	Reason     string
+	Details    string
+	CreatedAt  time.Time
+
+	// Set when a moderator resolves the report
+	Action     string
+	Note       string
+	ResolvedBy int
+	ResolvedAt time.Time
+}
+
+// Open reports whether the report still waits for a moderator
+func (r Report) Open() bool {
+	return r.ResolvedAt.IsZero()
+}
+
+// ReportStore keeps reports of abusive content and how moderators resolved
+// them. Lookups of a missing report return ErrReportNotFound.
+type ReportStore interface {
+	// Create files a report and returns its ID. A user has at most one open
+	// report per item; filing another returns ErrDuplicateReport.
+	Create(report Report) (int, error)
+	Get(reportID int) (Report, error)
+	// ListOpen returns up to limit open reports after skipping offset, oldest
+	// first, and how many are open in total
+	ListOpen(limit, offset int) ([]Report, int, error)
+	// ListByReporter returns the reports filed by a user, newest first
+	ListByReporter(userID int) ([]Report, error)
+	// Resolve closes the report and every other open report on the same item
+	// with the given action, returning the reports it closed. Resolving a
+	// closed report returns ErrReportResolved.
+	Resolve(reportID, moderatorID int, action, note string) ([]Report, error)
+}
+
+// Sanction is a measure a moderator took against a user
+type Sanction struct {
+	ID          int
+	UserID      int
+	Kind        string
+	Reason      string
+	ModeratorID int
+	// ReportID is the report that led to the sanction, or zero
+	ReportID  int
+	CreatedAt time.Time
+	// ExpiresAt is zero for sanctions without an end
+	ExpiresAt time.Time
+
+	// Set when a moderator lifts the sanction before it ends
+	LiftedBy int
+	LiftedAt time.Time
+}
+
+// ActiveAt reports whether the sanction applies at the given time
+func (s Sanction) ActiveAt(now time.Time) bool {
+	return s.LiftedAt.IsZero() && (s.ExpiresAt.IsZero() || s.ExpiresAt.After(now))
+}
+
+// SanctionStore keeps the warnings, suspensions, bans and shadowbans given
+// to users
+type SanctionStore interface {
+	// Create records a sanction and returns its ID
+	Create(sanction Sanction) (int, error)
+	// Get loads a sanction, returning ErrSanctionNotFound when it is missing
+	Get(sanctionID int) (Sanction, error)
+	// ListForUser returns the sanctions of a user, newest first
+	ListForUser(userID int) ([]Sanction, error)
+	// Active returns the sanctions of a user that are neither lifted nor
+	// expired at now
+	Active(userID int, now time.Time) ([]Sanction, error)
+	// ActiveUserIDs returns the users with an active sanction of the given kind
+	ActiveUserIDs(kind string, now time.Time) ([]int, error)
+	// Lift ends an active sanction early. Missing or already lifted
+	// sanctions return ErrSanctionNotFound.
+	Lift(sanctionID, moderatorID int) error
+}
+
+// AuditEntry records one privileged action. Before and After are JSON
+// snapshots of the target, empty when it did not exist before or after.
+type AuditEntry struct {
+	ID         int
+	ActorID    int
+	Action     string
+	TargetType string
+	// TargetID is zero for actions on a whole collection, such as
+	// reordering categories
+	TargetID  int
+	Before    string
+	After     string
+	CreatedAt time.Time
+}
+
+// AuditFilter narrows down audit entries. Zero fields match every entry.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	Since      time.Time
+	Until      time.Time
+}
+
+// AuditStore keeps an append-only log of privileged actions. Entries are
+// never changed or removed.
+type AuditStore interface {
+	// Append records an entry and returns its ID. CreatedAt is set when zero.
+	Append(entry AuditEntry) (int, error)
+	// List returns up to limit entries matching filter after skipping offset,
+	// newest first, and how many match in total
+	List(filter AuditFilter, limit, offset int) ([]AuditEntry, int, error)
+	// Each calls fn with every entry matching filter, oldest first, and
+	// stops at the first error fn returns
+	Each(filter AuditFilter, fn func(AuditEntry) error) error
+}
+
+// RoomStore keeps group chat rooms with their members and messages. Members
+// are listed owner first, then by name.
+type RoomStore interface {
+	// Create makes a room owned by userID and returns its ID. A categoryID
+	// of 0 leaves the room without a category.
+	Create(userID int, name string, categoryID int) (int, error)
+	// Get loads a room with its members, returning ErrRoomNotFound when it
+	// is missing
+	Get(roomID int) (model.Room, error)
+	// ListForUser returns the rooms of a user, most recently active first
+	ListForUser(userID int) ([]model.Room, error)
+	IsMember(roomID, userID int) (bool, error)
+	MemberIDs(roomID int) ([]int, error)
+	// Invite adds inviteeID to a room; any member may invite
+	Invite(roomID, inviterID, inviteeID int) error
+	// Leave removes a user from a room. Ownership passes to the longest
+	// standing member when the owner leaves, and an empty room is deleted
+	// with its messages.
+	Leave(roomID, userID int) error
+	// Rename changes the name of a room; only its owner may
+	Rename(roomID, userID int, name string) error
+	// AddMessage stores a room message and returns it with its ID and
+	// timestamp filled in. Hidden messages are only ever shown to their sender.
+	AddMessage(roomID, senderID int, content string, hidden bool) (model.RoomMessage, error)
+	// History returns up to limit messages of a room older than beforeID
+	// (or the latest ones when zero), oldest first, as viewerID sees them,
+	// and reports whether older ones exist
+	History(roomID, viewerID, beforeID, limit int) ([]model.RoomMessage, bool, error)
+}
+
+// SearchStore runs full-text searches over posts, comments and private
+// messages. Scopes are the search package's Scope constants.
+type SearchStore interface {
+	// Available reports whether the backend can search; SQLite needs FTS5
+	// and the search index for it
+	Available() bool
+	// Search matches input, as parsed by search.BuildQuery, against each
+	// requested scope and returns up to limit matches of each, best first.
+	// Messages are limited to the user's own chats, and posts and comments
+	// written by hiddenAuthors are left out. It returns ErrSearchUnavailable
+	// when Available is false.
+	Search(userID int, input string, scopes []string, limit int, hiddenAuthors []int) (map[string][]model.SearchResult, error)
+}
@@ -0,0 +1,90 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/store"
+)
+
+// Roles a user can have, from least to most privileged
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission names an action that only some roles may take
+type Permission string
+
+const (
+	// ModerateContent allows acting on other users' posts, comments and chat
+	ModerateContent Permission = "moderate_content"
+	// ManageCategories allows creating, editing, reordering and archiving categories
+	ManageCategories Permission = "manage_categories"
+	// ManageRoles allows promoting and demoting users
+	ManageRoles Permission = "manage_roles"
+	// BanUsers allows banning users for good
+	BanUsers Permission = "ban_users"
+	// ViewAuditLog allows reading and exporting the log of privileged actions
+	ViewAuditLog Permission = "view_audit_log"
+)
+
+// rolePermissions lists what each role may do beyond what every logged-in
+// user can
+var rolePermissions = map[string][]Permission{
+	RoleUser:      nil,
+	RoleModerator: {ModerateContent},
+	RoleAdmin:     {ModerateContent, ManageCategories, ManageRoles, BanUsers, ViewAuditLog},
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Permissions returns the permissions granted to a role
+func Permissions(role string) []Permission {
+	return append([]Permission{}, rolePermissions[role]...)
+}
+
+// Can reports whether a role grants the given permission
+func Can(role string, permission Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether a user's role grants the given permission.
+// Unknown users have none.
+func HasPermission(users store.UserStore, userID int, permission Permission) (bool, error) {
+	role, err := users.Role(userID)
+	return Can(role, permission), err
+}
+
+// BootstrapAdmin makes the named user an admin unless the forum already has
+// one, so that a new forum can be administered at all. It reports whether
+// the user was promoted.
+func BootstrapAdmin(users store.UserStore, username string) (bool, error) {
+	all, err := users.List()
+	if err != nil {
+		return false, err
+	}
+	for _, u := range all {
+		if u.Role == RoleAdmin {
+			return false, nil
+		}
+	}
+
+	userID, err := users.IDByUsername(username)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, fmt.Errorf("user %q does not exist, register it first", username)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, users.SetRole(userID, RoleAdmin)
+}
@@ -2,24 +2,13 @@ package user
 
 import (
 	"errors"
-	"forum/internal/database"
+	"forum/internal/store"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// EmailExists checks if an email is already registered
-func EmailExists(email string) (bool, error) {
-	var count int
-	err := database.Db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count)
-	return count > 0, err
-}
-
-// UsernameExists checks if a username is already taken
-func UsernameExists(username string) (bool, error) {
-	var count int
-	err := database.Db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count)
-	return count > 0, err
-}
+// ErrInvalidCredentials is returned for an unknown identifier or a wrong password
+var ErrInvalidCredentials = errors.New("invalid credentials")
 
 // HashPassword generates a bcrypt hash for the given password
 func HashPassword(password string) (string, error) {
@@ -27,42 +16,19 @@ func HashPassword(password string) (string, error) {
 	return string(hashed), err
 }
 
-// SaveUser adds a new user to the database
-func SaveUser(username, email, hashedPassword, firstName, lastName, gender string, age int) error {
-	_, err := database.Db.Exec(
-		"INSERT INTO users (username, email, password, first_name, last_name, age, gender) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		username, email, hashedPassword, firstName, lastName, age, gender,
-	)
-	return err
-}
-
-// AuthenticateUser verifies user credentials
-func AuthenticateUser(identifier, password string) (int, error) {
-	var userID int
-	var storedHash string
-	
-	// Try to authenticate with email
-	err := database.Db.QueryRow(
-		"SELECT id, password FROM users WHERE email = ?",
-		identifier,
-	).Scan(&userID, &storedHash)
-	
-	// If email lookup fails, try username
-	if err != nil {
-		err = database.Db.QueryRow(
-			"SELECT id, password FROM users WHERE username = ?",
-			identifier,
-		).Scan(&userID, &storedHash)
-		
-		if err != nil {
-			return 0, err
-		}
+// AuthenticateUser verifies user credentials against the user store
+func AuthenticateUser(users store.UserStore, identifier, password string) (int, error) {
+	userID, storedHash, err := users.Credentials(identifier)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, ErrInvalidCredentials
+	} else if err != nil {
+		return 0, err
 	}
-	
+
 	// Compare passwords
 	if bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)) != nil {
-		return 0, errors.New("invalid credentials")
+		return 0, ErrInvalidCredentials
 	}
-	
+
 	return userID, nil
-}
\ No newline at end of file
+}
@@ -2,7 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
-	"forum/internal/database"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -19,13 +19,26 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// Connection limits, overridden from the configuration at startup
+var (
+	// MaxMessageSize is the largest message a client may send, in bytes
+	MaxMessageSize int64 = 4096
+	// PongWait is how long a connection may stay silent; pings are sent at
+	// 9/10 of it so that a live client always answers in time
+	PongWait = 60 * time.Second
+	// WriteWait is the time allowed to write one message
+	WriteWait = 10 * time.Second
+	// SendBufferSize is how many outgoing messages are queued per connection
+	SendBufferSize = 256
+)
+
 // Connection wraps a websocket connection
 type Connection struct {
 	ws *websocket.Conn
 }
 
 // ServeWs handles websocket connections
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int, username string) {
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int, username, sessionID string) {
 	// Upgrade HTTP to WebSocket
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -36,32 +49,67 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int, usern
 	// Create client and connection
 	conn := &Connection{ws: ws}
 	client := &Client{
-		UserID:   userID,
-		Username: username,
-		Send:     make(chan []byte, 256),
-		Hub:      hub,
-		Conn:     conn,
+		UserID:    userID,
+		Username:  username,
+		SessionID: sessionID,
+		Send:      make(chan []byte, SendBufferSize),
+		Hub:       hub,
+		Conn:      conn,
+
+		limiters:   newConnectionLimiters(),
+		violations: newLimiter(ViolationLimit),
+	}
+
+	// Register with hub, unless it has already been shut down
+	select {
+	case client.Hub.Register <- client:
+	case <-client.Hub.done:
+		conn.sendClose(websocket.CloseServiceRestart, "server restarting")
+		ws.Close()
+		return
 	}
 
-	// Register with hub
-	client.Hub.Register <- client
+	// Let the new connection render its conversation list, badges and what
+	// arrived while the user was away right away
+	sendConversations(client)
+	sendUnreadCounts(client)
+	sendMissedMessages(client)
+	sendStoredNotifications(client)
 
 	// Start read/write processes
 	go client.readPump()
 	go client.writePump()
 }
 
+// closeWithReason sends a close frame with the given reason and closes the
+// underlying connection, which in turn unregisters the client via readPump
+func (c *Connection) closeWithReason(reason string) {
+	c.sendClose(websocket.ClosePolicyViolation, reason)
+	c.ws.Close()
+}
+
+// sendClose starts the closing handshake. The connection stays open until
+// the client answers with its own close frame, which ends readPump.
+func (c *Connection) sendClose(code int, reason string) error {
+	msg := websocket.FormatCloseMessage(code, reason)
+	return c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
 // readPump handles incoming messages
 func (c *Client) readPump() {
 	defer func() {
-		c.Hub.Unregister <- c
+		// Once Shutdown has stopped the hub nobody reads Unregister
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.done:
+		}
 		c.Conn.ws.Close()
 	}()
 
-	c.Conn.ws.SetReadLimit(4096)
-	c.Conn.ws.SetReadDeadline(time.Now().Add(60 * time.Second))
+	c.Conn.ws.SetReadLimit(MaxMessageSize)
+	c.Conn.ws.SetReadDeadline(time.Now().Add(PongWait))
 	c.Conn.ws.SetPongHandler(func(string) error {
-		c.Conn.ws.SetReadDeadline(time.Now().Add(60 * time.Second))
+		c.Conn.ws.SetReadDeadline(time.Now().Add(PongWait))
 		return nil
 	})
 
@@ -71,9 +119,11 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Parse the message
+		// Parse the message. Frames that do not parse still count against
+		// the rate limits.
 		var message Message
-		if err := json.Unmarshal(data, &message); err != nil {
+		err = json.Unmarshal(data, &message)
+		if !c.allow(message.Type) || err != nil {
 			continue
 		}
 
@@ -82,6 +132,14 @@ func (c *Client) readPump() {
 		case "message":
 			handleChatMessage(c, message)
 		case "typing":
+			if message.RoomID > 0 {
+				handleRoomTyping(c, message)
+				continue
+			}
+			// Nobody else learns that a shadowbanned user is typing
+			if c.Hub.shadowbanned(c.UserID) {
+				continue
+			}
 			// Simply forward typing notification with username
 			respMsg := Message{
 				Type:       "typing",
@@ -92,6 +150,13 @@ func (c *Client) readPump() {
 			respData, _ := json.Marshal(respMsg)
 			c.Hub.SendToUser(message.ReceiverID, respData)
 		case "typing_stopped":
+			if message.RoomID > 0 {
+				handleRoomTyping(c, message)
+				continue
+			}
+			if c.Hub.shadowbanned(c.UserID) {
+				continue
+			}
 			// Forward typing stopped notification
 			respMsg := Message{
 				Type:       "typing_stopped",
@@ -104,6 +169,18 @@ func (c *Client) readPump() {
 			handleHistoryRequest(c, message)
 		case "get_more_history":
 			handleMoreHistoryRequest(c, message)
+		case "get_conversations":
+			sendConversations(c)
+		case "mark_read":
+			handleMarkRead(c, message)
+		case "edit_message":
+			handleEditMessage(c, message)
+		case "delete_message":
+			handleDeleteMessage(c, message)
+		case "room_message":
+			handleRoomMessage(c, message)
+		case "room_history":
+			handleRoomHistory(c, message)
 		}
 	}
 }
@@ -113,25 +190,137 @@ func handleChatMessage(c *Client, message Message) {
 	// Set sender and store in database
 	senderID := c.UserID
 	receiverID := message.ReceiverID
-	content := message.Content
-	
-	StoreMessage(senderID, receiverID, content)
+	if err := validateContent(message.Content); err != nil {
+		sendError(c, err.Error())
+		return
+	}
 	
-	// Create response with timestamp
-	timestamp := time.Now().Format(time.RFC3339)
-	responseMsg := Message{
-		Type:       "message",
-		SenderID:   senderID,
-		ReceiverID: receiverID,
-		Content:    content,
-		Timestamp:  timestamp,
-		Username:   c.Username,
+	hidden := c.Hub.shadowbanned(senderID)
+	stored, err := c.Hub.Messages.Create(senderID, receiverID, message.Content, hidden)
+	if err != nil {
+		log.Printf("Error storing message: %v", err)
+		return
 	}
+	responseMsg := messageFromStore(stored)
+	responseMsg.Username = c.Username
 	
-	// Send to both sender and receiver
+	// Send to the receiver and to every connection of the sender
 	respData, _ := json.Marshal(responseMsg)
-	c.Hub.SendToUser(receiverID, respData)
-	c.Send <- respData
+	if hidden {
+		// Only the shadowbanned sender sees the message arrive
+		c.Hub.SendToUser(senderID, respData)
+		pushConversations(c.Hub, senderID)
+		return
+	}
+	if !c.Hub.SendToUser(receiverID, respData) {
+		// Receiver is offline, summarise it for them on their next connect
+		if err := c.Hub.Messages.AddPendingNotification(receiverID, senderID, responseMsg.ID); err != nil {
+			log.Printf("Error recording pending notification: %v", err)
+		}
+	}
+	if senderID != receiverID {
+		c.Hub.SendToUser(senderID, respData)
+	}
+
+	// Move the conversation to the top for both participants
+	pushConversations(c.Hub, receiverID)
+	if senderID != receiverID {
+		pushConversations(c.Hub, senderID)
+	}
+}
+
+// handleEditMessage lets the sender change a recent message and propagates
+// the new content to both participants
+func handleEditMessage(c *Client, message Message) {
+	updated, err := c.Hub.EditMessage(message.ID, c.UserID, message.Content)
+	if err != nil {
+		sendError(c, messageErrorText(err, "Failed to edit message"))
+		return
+	}
+	updated.Type = "message_updated"
+	updated.Username = c.Username
+
+	notifyParticipants(c.Hub, updated)
+}
+
+// handleDeleteMessage lets the sender remove a recent message and propagates
+// the tombstone to both participants
+func handleDeleteMessage(c *Client, message Message) {
+	deleted, err := c.Hub.DeleteMessage(message.ID, c.UserID)
+	if err != nil {
+		sendError(c, messageErrorText(err, "Failed to delete message"))
+		return
+	}
+	deleted.Type = "message_deleted"
+	deleted.Username = c.Username
+
+	notifyParticipants(c.Hub, deleted)
+}
+
+// notifyParticipants sends a message event to both sides of a conversation
+// and refreshes their conversation previews
+func notifyParticipants(hub *Hub, message Message) {
+	data, _ := json.Marshal(message)
+	if !message.Hidden {
+		hub.SendToUser(message.ReceiverID, data)
+		pushConversations(hub, message.ReceiverID)
+	}
+	if message.SenderID != message.ReceiverID || message.Hidden {
+		hub.SendToUser(message.SenderID, data)
+		pushConversations(hub, message.SenderID)
+	}
+}
+
+// messageErrorText turns an edit/delete error into text safe to show the
+// client, logging unexpected ones
+func messageErrorText(err error, fallback string) string {
+	switch {
+	case errors.Is(err, ErrMessageNotFound), errors.Is(err, ErrNotMessageOwner), errors.Is(err, ErrEditWindowOver),
+		errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrMessageTooLong):
+		return err.Error()
+	default:
+		log.Printf("%s: %v", fallback, err)
+		return fallback
+	}
+}
+
+// sendError reports a failed request back to the connection that made it
+func sendError(c *Client, text string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "error",
+		"message": text,
+	})
+
+	select {
+	case c.Send <- data:
+	default:
+	}
+}
+
+// handleMarkRead moves the read marker for a conversation and notifies the
+// partner whose messages were read
+func handleMarkRead(c *Client, message Message) {
+	partnerID := message.ReceiverID
+	if partnerID <= 0 {
+		return
+	}
+
+	lastReadID, err := c.Hub.Messages.MarkRead(c.UserID, partnerID, message.ID)
+	if err != nil {
+		log.Printf("Error marking conversation read: %v", err)
+		return
+	}
+
+	receipt, _ := json.Marshal(ReadReceipt{
+		Type:       "read_receipt",
+		ReaderID:   c.UserID,
+		LastReadID: lastReadID,
+		ReadAt:     time.Now().Format(time.RFC3339),
+	})
+	c.Hub.SendToUser(partnerID, receipt)
+
+	// Keep badges in sync across the reader's other tabs
+	pushConversations(c.Hub, c.UserID)
 }
 
 // handleHistoryRequest gets chat history
@@ -139,65 +328,65 @@ func handleHistoryRequest(c *Client, message Message) {
 	otherUserID := message.ReceiverID
 	
 	// Get message history between users
-	messages, err := GetMessageHistory(c.UserID, otherUserID, 10)
+	messages, hasMore, err := c.Hub.messageHistory(c.UserID, otherUserID, 0, message.Limit)
 	if err != nil {
 		return
 	}
 	
 	// Add usernames to messages
-	for i := range messages {
-		if messages[i].SenderID == c.UserID {
-			messages[i].Username = c.Username
-		} else {
-			var username string
-			database.Db.QueryRow("SELECT username FROM users WHERE id = ?", messages[i].SenderID).Scan(&username)
-			messages[i].Username = username
-		}
-	}
-	
+	addUsernames(c, messages)
+
+	// Let the client show how far the partner has read
+	partnerLastReadID, _ := c.Hub.Messages.LastReadID(otherUserID, c.UserID)
+
 	// Send history to client
 	response, _ := json.Marshal(map[string]interface{}{
-		"type":     "history",
-		"messages": messages,
+		"type":                 "history",
+		"messages":             messages,
+		"has_more":             hasMore,
+		"partner_last_read_id": partnerLastReadID,
 	})
 	
 	c.Send <- response
 }
 
-// handleMoreHistoryRequest gets older messages
+// handleMoreHistoryRequest gets the page of messages before the given ID
 func handleMoreHistoryRequest(c *Client, message Message) {
 	otherUserID := message.ReceiverID
-	before := message.Timestamp
 	
 	// Get older messages
-	messages, err := GetMoreMessageHistory(c.UserID, otherUserID, before, 10)
+	messages, hasMore, err := c.Hub.messageHistory(c.UserID, otherUserID, message.BeforeID, message.Limit)
 	if err != nil {
 		return
 	}
 	
 	// Add usernames 
-	for i := range messages {
-		if messages[i].SenderID == c.UserID {
-			messages[i].Username = c.Username
-		} else {
-			var username string
-			database.Db.QueryRow("SELECT username FROM users WHERE id = ?", messages[i].SenderID).Scan(&username)
-			messages[i].Username = username
-		}
-	}
+	addUsernames(c, messages)
 	
 	// Send response
 	response, _ := json.Marshal(map[string]interface{}{
 		"type":     "more_history",
 		"messages": messages,
+		"has_more": hasMore,
 	})
 	
 	c.Send <- response
 }
 
+// addUsernames fills in the sender name of each history message
+func addUsernames(c *Client, messages []Message) {
+	for i := range messages {
+		if messages[i].SenderID == c.UserID {
+			messages[i].Username = c.Username
+		} else {
+			messages[i].Username, _ = c.Hub.Users.Username(messages[i].SenderID)
+		}
+	}
+}
+
 // writePump sends messages to the client
 func (c *Client) writePump() {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(PongWait * 9 / 10)
 	defer func() {
 		ticker.Stop()
 		c.Conn.ws.Close()
@@ -206,7 +395,7 @@ func (c *Client) writePump() {
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.Conn.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.ws.SetWriteDeadline(time.Now().Add(WriteWait))
 			if !ok {
 				c.Conn.ws.WriteMessage(websocket.CloseMessage, []byte{})
 				return
@@ -231,7 +420,7 @@ func (c *Client) writePump() {
 			
 		case <-ticker.C:
 			// Send ping to keep connection alive
-			c.Conn.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.ws.SetWriteDeadline(time.Now().Add(WriteWait))
 			if err := c.Conn.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"encoding/json"
+	"forum/internal/model"
+	"log"
+)
+
+// previewLength is the maximum number of characters shown for the last message
+const previewLength = 60
+
+// GetConversations lists every other user as a conversation partner, most
+// recently active first and alphabetically for users never messaged, with
+// their online flags filled in from the hub
+func GetConversations(hub *Hub, userID int) ([]model.Conversation, error) {
+	conversations, err := hub.Messages.Conversations(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range conversations {
+		conversations[i].LastMessage = preview(conversations[i].LastMessage)
+		conversations[i].Online = hub.IsUserOnline(conversations[i].UserID)
+	}
+	return conversations, nil
+}
+
+// ReadReceipt tells a sender how far a partner has read their conversation
+type ReadReceipt struct {
+	Type       string `json:"type"`
+	ReaderID   int    `json:"reader_id"`
+	LastReadID int    `json:"last_read_id"`
+	ReadAt     string `json:"read_at"`
+}
+
+// sendUnreadCounts sends the per-conversation unread badges to a connection
+func sendUnreadCounts(c *Client) {
+	counts, total, err := c.Hub.Messages.UnreadCounts(c.UserID)
+	if err != nil {
+		log.Printf("Error loading unread counts for user %d: %v", c.UserID, err)
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":          "unread_counts",
+		"total":         total,
+		"conversations": counts,
+	})
+	c.Send <- data
+}
+
+// conversationsMessage builds the "conversations" websocket message for a user
+func conversationsMessage(hub *Hub, userID int) ([]byte, error) {
+	conversations, err := GetConversations(hub, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type":          "conversations",
+		"conversations": conversations,
+	})
+}
+
+// sendConversations sends the conversation list to a single connection
+func sendConversations(c *Client) {
+	data, err := conversationsMessage(c.Hub, c.UserID)
+	if err != nil {
+		log.Printf("Error loading conversations for user %d: %v", c.UserID, err)
+		return
+	}
+	c.Send <- data
+}
+
+// pushConversations sends the refreshed conversation list to every
+// connection of a user
+func pushConversations(hub *Hub, userID int) {
+	data, err := conversationsMessage(hub, userID)
+	if err != nil {
+		log.Printf("Error loading conversations for user %d: %v", userID, err)
+		return
+	}
+	hub.SendToUser(userID, data)
+}
+
+// preview shortens a message for display in the conversation list
+func preview(content string) string {
+	runes := []rune(content)
+	if len(runes) <= previewLength {
+		return content
+	}
+	return string(runes[:previewLength]) + "…"
+}
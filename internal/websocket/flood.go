@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"encoding/json"
+	"forum/internal/ratelimit"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Limit allows Count messages every Period. A zero Count means no limit.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// Limits caps the messages of one class a client may send: each connection
+// gets PerConnection, and all connections of a user share PerUser
+type Limits struct {
+	PerConnection Limit
+	PerUser       Limit
+}
+
+// Flood protection, overridden from the configuration at startup
+var (
+	// ChatLimits covers sending, editing and deleting messages
+	ChatLimits = Limits{Limit{10, 10 * time.Second}, Limit{20, 10 * time.Second}}
+	// TypingLimits covers typing notifications
+	TypingLimits = Limits{Limit{20, 10 * time.Second}, Limit{40, 10 * time.Second}}
+	// RequestLimits covers everything else, such as loading history
+	RequestLimits = Limits{Limit{30, 10 * time.Second}, Limit{60, 10 * time.Second}}
+	// ViolationLimit is how many throttled messages a connection may send
+	// before it is closed
+	ViolationLimit = Limit{10, time.Minute}
+)
+
+// messageClass groups the incoming message types that share limits
+type messageClass int
+
+const (
+	classChat messageClass = iota
+	classTyping
+	classRequest
+	classCount
+)
+
+// messageClasses sorts incoming message types into classes; anything else,
+// including frames that do not parse, counts as a request
+var messageClasses = map[string]messageClass{
+	"message":        classChat,
+	"edit_message":   classChat,
+	"delete_message": classChat,
+	"room_message":   classChat,
+	"typing":         classTyping,
+	"typing_stopped": classTyping,
+}
+
+// limitsOf returns the configured limits of each class
+func limitsOf() [classCount]Limits {
+	return [classCount]Limits{
+		classChat:    ChatLimits,
+		classTyping:  TypingLimits,
+		classRequest: RequestLimits,
+	}
+}
+
+// newLimiter builds a limiter for one limit, nil when there is none
+func newLimiter(limit Limit) *ratelimit.Limiter {
+	return ratelimit.NewLimiter(limit.Count, limit.Period)
+}
+
+// newUserLimiters builds the limiters shared by all connections of a user,
+// keyed by user ID
+func newUserLimiters() [classCount]*ratelimit.Limiter {
+	var limiters [classCount]*ratelimit.Limiter
+	for class, limits := range limitsOf() {
+		limiters[class] = newLimiter(limits.PerUser)
+	}
+	return limiters
+}
+
+// newConnectionLimiters builds the limiters of a single connection
+func newConnectionLimiters() [classCount]*ratelimit.Limiter {
+	var limiters [classCount]*ratelimit.Limiter
+	for class, limits := range limitsOf() {
+		limiters[class] = newLimiter(limits.PerConnection)
+	}
+	return limiters
+}
+
+// allow reports whether a message of the given type is within the limits of
+// the connection and its user. A throttled message is answered with an
+// error event; a connection that keeps sending them is closed.
+func (c *Client) allow(messageType string) bool {
+	class, ok := messageClasses[messageType]
+	if !ok {
+		class = classRequest
+	}
+
+	allowed, wait := c.limiters[class].Allow("")
+	if allowed {
+		allowed, wait = c.Hub.userLimiters[class].Allow(strconv.Itoa(c.UserID))
+		if !allowed {
+			// The message is dropped, so it must not use up the budget of
+			// this connection while the user's other connections are busy
+			c.limiters[class].Refund("")
+		}
+	}
+	if allowed {
+		return true
+	}
+
+	if ok, _ := c.violations.Allow(""); !ok {
+		log.Printf("Closing a connection of user %d for flooding", c.UserID)
+		c.Conn.closeWithReason("too many messages")
+		return false
+	}
+	sendRateLimited(c, messageType, wait)
+	return false
+}
+
+// sendRateLimited tells the client that a message was dropped and how many
+// seconds to wait before sending more of its kind
+func sendRateLimited(c *Client, messageType string, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        "error",
+		"code":        "rate_limited",
+		"message":     "You are sending messages too fast, please slow down",
+		"request":     messageType,
+		"retry_after": seconds,
+	})
+
+	select {
+	case c.Send <- data:
+	default:
+	}
+}
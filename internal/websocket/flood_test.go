@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"forum/internal/model"
+	"forum/internal/store/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// useLimits replaces the flood limits for the duration of a test. Hubs and
+// clients pick them up when they are created.
+func useLimits(t *testing.T, chat, typing, request Limits, violations Limit) {
+	t.Helper()
+	savedChat, savedTyping, savedRequest, savedViolations := ChatLimits, TypingLimits, RequestLimits, ViolationLimit
+	ChatLimits, TypingLimits, RequestLimits, ViolationLimit = chat, typing, request, violations
+	t.Cleanup(func() {
+		ChatLimits, TypingLimits, RequestLimits, ViolationLimit = savedChat, savedTyping, savedRequest, savedViolations
+	})
+}
+
+// newTestHub starts a hub on in-memory stores holding one user, alice
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	stores := memory.NewStores()
+	if _, err := stores.Users.Create(model.User{Username: "alice", Email: "alice@example.com"}, "hash"); err != nil {
+		t.Fatalf("creating alice: %v", err)
+	}
+
+	hub := NewHub(stores.Users, stores.Messages, stores.Rooms, stores.Sanctions, stores.Notifications)
+	go hub.Run()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx, "test finished")
+	})
+	return hub
+}
+
+// newTestClient builds a connection of a user that is not backed by a
+// socket, which is enough as long as it is not closed
+func newTestClient(hub *Hub, userID int) *Client {
+	return &Client{
+		UserID:     userID,
+		Send:       make(chan []byte, SendBufferSize),
+		Hub:        hub,
+		limiters:   newConnectionLimiters(),
+		violations: newLimiter(ViolationLimit),
+	}
+}
+
+// rateLimitReply is the error event sent for a throttled message
+type rateLimitReply struct {
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Request    string `json:"request"`
+	RetryAfter int    `json:"retry_after"`
+}
+
+// rateLimitReplies decodes the rate_limited events among frames, which may
+// each hold several newline separated messages
+func rateLimitReplies(t *testing.T, frames [][]byte) []rateLimitReply {
+	t.Helper()
+	var replies []rateLimitReply
+	for _, frame := range frames {
+		for _, line := range strings.Split(string(frame), "\n") {
+			var reply rateLimitReply
+			if err := json.Unmarshal([]byte(line), &reply); err != nil {
+				t.Fatalf("decoding %q: %v", line, err)
+			}
+			if reply.Code == "rate_limited" {
+				replies = append(replies, reply)
+			}
+		}
+	}
+	return replies
+}
+
+// sent returns the frames queued for a test client
+func sent(c *Client) [][]byte {
+	var frames [][]byte
+	for {
+		select {
+		case frame := <-c.Send:
+			frames = append(frames, frame)
+		default:
+			return frames
+		}
+	}
+}
+
+func TestFloodLimitsPerClass(t *testing.T) {
+	twoPerHour := Limits{PerConnection: Limit{2, time.Hour}}
+	for _, test := range []struct {
+		name        string
+		limits      *Limits
+		messageType string
+		otherType   string
+	}{
+		{"chat", &ChatLimits, "edit_message", "typing"},
+		{"typing", &TypingLimits, "typing_stopped", "message"},
+		{"request", &RequestLimits, "get_history", "typing"},
+		{"unknown types", &RequestLimits, "dance", "message"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			useLimits(t, Limits{}, Limits{}, Limits{}, Limit{})
+			*test.limits = twoPerHour
+			c := newTestClient(newTestHub(t), 1)
+
+			for i := 1; i <= 2; i++ {
+				if !c.allow(test.messageType) {
+					t.Fatalf("message %d was throttled", i)
+				}
+			}
+			if c.allow(test.messageType) {
+				t.Fatal("a message over the limit was allowed")
+			}
+			if !c.allow(test.otherType) {
+				t.Errorf("a %s message was throttled by the limit of another class", test.otherType)
+			}
+
+			replies := rateLimitReplies(t, sent(c))
+			if len(replies) != 1 {
+				t.Fatalf("got %d rate_limited replies, want 1", len(replies))
+			}
+			// Two messages an hour earn a token back every half hour
+			want := rateLimitReply{Type: "error", Code: "rate_limited", Request: test.messageType, RetryAfter: 1800}
+			if replies[0] != want {
+				t.Errorf("reply is %+v, want %+v", replies[0], want)
+			}
+		})
+	}
+}
+
+func TestFloodLimitPerUser(t *testing.T) {
+	useLimits(t, Limits{PerConnection: Limit{2, time.Hour}, PerUser: Limit{2, time.Hour}}, Limits{}, Limits{}, Limit{})
+	hub := newTestHub(t)
+	busy, idle := newTestClient(hub, 1), newTestClient(hub, 1)
+	other := newTestClient(hub, 2)
+
+	for i := 1; i <= 2; i++ {
+		if !busy.allow("message") {
+			t.Fatalf("message %d of the busy tab was throttled", i)
+		}
+	}
+	if idle.allow("message") {
+		t.Fatal("the user limit did not cover the second tab")
+	}
+	if replies := rateLimitReplies(t, sent(idle)); len(replies) != 1 || replies[0].RetryAfter != 1800 {
+		t.Errorf("the second tab was sent %+v, want one rate_limited reply", replies)
+	}
+	if !other.allow("message") {
+		t.Error("another user was throttled")
+	}
+
+	// The dropped message did not use up the idle tab's own budget
+	for i := 1; i <= 2; i++ {
+		if ok, _ := idle.limiters[classChat].Allow(""); !ok {
+			t.Errorf("token %d of the idle tab was used up", i)
+		}
+	}
+}
+
+func TestFloodClosesConnection(t *testing.T) {
+	useLimits(t, Limits{}, Limits{}, Limits{PerConnection: Limit{1, time.Hour}}, Limit{2, time.Hour})
+	hub := newTestHub(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r, 1, "alice", "session")
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	defer conn.Close()
+
+	send := func() {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"get_conversations"}`)); err != nil {
+			t.Fatalf("sending a request: %v", err)
+		}
+	}
+	// next reads frames until a rate_limited reply or the end of the
+	// connection, which it returns
+	next := func() (bool, error) {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			_, frame, err := conn.ReadMessage()
+			if err != nil {
+				return false, err
+			}
+			if len(rateLimitReplies(t, [][]byte{frame})) > 0 {
+				return true, nil
+			}
+		}
+	}
+
+	// One allowed request, then two throttled ones
+	send()
+	for i := 1; i <= 2; i++ {
+		send()
+		if limited, err := next(); !limited {
+			t.Fatalf("throttled request %d got no rate_limited reply: %v", i, err)
+		}
+	}
+
+	// One throttled request too many closes the connection
+	send()
+	_, err = next()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "too many messages" {
+		t.Fatalf("connection ended with %v, want a close for flooding", err)
+	}
+}
@@ -1,16 +1,23 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"forum/internal/moderation"
+	"forum/internal/ratelimit"
+	"forum/internal/store"
 	"log"
 	"sync"
-	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // Hub maintains all active client connections
 type Hub struct {
-	// Registered clients by user ID
-	Clients map[int]*Client
+	// Registered connections grouped by user ID; a user may have several
+	// open tabs or devices at once
+	Clients map[int]map[*Client]bool
 	
 	// Client registration channel
 	Register chan *Client
@@ -18,63 +25,118 @@ type Hub struct {
 	// Client unregistration channel
 	Unregister chan *Client
 	
+	// Stores used to look up users, keep private and room messages, check
+	// for shadowbans and hold notifications for offline users
+	Users         store.UserStore
+	Messages      store.MessageStore
+	Rooms         store.RoomStore
+	Sanctions     store.SanctionStore
+	Notifications store.NotificationStore
+
+	// Rate limits per message class shared by all connections of a user
+	userLimiters [classCount]*ratelimit.Limiter
+
+	// Closed by Shutdown to stop Run
+	done chan struct{}
+
 	// Mutex for thread-safety
 	mutex sync.Mutex
 }
 
-// Client represents a connected user
+// Client represents a single websocket connection of a user
 type Client struct {
-	UserID   int
-	Username string
-	Send     chan []byte
-	Hub      *Hub
-	Conn     *Connection
+	UserID    int
+	Username  string
+	SessionID string
+	Send      chan []byte
+	Hub       *Hub
+	Conn      *Connection
+
+	// Rate limits per message class of this connection, and how many
+	// throttled messages it may still send before it is closed
+	limiters   [classCount]*ratelimit.Limiter
+	violations *ratelimit.Limiter
 }
 
 // NewHub creates a new hub for managing clients
-func NewHub() *Hub {
+func NewHub(users store.UserStore, messages store.MessageStore, rooms store.RoomStore, sanctions store.SanctionStore,
+	notifications store.NotificationStore) *Hub {
 	return &Hub{
-		Clients:    make(map[int]*Client),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
+		Clients:       make(map[int]map[*Client]bool),
+		Register:      make(chan *Client),
+		Unregister:    make(chan *Client),
+		Users:         users,
+		Messages:      messages,
+		Rooms:         rooms,
+		Sanctions:     sanctions,
+		Notifications: notifications,
+		done:          make(chan struct{}),
+
+		userLimiters: newUserLimiters(),
 	}
 }
 
-// Run starts the hub and handles client events
+// Run starts the hub and handles client events until Shutdown
 func (h *Hub) Run() {
 	log.Println("Starting WebSocket hub")
 	for {
 		select {
+		case <-h.done:
+			log.Println("WebSocket hub stopped")
+			return
+
 		case client := <-h.Register:
 			h.mutex.Lock()
-			h.Clients[client.UserID] = client
+			connections, online := h.Clients[client.UserID]
+			if !online {
+				connections = make(map[*Client]bool)
+				h.Clients[client.UserID] = connections
+			}
+			connections[client] = true
 			h.mutex.Unlock()
-			h.broadcastUserList()
+
+			if online {
+				// Other users already see this user; only the new tab needs the list
+				h.sendUserList(client)
+			} else {
+				h.broadcastUserList()
+				go h.pushPresence(client.UserID)
+			}
 			
 		case client := <-h.Unregister:
 			h.mutex.Lock()
-			if _, ok := h.Clients[client.UserID]; ok {
-				delete(h.Clients, client.UserID)
+			wentOffline := false
+			if connections, ok := h.Clients[client.UserID]; ok && connections[client] {
+				delete(connections, client)
 				close(client.Send)
+				if len(connections) == 0 {
+					delete(h.Clients, client.UserID)
+					wentOffline = true
+				}
 			}
 			h.mutex.Unlock()
-			h.broadcastUserList()
+
+			// Only announce the user as offline once their last connection closes
+			if wentOffline {
+				h.broadcastUserList()
+				go h.pushPresence(client.UserID)
+			}
 		}
 	}
 }
 
-// broadcastUserList sends the updated user list to all clients
-func (h *Hub) broadcastUserList() {
-    h.mutex.Lock()
-    defer h.mutex.Unlock()
-    
+// userListMessage builds the user_list message for the currently online users
+func (h *Hub) userListMessage() ([]byte, error) {
     // Prepare user list for sending
     var users []map[string]interface{}
-    for userID, client := range h.Clients {
-        users = append(users, map[string]interface{}{
-            "id":       userID,
-            "username": client.Username,
-        })
+    for userID, connections := range h.Clients {
+        for client := range connections {
+            users = append(users, map[string]interface{}{
+                "id":       userID,
+                "username": client.Username,
+            })
+            break
+        }
     }
 
     // Create message object
@@ -84,7 +146,15 @@ func (h *Hub) broadcastUserList() {
     }
 
     // Convert to JSON
-    data, err := json.Marshal(message)
+    return json.Marshal(message)
+}
+
+// broadcastUserList sends the updated user list to all clients
+func (h *Hub) broadcastUserList() {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    data, err := h.userListMessage()
     if err != nil {
         log.Printf("Error creating user list: %v", err)
         return
@@ -94,36 +164,171 @@ func (h *Hub) broadcastUserList() {
     h.broadcastMessage(data)
 }
 
+// sendUserList sends the current user list to a single connection
+func (h *Hub) sendUserList(client *Client) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    data, err := h.userListMessage()
+    if err != nil {
+        log.Printf("Error creating user list: %v", err)
+        return
+    }
+
+    select {
+    case client.Send <- data:
+    default:
+    }
+}
+
 // broadcastMessage sends a message to all connected clients
 func (h *Hub) broadcastMessage(message []byte) {
-    for _, client := range h.Clients {
-		client.Send <- message
-		fmt.Println(string(message))
+    for _, connections := range h.Clients {
+        for client := range connections {
+            select {
+            case client.Send <- message:
+            default:
+            }
+        }
     }
 }
 
-// SendToUser sends a message to a specific user
+// SendToUser sends a message to every connection of a specific user and
+// reports whether at least one of them received it
 func (h *Hub) SendToUser(userID int, message []byte) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	
-	client, exists := h.Clients[userID]
-	if !exists {
-		return false
+	delivered := false
+	for client := range h.Clients[userID] {
+		select {
+		case client.Send <- message:
+			delivered = true
+		default:
+		}
 	}
-	
-	select {
-	case client.Send <- message:
-		return true
-	default:
-		return false
+	return delivered
+}
+
+// pushPresence refreshes the conversation lists of everyone else online
+// after a user came online or went offline, so that their online flags stay
+// current
+func (h *Hub) pushPresence(userID int) {
+	for _, onlineID := range h.onlineUserIDs() {
+		if onlineID != userID {
+			pushConversations(h, onlineID)
+		}
+	}
+}
+
+// onlineUserIDs returns the users with at least one open connection
+func (h *Hub) onlineUserIDs() []int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	userIDs := make([]int, 0, len(h.Clients))
+	for userID := range h.Clients {
+		userIDs = append(userIDs, userID)
 	}
+	return userIDs
 }
 
-// IsUserOnline checks if a user is currently connected
+// SendToUsers sends a message to every connection of each of the given users
+func (h *Hub) SendToUsers(userIDs []int, message []byte) {
+	for _, userID := range userIDs {
+		h.SendToUser(userID, message)
+	}
+}
+
+// IsUserOnline checks if a user has at least one open connection
 func (h *Hub) IsUserOnline(userID int) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
-	_, exists := h.Clients[userID]
-	return exists
-}
\ No newline at end of file
+	return len(h.Clients[userID]) > 0
+}
+
+// DisconnectSession closes every connection opened with the given session
+func (h *Hub) DisconnectSession(sessionID string) {
+	for _, client := range h.clientsWhere(func(c *Client) bool { return c.SessionID == sessionID }) {
+		client.Conn.closeWithReason("session revoked")
+	}
+}
+
+// DisconnectUser closes every connection of a user, telling the client why
+func (h *Hub) DisconnectUser(userID int, reason string) {
+	for _, client := range h.clientsWhere(func(c *Client) bool { return c.UserID == userID }) {
+		client.Conn.closeWithReason(reason)
+	}
+}
+
+// clientsWhere returns the connected clients that match. Closing connections
+// blocks on the network, so it is done on the result rather than while
+// holding the mutex that registration and broadcasts need.
+func (h *Hub) clientsWhere(match func(c *Client) bool) []*Client {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var clients []*Client
+	for _, connections := range h.Clients {
+		for client := range connections {
+			if match(client) {
+				clients = append(clients, client)
+			}
+		}
+	}
+	return clients
+}
+
+// shadowbanned reports whether what a user sends should be hidden from
+// everyone else. Failing to check lets the message through.
+func (h *Hub) shadowbanned(userID int) bool {
+	sanctions, err := h.Sanctions.Active(userID, time.Now())
+	if err != nil {
+		log.Printf("Error checking sanctions of user %d: %v", userID, err)
+		return false
+	}
+	return moderation.Shadowbanned(sanctions)
+}
+
+// Shutdown asks every client to close its connection with the given reason
+// and waits until all of them have gone. Connections still open when ctx
+// ends are dropped. The hub stops running afterwards.
+func (h *Hub) Shutdown(ctx context.Context, reason string) error {
+	all := func(c *Client) bool { return true }
+	for _, client := range h.clientsWhere(all) {
+		client.Conn.sendClose(websocket.CloseServiceRestart, reason)
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var err error
+	for err == nil && h.connectionCount() > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	if err != nil {
+		for _, client := range h.clientsWhere(all) {
+			client.Conn.ws.Close()
+		}
+	}
+
+	close(h.done)
+	return err
+}
+
+// connectionCount returns the number of open connections of all users
+func (h *Hub) connectionCount() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	count := 0
+	for _, connections := range h.Clients {
+		count += len(connections)
+	}
+	return count
+}
@@ -1,8 +1,36 @@
 package websocket
 
 import (
-	"forum/internal/database"
+	"errors"
+	"forum/internal/model"
+	"forum/internal/store"
+	"strings"
 	"time"
+	"unicode/utf8"
+)
+
+// Page sizes of chat history
+const (
+	// HistoryPageSize is the number of messages returned per history page
+	// when the client does not ask for a specific size
+	HistoryPageSize = 10
+	// MaxHistoryPageSize caps the page size a client may request
+	MaxHistoryPageSize = 50
+)
+
+// MaxContentLength is the longest chat message, in characters
+const MaxContentLength = 2000
+
+// MessageEditWindow is how long after sending a message its sender may still
+// edit or delete it, overridden from the configuration at startup
+var MessageEditWindow = 15 * time.Minute
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotMessageOwner = errors.New("only the sender can change this message")
+	ErrEditWindowOver  = errors.New("this message can no longer be changed")
+	ErrEmptyMessage    = errors.New("message cannot be empty")
+	ErrMessageTooLong  = errors.New("message is too long")
 )
 
 // Message represents a chat message
@@ -11,114 +39,151 @@ type Message struct {
 	ID         int    `json:"id,omitempty"`
 	SenderID   int    `json:"sender_id,omitempty"`
 	ReceiverID int    `json:"receiverID"` 
+	RoomID     int    `json:"room_id,omitempty"`
 	Content    string `json:"content,omitempty"`
 	Timestamp  string `json:"timestamp,omitempty"`
 	Username   string `json:"username,omitempty"`
+	EditedAt   string `json:"edited_at,omitempty"`
+	Deleted    bool   `json:"deleted,omitempty"`
+	BeforeID   int    `json:"before_id,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	// Hidden messages of shadowbanned users only reach their sender
+	Hidden bool `json:"-"`
+}
+
+// messageFromStore converts a stored private message to its chat form
+func messageFromStore(msg model.PrivateMessage) Message {
+	return Message{
+		Type:       "message",
+		ID:         msg.ID,
+		SenderID:   msg.SenderID,
+		ReceiverID: msg.ReceiverID,
+		Content:    msg.Content,
+		Timestamp:  msg.Timestamp,
+		EditedAt:   msg.EditedAt,
+		Deleted:    msg.Deleted,
+		Hidden:     msg.Hidden,
+	}
 }
 
-// StoreMessage saves a message to the database
-func StoreMessage(senderID, receiverID int, content string) error {
-	timestamp := time.Now().Format(time.RFC3339)
-	
-	_, err := database.Db.Exec(
-		"INSERT INTO private_messages (sender_id, receiver_id, content, timestamp) VALUES (?, ?, ?, ?)",
-		senderID, receiverID, content, timestamp,
-	)
-	
-	return err
+// validateContent checks the content of a private or room message
+func validateContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(content) > MaxContentLength {
+		return ErrMessageTooLong
+	}
+	return nil
 }
 
-// GetMessageHistory retrieves message history between two users
-func GetMessageHistory(userID1, userID2 int, limit int) ([]Message, error) {
+// pageSize clamps a requested page size to the configured bounds
+func pageSize(limit int) int {
 	if limit <= 0 {
-		limit = 10
-	}
-	
-	// Get messages between the two users
-	rows, err := database.Db.Query(`
-		SELECT sender_id, receiver_id, content, timestamp
-		FROM private_messages
-		WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)
-		ORDER BY timestamp DESC
-		LIMIT ?
-	`, userID1, userID2, userID2, userID1, limit)
-	
+		return HistoryPageSize
+	}
+	if limit > MaxHistoryPageSize {
+		return MaxHistoryPageSize
+	}
+	return limit
+}
+
+// messageHistory loads the messages between a user and a partner older than
+// beforeID (or the latest ones when beforeID is zero), oldest first, as the
+// user sees them, and reports whether older ones exist
+func (h *Hub) messageHistory(userID, partnerID, beforeID, limit int) ([]Message, bool, error) {
+	stored, hasMore, err := h.Messages.History(userID, partnerID, beforeID, pageSize(limit))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	defer rows.Close()
 
-	var messages []Message
-	for rows.Next() {
-		var msg Message
-		var timestamp string
-		err := rows.Scan(&msg.SenderID, &msg.ReceiverID, &msg.Content, &timestamp)
-		if err != nil {
-			continue
-		}
-		msg.Type = "message"
-		msg.Timestamp = timestamp
-		messages = append(messages, msg)
+	messages := make([]Message, 0, len(stored))
+	for _, msg := range stored {
+		messages = append(messages, messageFromStore(msg))
 	}
+	return messages, hasMore, nil
+}
 
-	// Reverse order to show oldest first
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+// EditMessage replaces the content of a message sent by senderID within the
+// edit window and returns the updated message
+func (h *Hub) EditMessage(messageID, senderID int, content string) (Message, error) {
+	if err := validateContent(content); err != nil {
+		return Message{}, err
 	}
 
-	return messages, nil
+	msg, err := h.changeableMessage(messageID, senderID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	editedAt := time.Now().Format(time.RFC3339)
+	if err := h.Messages.Update(messageID, content, editedAt); err != nil {
+		return Message{}, err
+	}
+
+	msg.Content = content
+	msg.EditedAt = editedAt
+	return msg, nil
 }
 
-// GetMoreMessageHistory loads older messages
-func GetMoreMessageHistory(userID1, userID2 int, before string, limit int) ([]Message, error) {
-	if limit <= 0 {
-		limit = 10
-	}
-	
-	// Parse timestamp or use current time
-	var beforeTime time.Time
-	if before != "" {
-		var err error
-		beforeTime, err = time.Parse(time.RFC3339, before)
-		if err != nil {
-			beforeTime = time.Now()
-		}
-	} else {
-		beforeTime = time.Now()
-	}
-	
-	// Get messages before the timestamp
-	rows, err := database.Db.Query(`
-		SELECT sender_id, receiver_id, content, timestamp
-		FROM private_messages
-		WHERE ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))
-		AND timestamp < ?
-		ORDER BY timestamp DESC
-		LIMIT ?
-	`, userID1, userID2, userID2, userID1, beforeTime.Format(time.RFC3339), limit)
-	
+// DeleteMessage replaces a message sent by senderID within the edit window
+// with a tombstone and returns it
+func (h *Hub) DeleteMessage(messageID, senderID int) (Message, error) {
+	msg, err := h.changeableMessage(messageID, senderID)
 	if err != nil {
-		return nil, err
+		return Message{}, err
 	}
-	defer rows.Close()
 
-	var messages []Message
-	for rows.Next() {
-		var msg Message
-		var timestamp string
-		err := rows.Scan(&msg.SenderID, &msg.ReceiverID, &msg.Content, &timestamp)
-		if err != nil {
-			continue
-		}
-		msg.Type = "message"
-		msg.Timestamp = timestamp
-		messages = append(messages, msg)
+	if err := h.Messages.Delete(messageID, time.Now().Format(time.RFC3339)); err != nil {
+		return Message{}, err
 	}
 
-	// Reverse order to show oldest first
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	msg.Content = ""
+	msg.Deleted = true
+	return msg, nil
+}
+
+// RemoveMessage replaces any message with a tombstone on behalf of a
+// moderator and tells both participants
+func (h *Hub) RemoveMessage(messageID int) error {
+	stored, err := h.Messages.Get(messageID)
+	if err == store.ErrNotFound || stored.Deleted {
+		return ErrMessageNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := h.Messages.Delete(messageID, time.Now().Format(time.RFC3339)); err != nil {
+		return err
 	}
 
-	return messages, nil
-}
\ No newline at end of file
+	msg := messageFromStore(stored)
+	msg.Type = "message_deleted"
+	msg.Content = ""
+	msg.Deleted = true
+	msg.Username, _ = h.Users.Username(msg.SenderID)
+	notifyParticipants(h, msg)
+	return nil
+}
+
+// changeableMessage loads a message and checks that senderID may still edit
+// or delete it
+func (h *Hub) changeableMessage(messageID, senderID int) (Message, error) {
+	stored, err := h.Messages.Get(messageID)
+	if err == store.ErrNotFound || stored.Deleted {
+		return Message{}, ErrMessageNotFound
+	} else if err != nil {
+		return Message{}, err
+	}
+
+	if stored.SenderID != senderID {
+		return Message{}, ErrNotMessageOwner
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, stored.Timestamp)
+	if err != nil || time.Since(sentAt) > MessageEditWindow {
+		return Message{}, ErrEditWindowOver
+	}
+
+	return messageFromStore(stored), nil
+}
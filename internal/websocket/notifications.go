@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// sendMissedMessages pushes the summary of messages received while offline to
+// a new connection and clears them once delivered
+func sendMissedMessages(c *Client) {
+	senders, err := c.Hub.Messages.MissedMessages(c.UserID)
+	if err != nil {
+		log.Printf("Error loading missed messages for user %d: %v", c.UserID, err)
+		return
+	}
+	if len(senders) == 0 {
+		return
+	}
+
+	total := 0
+	for i := range senders {
+		senders[i].LatestPreview = preview(senders[i].LatestPreview)
+		total += senders[i].Count
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "missed_messages",
+		"total":   total,
+		"senders": senders,
+	})
+	c.Send <- data
+
+	if err := c.Hub.Messages.ClearPendingNotifications(c.UserID); err != nil {
+		log.Printf("Error clearing missed messages for user %d: %v", c.UserID, err)
+	}
+}
+
+// notify sends a notification to every connection of a user, or stores it
+// for their next connection when they have none
+func (h *Hub) notify(userID int, data []byte) {
+	if h.SendToUser(userID, data) {
+		return
+	}
+	if err := h.Notifications.Add(userID, data); err != nil {
+		log.Printf("Error storing a notification for user %d: %v", userID, err)
+	}
+}
+
+// sendStoredNotifications pushes the notifications stored while the user was
+// offline to a new connection. Those that do not fit in its send buffer are
+// stored again for the next one.
+func sendStoredNotifications(c *Client) {
+	frames, err := c.Hub.Notifications.Take(c.UserID)
+	if err != nil {
+		log.Printf("Error loading stored notifications for user %d: %v", c.UserID, err)
+		return
+	}
+	for i, frame := range frames {
+		select {
+		case c.Send <- frame:
+		default:
+			for _, rest := range frames[i:] {
+				if err := c.Hub.Notifications.Add(c.UserID, rest); err != nil {
+					log.Printf("Error storing a notification for user %d: %v", c.UserID, err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// PushCommentReply tells a comment's author that someone replied to it, on
+// every open connection or on their next one
+func PushCommentReply(hub *Hub, authorID, postID, commentID, replyID int, replierName string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":       "comment_reply",
+		"post_id":    postID,
+		"comment_id": commentID,
+		"reply_id":   replyID,
+		"username":   replierName,
+	})
+	hub.notify(authorID, data)
+}
+
+// PushReportResolved tells a reporter that a moderator handled their report,
+// now or on their next connection
+func PushReportResolved(hub *Hub, reporterID, reportID int, action string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":      "report_resolved",
+		"report_id": reportID,
+		"action":    action,
+	})
+	hub.notify(reporterID, data)
+}
+
+// PushWarning shows a user the warning a moderator gave them, now or on
+// their next connection
+func PushWarning(hub *Hub, userID int, reason string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":   "moderation_warning",
+		"reason": reason,
+	})
+	hub.notify(userID, data)
+}
@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"encoding/json"
+	"forum/internal/model"
+	"log"
+)
+
+// roomMessageEvent is a room message as delivered to clients
+type roomMessageEvent struct {
+	Type string `json:"type"`
+	model.RoomMessage
+}
+
+// handleRoomMessage stores a room message and fans it out to every online member
+func handleRoomMessage(c *Client, message Message) {
+	if !requireRoomMember(c, message.RoomID) {
+		return
+	}
+	if err := validateContent(message.Content); err != nil {
+		sendError(c, err.Error())
+		return
+	}
+
+	hidden := c.Hub.shadowbanned(c.UserID)
+	stored, err := c.Hub.Rooms.AddMessage(message.RoomID, c.UserID, message.Content, hidden)
+	if err != nil {
+		log.Printf("Error storing room message: %v", err)
+		return
+	}
+	stored.Username = c.Username
+
+	data, _ := json.Marshal(roomMessageEvent{Type: "room_message", RoomMessage: stored})
+	if hidden {
+		c.Hub.SendToUser(c.UserID, data)
+		return
+	}
+	sendToRoom(c.Hub, message.RoomID, data, 0)
+}
+
+// handleRoomHistory sends a page of room messages before the given ID
+func handleRoomHistory(c *Client, message Message) {
+	if !requireRoomMember(c, message.RoomID) {
+		return
+	}
+
+	messages, hasMore, err := c.Hub.Rooms.History(message.RoomID, c.UserID, message.BeforeID, pageSize(message.Limit))
+	if err != nil {
+		log.Printf("Error loading room history: %v", err)
+		return
+	}
+
+	response, _ := json.Marshal(map[string]interface{}{
+		"type":     "room_history",
+		"room_id":  message.RoomID,
+		"messages": messages,
+		"has_more": hasMore,
+	})
+	c.Send <- response
+}
+
+// handleRoomTyping forwards typing notifications to the other members of a room
+func handleRoomTyping(c *Client, message Message) {
+	if !requireRoomMember(c, message.RoomID) || c.Hub.shadowbanned(c.UserID) {
+		return
+	}
+
+	respData, _ := json.Marshal(Message{
+		Type:     message.Type,
+		SenderID: c.UserID,
+		RoomID:   message.RoomID,
+		Username: c.Username,
+	})
+	sendToRoom(c.Hub, message.RoomID, respData, c.UserID)
+}
+
+// PushRoomUpdate tells every member of a room about its current name and
+// membership, e.g. after an invite, rename or leave
+func PushRoomUpdate(hub *Hub, roomID int) {
+	r, err := hub.Rooms.Get(roomID)
+	if err != nil {
+		// The room is gone once its last member leaves
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "room_updated",
+		"room": r,
+	})
+	sendToRoom(hub, roomID, data, 0)
+}
+
+// PushRoomLeft tells a user's connections that they are no longer in a room
+func PushRoomLeft(hub *Hub, roomID, userID int) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "room_left",
+		"room_id": roomID,
+	})
+	hub.SendToUser(userID, data)
+}
+
+// sendToRoom delivers a message to all members of a room except skipUserID
+func sendToRoom(hub *Hub, roomID int, data []byte, skipUserID int) {
+	memberIDs, err := hub.Rooms.MemberIDs(roomID)
+	if err != nil {
+		log.Printf("Error loading members of room %d: %v", roomID, err)
+		return
+	}
+
+	recipients := memberIDs[:0]
+	for _, userID := range memberIDs {
+		if userID != skipUserID {
+			recipients = append(recipients, userID)
+		}
+	}
+	hub.SendToUsers(recipients, data)
+}
+
+// requireRoomMember checks room membership for a websocket request
+func requireRoomMember(c *Client, roomID int) bool {
+	if roomID <= 0 {
+		return false
+	}
+
+	isMember, err := c.Hub.Rooms.IsMember(roomID, c.UserID)
+	if err != nil {
+		log.Printf("Error checking membership of room %d: %v", roomID, err)
+		return false
+	}
+	return isMember
+}
@@ -1,63 +1,209 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"forum/internal/config"
 	"forum/internal/database"
 	"forum/internal/handler"
+	"forum/internal/ratelimit"
 	"forum/internal/session"
+	"forum/internal/store"
+	"forum/internal/store/postgres"
+	"forum/internal/store/sqlite"
+	"forum/internal/user"
+	"forum/internal/websocket"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 )
 
 func main() {
-	// Initialize the database
-	initializeDatabase()
-	defer database.Db.Close()
-	
+	// "forum migrate ..." manages the schema without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
+	dryRun := flag.Bool("dry-run", false, "print the migrations startup would apply, then exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	cfg.Print(log.Writer())
+	slog.SetLogLoggerLevel(cfg.LogLevel)
+
+	if *dryRun {
+		if cfg.DB != "sqlite" {
+			log.Fatalf("-dry-run only checks the SQLite database, not %s", cfg.DB)
+		}
+		database.Connect(cfg.SQLiteDSN)
+		if err := database.Migrate(true); err != nil {
+			log.Fatalf("Failed to check migrations: %v", err)
+		}
+		database.Db.Close()
+		return
+	}
+
+	// Give the handlers access to the data layer
+	stores, closeStores, err := openStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open the %s store: %v", cfg.DB, err)
+	}
+	// Check for the search index now, logging at startup why it is missing
+	stores.Search.Available()
+	handler.Init(stores, cfg.SessionTTL)
+	handler.Sessions.CleanupExpiredSessions()
+	handler.AccountLockout = ratelimit.NewLockout(cfg.LoginMaxFailures, cfg.LoginLockout, cfg.LoginMaxLockout)
+	handler.IPLockout = ratelimit.NewLockout(cfg.LoginMaxIPFailures, cfg.LoginLockout, cfg.LoginMaxLockout)
+	if cfg.BootstrapAdmin != "" {
+		bootstrapAdmin(stores, cfg.BootstrapAdmin)
+	}
+
 	// Initialize the WebSocket hub
+	websocket.MaxMessageSize = cfg.WSMaxMessageSize
+	websocket.PongWait = cfg.WSPongWait
+	websocket.WriteWait = cfg.WSWriteWait
+	websocket.SendBufferSize = cfg.WSSendBuffer
+	websocket.MessageEditWindow = cfg.WSMessageEditWindow
+	websocket.ChatLimits = websocket.Limits{
+		PerConnection: websocket.Limit(cfg.WSRateChat),
+		PerUser:       websocket.Limit(cfg.WSRateChatUser),
+	}
+	websocket.TypingLimits = websocket.Limits{
+		PerConnection: websocket.Limit(cfg.WSRateTyping),
+		PerUser:       websocket.Limit(cfg.WSRateTypingUser),
+	}
+	websocket.RequestLimits = websocket.Limits{
+		PerConnection: websocket.Limit(cfg.WSRateRequest),
+		PerUser:       websocket.Limit(cfg.WSRateRequestUser),
+	}
+	websocket.ViolationLimit = websocket.Limit(cfg.WSMaxViolations)
 	handler.InitWebSocketHub()
 	log.Println("WebSocket hub initialized")
-	
+
+	// Run until SIGTERM (e.g. a container restart) or Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	// Set up cleanup routine for sessions
-	go sessionCleanupRoutine()
-	
+	cleanupDone := make(chan struct{})
+	go func() {
+		sessionCleanupRoutine(ctx, handler.Sessions, cfg.SessionCleanupInterval)
+		close(cleanupDone)
+	}()
+
 	// Set up routes and start server
-	startServer()
+	server := startServer(cfg)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- listen(server, cfg)
+	}()
+
+	failed := false
+	select {
+	case err := <-serverErr:
+		log.Printf("Server failed: %v", err)
+		failed = true
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	}
+	// A second signal from here on kills the process right away
+	stop()
+
+	shutdown(server, cfg.ShutdownTimeout)
+	<-cleanupDone
+
+	closeStores()
+	log.Println("Server stopped")
+
+	if failed {
+		os.Exit(1)
+	}
 }
 
-// Periodically clean up expired sessions
-func sessionCleanupRoutine() {
-	ticker := time.NewTicker(30 * time.Minute)
+// Periodically clean up expired sessions until ctx is done
+func sessionCleanupRoutine(ctx context.Context, sessions *session.Manager, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	
 	for {
 		select {
 		case <-ticker.C:
-			session.CleanupExpiredSessions()
+			sessions.CleanupExpiredSessions()
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
-func startServer() {
+// startServer registers the routes and returns the server to run them
+func startServer(cfg config.Config) *http.Server {
 	// Serve static files
-	serveStaticFiles()
+	serveStaticFiles(cfg.StaticDir)
 	
 	// Register auth handlers
-	http.HandleFunc("/register", handler.RegisterHandler)
-	http.HandleFunc("/login", handler.LoginHandler)
+	http.HandleFunc("/register", rateLimit(cfg.RateLimitRegister, handler.RegisterHandler))
+	http.HandleFunc("/login", rateLimit(cfg.RateLimitLogin, handler.LoginHandler))
 	http.HandleFunc("/logout", handler.LogoutHandler)
 	
 	// Register content handlers
-	http.HandleFunc("/createPost", handler.CreatePostHandler)
-	http.HandleFunc("/comment", handler.CommentHandler)
-	http.HandleFunc("/like", handler.LikeHandler)
+	http.HandleFunc("/posts", handler.ListPostsHandler)
+	http.HandleFunc("/createPost", rateLimit(cfg.RateLimitPost, handler.CreatePostHandler))
+	http.HandleFunc("/comment", rateLimit(cfg.RateLimitComment, handler.CommentHandler))
+	http.HandleFunc("/like", rateLimit(cfg.RateLimitLike, handler.LikeHandler))
 	http.HandleFunc("/filter", handler.FilterHandler)
 	http.HandleFunc("/post", handler.ViewPostHandler)
+	http.HandleFunc("/post/edit", handler.EditPostHandler)
+	http.HandleFunc("/post/delete", handler.DeletePostHandler)
+	http.HandleFunc("/post/revisions", handler.PostRevisionsHandler)
+	http.HandleFunc("/comment/edit", handler.EditCommentHandler)
+	http.HandleFunc("/comment/delete", handler.DeleteCommentHandler)
+	http.HandleFunc("/search", handler.SearchHandler)
+	http.HandleFunc("/categories", handler.CategoriesHandler)
+	http.HandleFunc("/report", handler.ReportHandler)
+	http.HandleFunc("/reports", handler.ReportsHandler)
 	
 	// Register user handlers
 	http.HandleFunc("/user/status", handler.UserStatusHandler)
 	http.HandleFunc("/user/all", handler.GetAllUsersHandler)
+	http.HandleFunc("/user/sessions", handler.UserSessionsHandler)
+	http.HandleFunc("/user/sessions/revoke", handler.RevokeSessionHandler)
+	http.HandleFunc("/chat/conversations", handler.ConversationsHandler)
+
+	// Register admin handlers
+	manageCategories := func(next http.HandlerFunc) http.HandlerFunc {
+		return handler.RequirePermission(user.ManageCategories, next)
+	}
+	http.HandleFunc("/admin/categories/create", manageCategories(handler.CreateCategoryHandler))
+	http.HandleFunc("/admin/categories/update", manageCategories(handler.UpdateCategoryHandler))
+	http.HandleFunc("/admin/categories/reorder", manageCategories(handler.ReorderCategoriesHandler))
+	http.HandleFunc("/admin/categories/archive", manageCategories(handler.ArchiveCategoryHandler))
+	http.HandleFunc("/admin/users", handler.RequirePermission(user.ManageRoles, handler.AdminUsersHandler))
+	http.HandleFunc("/admin/users/role", handler.RequirePermission(user.ManageRoles, handler.SetRoleHandler))
+	http.HandleFunc("/admin/audit", handler.RequirePermission(user.ViewAuditLog, handler.AuditLogHandler))
+	http.HandleFunc("/admin/audit/export", handler.RequirePermission(user.ViewAuditLog, handler.ExportAuditLogHandler))
+
+	// Register moderation handlers
+	http.HandleFunc("/moderation/reports", handler.RequirePermission(user.ModerateContent, handler.ModerationQueueHandler))
+	http.HandleFunc("/moderation/reports/resolve", handler.RequirePermission(user.ModerateContent, handler.ResolveReportHandler))
+	http.HandleFunc("/moderation/sanctions", handler.RequirePermission(user.ModerateContent, handler.SanctionsHandler))
+	http.HandleFunc("/moderation/sanctions/create", handler.RequirePermission(user.ModerateContent, handler.SanctionUserHandler))
+	http.HandleFunc("/moderation/sanctions/lift", handler.RequirePermission(user.ModerateContent, handler.LiftSanctionHandler))
+
+	// Register chat room handlers
+	http.HandleFunc("/rooms", handler.RoomsHandler)
+	http.HandleFunc("/rooms/create", handler.CreateRoomHandler)
+	http.HandleFunc("/rooms/invite", handler.InviteRoomHandler)
+	http.HandleFunc("/rooms/leave", handler.LeaveRoomHandler)
+	http.HandleFunc("/rooms/rename", handler.RenameRoomHandler)
 	
 	// WebSocket endpoint
 	http.HandleFunc("/ws", logRequest(handler.WebSocketHandler))
@@ -70,7 +216,7 @@ func startServer() {
 						r.Header.Get("X-Requested-With") == "XMLHttpRequest" ||
 						r.URL.Query().Get("api") == "true"
 		
-		log.Printf("Root path request: %s, isAPIRequest: %v", r.URL.Path, isAPIRequest)
+		slog.Debug("Root path request", "path", r.URL.Path, "api", isAPIRequest)
 		
 		if r.URL.Path == "/" && isAPIRequest {
 			// If JSON is requested, use the HomeHandler
@@ -84,28 +230,113 @@ func startServer() {
 		}
 	})
 	
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	return &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      handler.RequireCSRFToken(http.DefaultServeMux),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
 }
 
-func initializeDatabase() {
-	log.Println("Initializing database...")
-	database.InitDB()
-	session.CleanupExpiredSessions()
-	log.Println("Database initialization complete")
+// listen serves HTTP or, with a certificate configured, HTTPS until the
+// server fails or is shut down
+func listen(server *http.Server, cfg config.Config) error {
+	var err error
+	if cfg.TLSCert != "" {
+		log.Printf("Server starting on %s (HTTPS)", cfg.Addr)
+		err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	} else {
+		log.Printf("Server starting on %s", cfg.Addr)
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func serveStaticFiles() {
+// shutdown stops accepting connections, lets open requests finish and asks
+// websocket clients to reconnect later. Requests and websocket connections
+// each get timeout to finish before they are dropped.
+func shutdown(server *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Hijacked websocket connections are not tracked by the server, the hub
+	// closes them
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server did not stop cleanly: %v", err)
+	}
+
+	// Slow HTTP requests must not eat into the time websocket clients get
+	// to close
+	hubCtx, hubCancel := context.WithTimeout(context.Background(), timeout)
+	defer hubCancel()
+	if err := handler.WebSocketHub.Shutdown(hubCtx, "server restarting"); err != nil {
+		log.Printf("WebSocket connections did not close cleanly: %v", err)
+	}
+}
+
+// bootstrapAdmin gives a new forum its first admin. It only logs problems
+// because the user may not have registered yet.
+func bootstrapAdmin(stores store.Stores, username string) {
+	promoted, err := user.BootstrapAdmin(stores.Users, username)
+	switch {
+	case err != nil:
+		log.Printf("Could not make %s an admin: %v", username, err)
+	case promoted:
+		log.Printf("Made %s the first admin", username)
+	default:
+		log.Printf("Not making %s an admin, the forum already has one", username)
+	}
+}
+
+// openStores returns the data layer selected with -db, migrated to the
+// latest schema, and a function that releases it. Only the selected
+// database is opened.
+func openStores(cfg config.Config) (store.Stores, func(), error) {
+	switch cfg.DB {
+	case "sqlite":
+		log.Println("Initializing database...")
+		database.InitDB(cfg.SQLiteDSN)
+		log.Println("Database initialization complete")
+		return sqlite.NewStores(database.Db), func() {
+			if err := database.Db.Close(); err != nil {
+				log.Printf("Error closing database: %v", err)
+			}
+		}, nil
+	case "postgres":
+		db, err := postgres.Open(cfg.PostgresDSN)
+		if err != nil {
+			return store.Stores{}, nil, err
+		}
+		if err := postgres.Migrate(db); err != nil {
+			db.Close()
+			return store.Stores{}, nil, err
+		}
+		log.Println("PostgreSQL connected successfully")
+		return postgres.NewStores(db), func() { db.Close() }, nil
+	}
+	return store.Stores{}, nil, fmt.Errorf("unknown backend %q, use sqlite or postgres", cfg.DB)
+}
+
+func serveStaticFiles(dir string) {
 	// Serve static files (CSS, JS, images)
-	fs := http.FileServer(http.Dir("./web/static"))
+	fs := http.FileServer(http.Dir(dir))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
 	log.Println("Static file server initialized")
 }
 
+// rateLimit limits the requests each client IP address makes to a route
+func rateLimit(rate config.Rate, next http.HandlerFunc) http.HandlerFunc {
+	return handler.RateLimit(ratelimit.NewLimiter(rate.Count, rate.Period), next)
+}
+
 // Middleware for logging requests
 func logRequest(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL)
+		slog.Debug("Request", "remote", r.RemoteAddr, "method", r.Method, "url", r.URL.String())
 		next(w, r)
 	}
 }
\ No newline at end of file
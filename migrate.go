@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"forum/internal/config"
+	"forum/internal/database"
+	"log"
+	"os"
+)
+
+// runMigrateCommand handles "forum migrate <status|up|down>":
+//
+//	forum migrate status              list applied and pending migrations
+//	forum migrate up [-dry-run]       apply pending migrations
+//	forum migrate down [-to N | -steps N] [-dry-run]
+//	                                  roll back to version N, or N steps
+func runMigrateCommand(args []string) {
+	if len(args) == 0 {
+		migrateUsage()
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only print what would change")
+	to := flags.Int("to", -1, "version to roll back to")
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	// The server settings are accepted too, so that -sqlite-dsn and -config
+	// select the database to migrate
+	cfg, err := config.Load(flags, args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if cfg.DB != "sqlite" {
+		log.Fatalf("forum migrate only manages the SQLite database; the %s database is migrated when the server starts", cfg.DB)
+	}
+
+	database.Connect(cfg.SQLiteDSN)
+	defer database.Db.Close()
+
+	switch args[0] {
+	case "status":
+		err = printMigrationStatus()
+	case "up":
+		err = database.Migrate(*dryRun)
+	case "down":
+		var version int
+		version, err = database.SchemaVersion()
+		if err != nil {
+			break
+		}
+		target := version - *steps
+		if *to >= 0 {
+			target = *to
+		}
+		err = database.Rollback(target, *dryRun)
+	default:
+		migrateUsage()
+	}
+
+	if err != nil {
+		log.Fatalf("Migration command failed: %v", err)
+	}
+}
+
+// printMigrationStatus lists the applied migrations and the pending ones
+func printMigrationStatus() error {
+	applied, err := database.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+	pending, err := database.PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range applied {
+		fmt.Printf("%4d  applied %s  %s\n", migration.Version, migration.AppliedAt.Format("2006-01-02 15:04:05"), migration.Name)
+	}
+	for _, migration := range pending {
+		fmt.Printf("%4d  pending                      %s\n", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+func migrateUsage() {
+	fmt.Fprintln(os.Stderr, "usage: forum migrate status")
+	fmt.Fprintln(os.Stderr, "       forum migrate up [-dry-run]")
+	fmt.Fprintln(os.Stderr, "       forum migrate down [-to version | -steps n] [-dry-run]")
+	os.Exit(2)
+}
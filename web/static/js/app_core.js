@@ -4,8 +4,11 @@
 const state = {
   sessionID: null,
   username: null,
+  // Sent with every request that changes state, see checkLogin
+  csrfToken: null,
   posts: [],
   currentPost: null,
+  categories: [],
 };
 
 // Make state available globally
@@ -23,7 +26,8 @@ document.addEventListener("DOMContentLoaded", async function () {
     }
   });
 
-  // Check login status and set up page
+  // Load categories, check login status and set up page
+  await loadCategories();
   await checkLogin();
   setupNavigationEvents();
   loadCurrentPage();
@@ -42,6 +46,7 @@ async function checkLogin() {
     // Update state
     state.sessionID = data.sessionID;
     state.username = data.username;
+    state.csrfToken = data.csrfToken;
     updateUI();
 
     // Handle WebSocket connection based on login status change
@@ -62,6 +67,17 @@ async function checkLogin() {
   }
 }
 
+// Load the active categories for the sidebar and the post form
+async function loadCategories() {
+  try {
+    const response = await fetch("/categories");
+    const data = await response.json();
+    state.categories = data.categories || [];
+  } catch (error) {
+    console.error("Failed to load categories:", error);
+  }
+}
+
 // Update UI elements after login status change
 function updateUI() {
   // Update auth box
@@ -79,7 +95,8 @@ function updateUI() {
 
   // Update sidebar
   document.getElementById("sidebar").innerHTML = window.templates.sidebar(
-    state.sessionID
+    state.sessionID,
+    state.categories
   );
 
   // Update chat sidebar
@@ -92,6 +109,9 @@ function updateUI() {
       if (window.chatUI && window.chatUI.fetchAllUsers) {
         setTimeout(window.chatUI.fetchAllUsers, 100);
       }
+      if (window.chatRooms && window.chatRooms.setupSidebar) {
+        window.chatRooms.setupSidebar();
+      }
     } else {
       chatSidebar.innerHTML = ""; // Empty for non-logged-in users
     }
@@ -103,7 +123,10 @@ async function handleLogout(event) {
   event.preventDefault();
 
   try {
-    const response = await fetch("/logout", { method: "POST" });
+    const response = await fetch("/logout", {
+      method: "POST",
+      headers: { "X-CSRF-Token": state.csrfToken },
+    });
     if (response.ok) {
       state.sessionID = null;
       state.username = null;
@@ -200,6 +223,9 @@ function loadCurrentPage() {
     if (window.chatUI && window.chatUI.fetchAllUsers) {
       setTimeout(window.chatUI.fetchAllUsers, 100);
     }
+    if (window.chatRooms && window.chatRooms.setupSidebar) {
+      window.chatRooms.setupSidebar();
+    }
   }
 }
 
@@ -8,6 +8,7 @@ async function submitLogin(event) {
   try {
     const response = await fetch("/login", {
       method: "POST",
+      headers: { "X-CSRF-Token": window.state.csrfToken },
       body: formData,
     });
 
@@ -17,6 +18,7 @@ async function submitLogin(event) {
       // Login successful
       window.state.sessionID = data.sessionID;
       window.state.username = data.username;
+      window.state.csrfToken = data.csrfToken;
 
       // Dispatch an event to notify other modules about the state update
       window.dispatchEvent(new Event("stateUpdated"));
@@ -79,6 +81,7 @@ async function submitRegister(event) {
   try {
     const response = await fetch("/register", {
       method: "POST",
+      headers: { "X-CSRF-Token": window.state.csrfToken },
       body: formData,
     });
 
@@ -115,6 +118,7 @@ async function submitPost(event) {
   try {
     const response = await fetch("/createPost", {
       method: "POST",
+      headers: { "X-CSRF-Token": window.state.csrfToken },
       body: formData,
     });
 
@@ -153,6 +157,7 @@ async function submitComment(event) {
   try {
     const response = await fetch("/comment", {
       method: "POST",
+      headers: { "X-CSRF-Token": window.state.csrfToken },
       body: formData,
     });
 
@@ -212,6 +217,7 @@ async function submitReaction(button) {
   try {
     const response = await fetch("/like", {
       method: "POST",
+      headers: { "X-CSRF-Token": window.state.csrfToken },
       body: formData,
     });
 
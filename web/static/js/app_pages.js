@@ -1,9 +1,9 @@
 // app-pages.js - Page loading functions for Forum SPA
 
-// Load the home page with all posts
-async function loadHomePage() {
+// Load the home page with the first page of posts
+async function loadHomePage(sort = "top") {
   try {
-    const response = await fetch("/?api=true", {
+    const response = await fetch(`/?api=true&sort=${encodeURIComponent(sort)}`, {
       headers: {
         Accept: "application/json",
         "X-Requested-With": "XMLHttpRequest",
@@ -16,11 +16,15 @@ async function loadHomePage() {
 
     const data = await response.json();
     window.state.posts = data.Posts || [];
+    window.state.postSort = sort;
 
     document.getElementById("content").innerHTML = window.templates.homePage(
-      window.state.posts
+      window.state.posts,
+      sort,
+      data.NextCursor
     );
     setupReactionButtons();
+    setupPostListing();
   } catch (error) {
     console.error("Error:", error);
     document.getElementById("content").innerHTML = window.templates.error(
@@ -29,6 +33,62 @@ async function loadHomePage() {
   }
 }
 
+// Set up the sort selector and the "Load more" button of the home page
+function setupPostListing() {
+  const sortSelect = document.getElementById("post-sort");
+  if (sortSelect) {
+    sortSelect.addEventListener("change", () => loadHomePage(sortSelect.value));
+  }
+
+  setupLoadMoreButton();
+}
+
+// Set up the "Load more" button, if there is another page
+function setupLoadMoreButton() {
+  const loadMoreButton = document.getElementById("load-more-posts");
+  if (loadMoreButton) {
+    loadMoreButton.addEventListener("click", () =>
+      loadMorePosts(loadMoreButton.dataset.cursor)
+    );
+  }
+}
+
+// Append the next page of posts to the home page
+async function loadMorePosts(cursor) {
+  try {
+    const params = new URLSearchParams({
+      sort: window.state.postSort || "top",
+      cursor,
+    });
+    const response = await fetch(`/posts?${params}`, {
+      headers: { Accept: "application/json" },
+    });
+
+    if (!response.ok) {
+      throw new Error("Failed to load more posts");
+    }
+
+    const data = await response.json();
+    const posts = data.posts || [];
+    window.state.posts = window.state.posts.concat(posts);
+
+    document
+      .getElementById("post-list")
+      .insertAdjacentHTML(
+        "beforeend",
+        posts.map((post) => window.templates.postCard(post)).join("")
+      );
+
+    document.getElementById("load-more-posts").outerHTML =
+      window.templates.loadMorePosts(data.next_cursor);
+
+    setupReactionButtons();
+    setupLoadMoreButton();
+  } catch (error) {
+    console.error("Error:", error);
+  }
+}
+
 // Load a single post page
 async function loadPostPage(postId) {
   try {
@@ -146,7 +206,7 @@ function showRegisterPage() {
 // Show create post page
 function showCreatePostPage() {
   document.getElementById("content").innerHTML =
-    window.templates.createPostForm();
+    window.templates.createPostForm(window.state.categories);
 
   const createPostForm = document.getElementById("create-post-form");
   if (createPostForm) {
@@ -174,6 +234,10 @@ function setupReactionButtons() {
   );
 
   reactionButtons.forEach((button) => {
+    // Skip buttons that already have a handler, e.g. after "Load more"
+    if (button.dataset.bound) return;
+    button.dataset.bound = "true";
+
     button.addEventListener("click", function () {
       if (window.appForms && window.appForms.submitReaction) {
         window.appForms.submitReaction(this);
@@ -82,6 +82,30 @@ function connect() {
           window.chatMessages.handleUserList(data.users);
         } else if (data.type === "message" && window.chatMessages) {
           window.chatMessages.handleMessage(data);
+        } else if (
+          (data.type === "message_updated" ||
+            data.type === "message_deleted") &&
+          window.chatMessages
+        ) {
+          window.chatMessages.handleMessageChange(data);
+        } else if (data.type === "unread_counts" && window.chatMessages) {
+          window.chatMessages.handleUnreadCounts(data.conversations);
+        } else if (data.type === "missed_messages" && window.chatMessages) {
+          window.chatMessages.handleMissedMessages(data.senders);
+        } else if (
+          (data.type === "typing" || data.type === "typing_stopped") &&
+          data.room_id &&
+          window.chatRooms
+        ) {
+          window.chatRooms.handleRoomTyping(data);
+        } else if (data.type === "room_message" && window.chatRooms) {
+          window.chatRooms.handleRoomMessage(data);
+        } else if (data.type === "room_history" && window.chatRooms) {
+          window.chatRooms.handleRoomHistory(data);
+        } else if (data.type === "room_updated" && window.chatRooms) {
+          window.chatRooms.handleRoomUpdated(data.room);
+        } else if (data.type === "room_left" && window.chatRooms) {
+          window.chatRooms.handleRoomLeft(data.room_id);
         } else if (data.type === "typing") {
           // Show typing indicator
           const typingIndicator = document.getElementById("typing-indicator");
@@ -107,6 +131,25 @@ function connect() {
           if (Array.isArray(data.messages)) {
             window.chatUI.displayMoreMessageHistory(data.messages);
           }
+        } else if (data.type === "comment_reply") {
+          showForumNotification(
+            `${data.username || "Someone"} replied to your comment`,
+            "Open the post to read the reply."
+          );
+        } else if (data.type === "moderation_warning") {
+          alert(
+            "A moderator has warned you" +
+              (data.reason ? ": " + data.reason : ".")
+          );
+        } else if (data.type === "report_resolved") {
+          showForumNotification(
+            "Your report was reviewed",
+            data.action === "dismiss"
+              ? "A moderator found nothing to act on."
+              : "A moderator acted on it."
+          );
+        } else if (data.type === "error") {
+          console.warn("Chat error:", data.message);
         }
       } catch (e) {
         console.log("Error processing WebSocket message:", e);
@@ -128,6 +171,18 @@ function connect() {
   }
 }
 
+// Show a browser notification about something that happened on the forum
+function showForumNotification(title, body) {
+  if (!("Notification" in window)) {
+    return;
+  }
+  if (Notification.permission === "granted") {
+    new Notification(title, { body: body });
+  } else if (Notification.permission !== "denied") {
+    Notification.requestPermission();
+  }
+}
+
 // Check WebSocket connection status
 function checkAndConnectWebSocket() {
   // If user isn't logged in, stop checking and clean up
@@ -41,10 +41,87 @@ function handleMessage(message) {
     if (!currentChatUser || message.sender_id !== currentChatUser.id) {
       usersWithUnreadMessages.add(message.sender_id);
       showMessageNotification(message);
+    } else {
+      markConversationRead(message.sender_id);
     }
   }
 }
 
+// Handle an edited or deleted message by updating it in place
+function handleMessageChange(message) {
+  const messageElem = document.querySelector(
+    `.message[data-message-id="${message.id}"]`
+  );
+  if (!messageElem) {
+    return;
+  }
+
+  const textElem = messageElem.querySelector(".message-text");
+  if (!textElem) {
+    return;
+  }
+
+  if (message.deleted) {
+    textElem.textContent = "Message deleted";
+    messageElem.classList.add("deleted");
+  } else {
+    textElem.textContent = message.content;
+    messageElem.classList.add("edited");
+  }
+}
+
+// Handle unread badge counts sent by the server on connect
+function handleUnreadCounts(conversations) {
+  usersWithUnreadMessages.clear();
+  if (conversations) {
+    Object.keys(conversations).forEach((userId) => {
+      if (conversations[userId] > 0) {
+        usersWithUnreadMessages.add(parseInt(userId, 10));
+      }
+    });
+  }
+
+  if (window.chatUI && window.chatUI.updateUsersList) {
+    window.chatUI.updateUsersList();
+  }
+}
+
+// Handle the summary of messages that arrived while the user was offline
+function handleMissedMessages(senders) {
+  if (!Array.isArray(senders)) {
+    return;
+  }
+
+  senders.forEach((sender) => {
+    usersWithUnreadMessages.add(sender.sender_id);
+    showMessageNotification({
+      sender_id: sender.sender_id,
+      content:
+        sender.count > 1
+          ? `${sender.count} new messages, latest: ${sender.latest_preview}`
+          : sender.latest_preview,
+    });
+  });
+
+  if (window.chatUI && window.chatUI.updateUsersList) {
+    window.chatUI.updateUsersList();
+  }
+}
+
+// Tell the server that the conversation with a user has been read
+function markConversationRead(userId) {
+  if (!socket || socket.readyState !== WebSocket.OPEN) {
+    return;
+  }
+
+  socket.send(
+    JSON.stringify({
+      type: "mark_read",
+      receiverID: parseInt(userId, 10),
+    })
+  );
+}
+
 // Update the displayMessage function in chat_messages.js
 function displayMessage(message) {
     let currentChatUser = null;
@@ -85,6 +162,9 @@ function displayMessage(message) {
             timeElem.textContent = `${dateStr} ${timeStr}`;
             // Add timestamp as data attribute
             timeElem.setAttribute("data-timestamp", message.timestamp);
+            if (message.id) {
+              pending.setAttribute("data-message-id", message.id);
+            }
   
             // If we have new message data, update the sorting of users
             if (!lastMessagesData[message.receiverID]) {
@@ -141,6 +221,10 @@ function displayMessage(message) {
               }">${dateStr} ${timeStr}</div>
           `;
   
+      if (message.id) {
+        messageElem.setAttribute("data-message-id", message.id);
+      }
+
       messagesContainer.appendChild(messageElem);
       messagesContainer.scrollTop = messagesContainer.scrollHeight;
   
@@ -288,6 +372,10 @@ function getLastMessagesData(userId) {
 window.chatMessages = {
   handleUserList,
   handleMessage,
+  handleMessageChange,
+  handleUnreadCounts,
+  handleMissedMessages,
+  markConversationRead,
   displayMessage,
   showMessageNotification,
   sendMessage,
@@ -0,0 +1,418 @@
+// chat-rooms.js - Group chat rooms: listing, creating, inviting and messaging
+
+// Global variables for rooms
+let rooms = [];
+let currentRoom = null;
+let roomsWithUnreadMessages = new Set();
+
+// Fetch the rooms of the logged-in user
+async function fetchRooms() {
+  if (!window.state || !window.state.sessionID) {
+    return;
+  }
+
+  try {
+    const response = await fetch("/rooms", {
+      headers: { Accept: "application/json" },
+    });
+    if (!response.ok) {
+      return;
+    }
+
+    const data = await response.json();
+    rooms = data.rooms || [];
+    updateRoomsList();
+  } catch (error) {
+    console.log("Error fetching rooms:", error);
+  }
+}
+
+// Render the rooms list in the chat sidebar
+function updateRoomsList() {
+  const roomsList = document.getElementById("rooms-list");
+  if (!roomsList) {
+    return;
+  }
+
+  roomsList.innerHTML = "";
+
+  if (rooms.length === 0) {
+    const emptyMessage = document.createElement("p");
+    emptyMessage.className = "empty-users-message";
+    emptyMessage.textContent = "No rooms yet";
+    roomsList.appendChild(emptyMessage);
+    return;
+  }
+
+  rooms.forEach((room) => {
+    const roomItem = document.createElement("div");
+    roomItem.className = "user-item room-item";
+    if (roomsWithUnreadMessages.has(room.id)) {
+      roomItem.classList.add("has-new-message");
+    }
+    roomItem.textContent = `# ${room.name}`;
+    roomItem.dataset.roomId = room.id;
+
+    roomItem.addEventListener("click", function () {
+      openRoom(room.id);
+    });
+
+    roomsList.appendChild(roomItem);
+  });
+}
+
+// Wire up the rooms section of a freshly rendered chat sidebar
+function setupSidebar() {
+  const createButton = document.getElementById("create-room-button");
+  if (createButton) {
+    createButton.addEventListener("click", createRoom);
+  }
+  setTimeout(fetchRooms, 100);
+}
+
+// Post a form to one of the room endpoints and return the JSON response
+async function postRoomForm(url, fields) {
+  const formData = new FormData();
+  Object.keys(fields).forEach((key) => formData.append(key, fields[key]));
+
+  const response = await fetch(url, {
+    method: "POST",
+    headers: { "X-CSRF-Token": window.state.csrfToken },
+    body: formData,
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.message || "Request failed");
+  }
+  return data;
+}
+
+// Ask for a name and create a room owned by the current user
+async function createRoom() {
+  const name = prompt("Room name:");
+  if (!name || !name.trim()) {
+    return;
+  }
+
+  try {
+    const data = await postRoomForm("/rooms/create", { name: name.trim() });
+    await fetchRooms();
+    openRoom(data.id);
+  } catch (error) {
+    alert(error.message);
+  }
+}
+
+// Ask for a username and invite them to the open room
+async function inviteToRoom() {
+  if (!currentRoom) {
+    return;
+  }
+
+  const username = prompt("Username to invite:");
+  if (!username || !username.trim()) {
+    return;
+  }
+
+  try {
+    await postRoomForm("/rooms/invite", {
+      room_id: currentRoom.id,
+      username: username.trim(),
+    });
+  } catch (error) {
+    alert(error.message);
+  }
+}
+
+// Leave the open room and go back to the posts
+async function leaveRoom() {
+  if (!currentRoom || !confirm(`Leave ${currentRoom.name}?`)) {
+    return;
+  }
+
+  try {
+    await postRoomForm("/rooms/leave", { room_id: currentRoom.id });
+  } catch (error) {
+    alert(error.message);
+  }
+}
+
+// Close the room view and show the posts again
+function closeRoom() {
+  currentRoom = null;
+  if (window.appPages) {
+    window.appPages.loadHomePage();
+  } else {
+    window.location.href = "/";
+  }
+}
+
+// Open the chat view of a room and load its latest messages
+function openRoom(roomId) {
+  roomId = parseInt(roomId, 10);
+  const room = rooms.find((r) => r.id === roomId);
+  if (!room) {
+    return;
+  }
+  currentRoom = room;
+  roomsWithUnreadMessages.delete(roomId);
+  updateRoomsList();
+
+  const content = document.getElementById("content");
+  if (!content) {
+    return;
+  }
+  content.innerHTML = window.templates.roomInterface(room);
+
+  document.getElementById("back-to-posts").addEventListener("click", closeRoom);
+  document.getElementById("invite-room-button").addEventListener("click", inviteToRoom);
+  document.getElementById("leave-room-button").addEventListener("click", leaveRoom);
+
+  const socket = window.chatConnection ? window.chatConnection.socket() : null;
+  let typingTimer;
+  let isTyping = false;
+
+  function stopTyping() {
+    clearTimeout(typingTimer);
+    if (isTyping && socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify({ type: "typing_stopped", room_id: roomId }));
+    }
+    isTyping = false;
+  }
+
+  const messageInput = document.getElementById("message-input");
+  document
+    .getElementById("send-message-button")
+    .addEventListener("click", function () {
+      stopTyping();
+      sendRoomMessage();
+    });
+
+  messageInput.addEventListener("keydown", function (e) {
+    if (e.key === "Enter" && !e.shiftKey) {
+      e.preventDefault();
+      stopTyping();
+      sendRoomMessage();
+      return;
+    }
+
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      if (!isTyping) {
+        socket.send(JSON.stringify({ type: "typing", room_id: roomId }));
+        isTyping = true;
+      }
+      clearTimeout(typingTimer);
+      typingTimer = setTimeout(stopTyping, 1500);
+    }
+  });
+
+  if (socket && socket.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify({ type: "room_history", room_id: roomId }));
+  }
+
+  setupRoomScrollListener(roomId);
+  setTimeout(() => messageInput.focus(), 100);
+}
+
+// Send the content of the message input to the open room
+function sendRoomMessage() {
+  const socket = window.chatConnection ? window.chatConnection.socket() : null;
+  if (!socket || socket.readyState !== WebSocket.OPEN) {
+    alert("WebSocket not connected. Please refresh the page.");
+    return;
+  }
+
+  const messageInput = document.getElementById("message-input");
+  if (!currentRoom || !messageInput) {
+    return;
+  }
+
+  const content = messageInput.value.trim();
+  if (!content) {
+    return;
+  }
+
+  socket.send(
+    JSON.stringify({
+      type: "room_message",
+      room_id: currentRoom.id,
+      content: content,
+    })
+  );
+  messageInput.value = "";
+  messageInput.focus();
+}
+
+// Build the element of one room message
+function roomMessageElement(message) {
+  const messageElem = document.createElement("div");
+  messageElem.className = "message";
+  messageElem.classList.add(
+    message.sender_id === window.state.sessionID ? "outgoing" : "incoming"
+  );
+  messageElem.setAttribute("data-message-id", message.id);
+
+  const escape = window.chatUI.escapeHTML;
+  const time = new Date(message.timestamp).toLocaleString();
+  messageElem.innerHTML = `
+          <div class="message-sender">${escape(message.username || "Unknown")}</div>
+          <div class="message-text">${escape(message.content)}</div>
+          <div class="message-time">${time}</div>
+      `;
+  return messageElem;
+}
+
+// Handle a new message in one of the user's rooms
+function handleRoomMessage(message) {
+  if (!currentRoom || currentRoom.id !== message.room_id) {
+    roomsWithUnreadMessages.add(message.room_id);
+    updateRoomsList();
+    return;
+  }
+
+  const messagesContainer = document.getElementById("messages-container");
+  if (!messagesContainer) {
+    return;
+  }
+  const emptyState = messagesContainer.querySelector(".chat-empty-state");
+  if (emptyState) {
+    emptyState.remove();
+  }
+
+  messagesContainer.appendChild(roomMessageElement(message));
+  messagesContainer.scrollTop = messagesContainer.scrollHeight;
+}
+
+// Handle a page of room history, either the latest one or an older one
+function handleRoomHistory(data) {
+  if (!currentRoom || currentRoom.id !== data.room_id) {
+    return;
+  }
+
+  const messagesContainer = document.getElementById("messages-container");
+  if (!messagesContainer) {
+    return;
+  }
+  messagesContainer.dataset.hasMore = data.has_more ? "true" : "false";
+  messagesContainer.dataset.loading = "false";
+
+  const messages = data.messages || [];
+  const fragment = document.createDocumentFragment();
+  messages.forEach((message) => fragment.appendChild(roomMessageElement(message)));
+
+  const olderPage = messagesContainer.querySelector(".message[data-message-id]");
+  if (!olderPage) {
+    if (messages.length > 0) {
+      messagesContainer.innerHTML = "";
+      messagesContainer.appendChild(fragment);
+    }
+    messagesContainer.scrollTop = messagesContainer.scrollHeight;
+    return;
+  }
+
+  // Keep the view where it was while older messages are prepended
+  const oldScrollHeight = messagesContainer.scrollHeight;
+  messagesContainer.insertBefore(fragment, messagesContainer.firstChild);
+  messagesContainer.scrollTop += messagesContainer.scrollHeight - oldScrollHeight;
+}
+
+// Load older room messages when scrolling to the top
+function setupRoomScrollListener(roomId) {
+  const messagesContainer = document.getElementById("messages-container");
+  if (!messagesContainer) {
+    return;
+  }
+
+  messagesContainer.addEventListener("scroll", function () {
+    if (
+      messagesContainer.scrollTop >= 50 ||
+      messagesContainer.dataset.hasMore !== "true" ||
+      messagesContainer.dataset.loading === "true"
+    ) {
+      return;
+    }
+
+    const oldest = messagesContainer.querySelector(".message[data-message-id]");
+    const socket = window.chatConnection ? window.chatConnection.socket() : null;
+    if (!oldest || !socket || socket.readyState !== WebSocket.OPEN) {
+      return;
+    }
+
+    messagesContainer.dataset.loading = "true";
+    socket.send(
+      JSON.stringify({
+        type: "room_history",
+        room_id: roomId,
+        before_id: parseInt(oldest.getAttribute("data-message-id"), 10),
+      })
+    );
+  });
+}
+
+// Handle a change of a room's name or members
+function handleRoomUpdated(room) {
+  const index = rooms.findIndex((r) => r.id === room.id);
+  if (index === -1) {
+    rooms.unshift(room);
+  } else {
+    rooms[index] = room;
+  }
+  updateRoomsList();
+
+  if (currentRoom && currentRoom.id === room.id) {
+    currentRoom = room;
+    const title = document.getElementById("room-title");
+    if (title) {
+      title.textContent = `# ${room.name}`;
+    }
+    const members = document.getElementById("room-members");
+    if (members) {
+      members.textContent = room.members.map((m) => m.username).join(", ");
+    }
+  }
+}
+
+// Handle the current user leaving a room, possibly from another tab
+function handleRoomLeft(roomId) {
+  rooms = rooms.filter((r) => r.id !== roomId);
+  roomsWithUnreadMessages.delete(roomId);
+  updateRoomsList();
+
+  if (currentRoom && currentRoom.id === roomId) {
+    closeRoom();
+  }
+}
+
+// Show or hide who is typing in the open room
+function handleRoomTyping(data) {
+  if (!currentRoom || currentRoom.id !== data.room_id) {
+    return;
+  }
+
+  const typingIndicator = document.getElementById("typing-indicator");
+  if (!typingIndicator) {
+    return;
+  }
+  if (data.type === "typing") {
+    typingIndicator.innerHTML = `${window.chatUI.escapeHTML(
+      data.username || "User"
+    )} is typing<span class="typing-dots"><span>.</span><span>.</span><span>.</span></span>`;
+    typingIndicator.style.display = "block";
+  } else {
+    typingIndicator.style.display = "none";
+  }
+}
+
+// Export the chat rooms module functions
+window.chatRooms = {
+  setupSidebar,
+  fetchRooms,
+  updateRoomsList,
+  createRoom,
+  openRoom,
+  handleRoomMessage,
+  handleRoomHistory,
+  handleRoomUpdated,
+  handleRoomLeft,
+  handleRoomTyping,
+};
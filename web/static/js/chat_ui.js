@@ -193,21 +193,29 @@ function openChat(userId, username) {
 
   const socket = window.chatConnection ? window.chatConnection.socket() : null;
   let typingTimer;
+  // Whether the partner was told that we are typing. The server rate limits
+  // typing notifications, so they are only sent when this changes.
+  let isTyping = false;
+
+  function stopTyping() {
+    clearTimeout(typingTimer);
+    if (isTyping && socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(
+        JSON.stringify({
+          type: "typing_stopped",
+          receiverID: userId,
+        })
+      );
+    }
+    isTyping = false;
+  }
 
   // Set up message send button
   document
     .getElementById("send-message-button")
     .addEventListener("click", function () {
       // Send typing_stopped when message is sent
-      clearTimeout(typingTimer);
-      if (socket && socket.readyState === WebSocket.OPEN) {
-        socket.send(
-          JSON.stringify({
-            type: "typing_stopped",
-            receiverID: userId,
-          })
-        );
-      }
+      stopTyping();
 
       if (window.chatMessages && window.chatMessages.sendMessage) {
         window.chatMessages.sendMessage();
@@ -219,23 +227,19 @@ function openChat(userId, username) {
   messageInput.addEventListener("keydown", function () {
     // Send typing status to other user
     if (socket && socket.readyState === WebSocket.OPEN) {
-      socket.send(
-        JSON.stringify({
-          type: "typing",
-          receiverID: userId,
-        })
-      );
-
-      // Stop typing indicator after inactivity
-      clearTimeout(typingTimer);
-      typingTimer = setTimeout(() => {
+      if (!isTyping) {
         socket.send(
           JSON.stringify({
-            type: "typing_stopped",
+            type: "typing",
             receiverID: userId,
           })
         );
-      }, 300);
+        isTyping = true;
+      }
+
+      // Stop typing indicator after inactivity
+      clearTimeout(typingTimer);
+      typingTimer = setTimeout(stopTyping, 1500);
     }
   });
 
@@ -245,15 +249,7 @@ function openChat(userId, username) {
       e.preventDefault();
 
       // Send typing_stopped when message is sent
-      clearTimeout(typingTimer);
-      if (socket && socket.readyState === WebSocket.OPEN) {
-        socket.send(
-          JSON.stringify({
-            type: "typing_stopped",
-            receiverID: userId,
-          })
-        );
-      }
+      stopTyping();
 
       if (window.chatMessages && window.chatMessages.sendMessage) {
         window.chatMessages.sendMessage();
@@ -269,6 +265,10 @@ function openChat(userId, username) {
         receiverID: userId,
       })
     );
+
+    if (window.chatMessages && window.chatMessages.markConversationRead) {
+      window.chatMessages.markConversationRead(userId);
+    }
   } else {
     const messagesContainer = document.getElementById("messages-container");
     if (messagesContainer) {
@@ -426,6 +426,10 @@ function displayMoreMessageHistory(messages) {
       messageElem.classList.add("incoming");
     }
 
+    if (message.id) {
+      messageElem.setAttribute("data-message-id", message.id);
+    }
+
     const time = new Date(message.timestamp).toLocaleTimeString();
     messageElem.innerHTML = `
             <div class="message-text">${escapeHTML(message.content)}</div>
@@ -465,17 +469,13 @@ function setupScrollListener() {
   if (!messagesContainer) return;
 
   let noMoreMessages = false;
-  let oldestMessageTimestamp = null;
-
-  // Find the oldest message timestamp if there are messages
-  const findOldestMessageTimestamp = () => {
-    const messages = messagesContainer.querySelectorAll(".message");
-    if (messages.length > 0) {
-      const firstMessage = messages[0];
-      const timeElem = firstMessage.querySelector(".message-time");
-      if (timeElem) {
-        return timeElem.getAttribute("data-timestamp");
-      }
+  let oldestMessageId = null;
+
+  // Find the ID of the oldest message shown
+  const findOldestMessageId = () => {
+    const message = messagesContainer.querySelector(".message[data-message-id]");
+    if (message) {
+      return parseInt(message.getAttribute("data-message-id"), 10);
     }
     return null;
   };
@@ -495,10 +495,10 @@ function setupScrollListener() {
       loadingIndicator.textContent = "Loading more messages...";
       messagesContainer.prepend(loadingIndicator);
 
-      // Get the oldest message timestamp
-      oldestMessageTimestamp = findOldestMessageTimestamp();
+      // Get the oldest message ID
+      oldestMessageId = findOldestMessageId();
 
-      // Request more messages using the timestamp
+      // Request the page of messages before it
       const socket = window.chatConnection
         ? window.chatConnection.socket()
         : null;
@@ -507,7 +507,7 @@ function setupScrollListener() {
           JSON.stringify({
             type: "get_more_history",
             receiverID: currentChatUser.id,
-            timestamp: oldestMessageTimestamp,
+            before_id: oldestMessageId,
           })
         );
 
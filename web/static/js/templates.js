@@ -27,15 +27,42 @@ const templates = {
       </div>
     `,
 
-  homePage: (posts) => `
+  homePage: (posts, sort, nextCursor) => `
       <h2>All posts</h2>
-      ${
-        posts.length > 0
-          ? posts.map((post) => templates.postCard(post)).join("")
-          : "<p>No posts available.</p>"
-      }
+      <div class="post-sort">
+        <label for="post-sort">Sort by:</label>
+        <select id="post-sort">
+          ${[
+            ["top", "Top"],
+            ["hot", "Hot"],
+            ["newest", "Newest"],
+            ["comments", "Most commented"],
+          ]
+            .map(
+              ([value, label]) =>
+                `<option value="${value}" ${
+                  value === sort ? "selected" : ""
+                }>${label}</option>`
+            )
+            .join("")}
+        </select>
+      </div>
+      <div id="post-list">
+        ${
+          posts.length > 0
+            ? posts.map((post) => templates.postCard(post)).join("")
+            : "<p>No posts available.</p>"
+        }
+      </div>
+      ${templates.loadMorePosts(nextCursor)}
     `,
 
+  // Button that loads the next page of posts
+  loadMorePosts: (nextCursor) =>
+    nextCursor
+      ? `<button id="load-more-posts" data-cursor="${nextCursor}">Load more</button>`
+      : "",
+
   // Single post view
   postDetail: (post) => `
       <div class="post" id="post-${post.ID}">
@@ -59,7 +86,11 @@ const templates = {
           </button>
         </div>
         
-        <h3>Comments (${post.Comments ? post.Comments.length : 0})</h3>
+        <h3>Comments (${
+          post.Comments
+            ? post.Comments.reduce((n, c) => n + 1 + (c.ReplyCount || 0), 0)
+            : 0
+        })</h3>
         <div id="comments-container">
           ${templates.comments(post.Comments)}
         </div>
@@ -74,9 +105,21 @@ const templates = {
       return "<p>No comments yet. Be the first to comment!</p>";
     }
 
-    return comments
-      .map(
-        (comment) => `
+    return comments.map((comment) => templates.comment(comment)).join("");
+  },
+
+  // Single comment with its nested replies
+  comment: (comment) => {
+    if (comment.Deleted) {
+      return `
+        <div class="comment deleted">
+          <p class="comment-content"><em>[deleted]</em></p>
+          ${templates.replies(comment)}
+        </div>
+      `;
+    }
+
+    return `
         <div class="comment">
           <p class="comment-author"><strong>${
             comment.Username
@@ -94,10 +137,24 @@ const templates = {
               👎 <span>${comment.Dislikes || 0}</span>
             </button>
           </div>
+          ${templates.replies(comment)}
         </div>
-      `
-      )
-      .join("");
+      `;
+  },
+
+  // Replies below a comment, or how many are hidden when collapsed
+  replies: (comment) => {
+    if (comment.Collapsed) {
+      return `<p class="comment-collapsed">${comment.ReplyCount} more ${
+        comment.ReplyCount === 1 ? "reply" : "replies"
+      }</p>`;
+    }
+    if (!comment.Replies || comment.Replies.length === 0) {
+      return "";
+    }
+    return `<div class="comment-replies">${comment.Replies.map((reply) =>
+      templates.comment(reply)
+    ).join("")}</div>`;
   },
 
   // Comment form
@@ -208,7 +265,7 @@ const templates = {
     `,
 
   // Create post form
-  createPostForm: () => `
+  createPostForm: (categories) => `
       <div class="form-container">
         <h2>Create a New Post</h2>
         <form id="create-post-form">
@@ -220,13 +277,12 @@ const templates = {
           
           <label>Categories:</label>
           <div class="checkbox-container">
-            <label><input type="checkbox" name="categories" value="General"> General</label>
-            <label><input type="checkbox" name="categories" value="Local News & Events"> Local News & Events</label>
-            <label><input type="checkbox" name="categories" value="Viking line"> Viking Line</label>
-            <label><input type="checkbox" name="categories" value="Travel"> Travel</label>
-            <label><input type="checkbox" name="categories" value="Sailing"> Sailing</label>
-            <label><input type="checkbox" name="categories" value="Cuisine & food"> Cuisine & Food</label>
-            <label><input type="checkbox" name="categories" value="Politics"> Politics</label>
+            ${categories
+              .map(
+                (category) =>
+                  `<label title="${category.description}"><input type="checkbox" name="categories" value="${category.id}"> ${category.name}</label>`
+              )
+              .join("")}
           </div>
           
           <button type="submit">Publish Post</button>
@@ -261,6 +317,37 @@ const templates = {
   </div>
   `,
 
+  // Chat room interface template
+  roomInterface: (room) => `
+    <div class="chat-interface">
+    <div class="chat-title">
+      <h2 id="room-title"># ${window.chatUI.escapeHTML(room.name)}</h2>
+      <div class="room-actions">
+        <button id="invite-room-button" class="back-button">Invite</button>
+        <button id="leave-room-button" class="back-button">Leave</button>
+        <button id="back-to-posts" class="back-button">Back to Posts</button>
+      </div>
+    </div>
+    <p id="room-members" class="room-members">${window.chatUI.escapeHTML(
+      room.members.map((member) => member.username).join(", ")
+    )}</p>
+
+    <div id="messages-container" class="messages-container">
+      <div class="chat-empty-state">
+        <h3>Start a conversation</h3>
+        <p>No messages yet. Send a message to start the conversation.</p>
+      </div>
+    </div>
+
+    <div id="typing-indicator" class="typing-indicator"></div>
+
+    <div class="chat-footer">
+      <textarea id="message-input" placeholder="Type a message..." rows="2"></textarea>
+      <button id="send-message-button">Send</button>
+    </div>
+  </div>
+  `,
+
   // Helper templates
   loading: () => '<div class="loading">Loading...</div>',
 
@@ -291,7 +378,7 @@ const templates = {
   },
 
   // Sidebar with categories
-  sidebar: (sessionID) => {
+  sidebar: (sessionID, categories) => {
     if (!sessionID) {
       return `
         <aside id="category-sidebar" style="display: none;">
@@ -304,13 +391,12 @@ const templates = {
     return `
       <aside id="category-sidebar">
         <h2>Categories:</h2>
-        <p><a href="/filter?category=General" data-navigate>General</a></p>
-        <p><a href="/filter?category=Local%20News%20%26%20Events" data-navigate>Local News & Events</a></p>
-        <p><a href="/filter?category=Viking%20line" data-navigate>Viking line</a></p>
-        <p><a href="/filter?category=Travel" data-navigate>Travel</a></p>
-        <p><a href="/filter?category=Sailing" data-navigate>Sailing</a></p>
-        <p><a href="/filter?category=Cuisine%20%26%20food" data-navigate>Cuisine & food</a></p>
-        <p><a href="/filter?category=Politics" data-navigate>Politics</a></p>
+        ${categories
+          .map(
+            (category) =>
+              `<p><a href="/filter?category_id=${category.id}" title="${category.description}" data-navigate>${category.name}</a></p>`
+          )
+          .join("")}
 
         <h2>Filters:</h2>
       <p><a href="/filter?user_created=true" data-navigate>My Posts</a></p>
@@ -332,6 +418,13 @@ const templates = {
     <div id="users-list" class="users-list">
       <p class="empty-users-message">Loading users...</p>
     </div>
+    <div class="chat-header rooms-header">
+      <h3>Rooms</h3>
+      <button id="create-room-button" class="new-room-button">New</button>
+    </div>
+    <div id="rooms-list" class="users-list">
+      <p class="empty-users-message">Loading rooms...</p>
+    </div>
   `,
 };
 